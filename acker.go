@@ -0,0 +1,63 @@
+package vnats
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Acker controls the acknowledgment of a received message explicitly.
+type Acker interface {
+	// Ack acknowledges the message as processed.
+	Ack() error
+
+	// Nak negatively acknowledges the message, so it is redelivered after delay.
+	Nak(delay time.Duration) error
+
+	// Term terminates the message, so it is never redelivered, e.g. if it can't be processed at all.
+	Term() error
+
+	// InProgress resets the AckWait duration of the message, to signal that it is still being processed.
+	InProgress() error
+}
+
+// AckHandler processes a message and acknowledges it with acker. A message, which is neither acknowledged nor
+// NAKed or terminated, is redelivered after the AckWait duration.
+type AckHandler func(ctx context.Context, msg Msg, acker Acker)
+
+// StartWithAcker starts the Subscriber like StartWithContext, but the handler acknowledges each message explicitly
+// instead of returning an error, e.g. to terminate or park messages.
+func (s *Subscriber) StartWithAcker(handler AckHandler) error {
+	return s.start(handler)
+}
+
+type msgAcker struct {
+	natsMsg     *nats.Msg
+	stats       *subscriberStats
+	deliveredAt time.Time
+}
+
+func (a *msgAcker) Ack() error {
+	return a.count(Acked, a.natsMsg.Ack())
+}
+
+func (a *msgAcker) Nak(delay time.Duration) error {
+	return a.count(Naked, a.natsMsg.NakWithDelay(delay))
+}
+
+func (a *msgAcker) Term() error {
+	return a.count(Termed, a.natsMsg.Term())
+}
+
+// count counts the acknowledgment with result, if it succeeded.
+func (a *msgAcker) count(result AckResult, err error) error {
+	if err == nil {
+		a.stats.ack(result, time.Since(a.deliveredAt))
+	}
+	return err
+}
+
+func (a *msgAcker) InProgress() error {
+	return a.natsMsg.InProgress()
+}
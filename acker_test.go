@@ -0,0 +1,208 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// recordingAcker records the acknowledgment of a message.
+type recordingAcker struct {
+	acked      bool
+	nakDelay   time.Duration
+	terminated bool
+	inProgress int
+}
+
+func (a *recordingAcker) Ack() error {
+	a.acked = true
+	return nil
+}
+
+func (a *recordingAcker) Nak(delay time.Duration) error {
+	a.nakDelay = delay
+	return nil
+}
+
+func (a *recordingAcker) Term() error {
+	a.terminated = true
+	return nil
+}
+
+func (a *recordingAcker) InProgress() error {
+	a.inProgress++
+	return nil
+}
+
+func TestSubscriber_implicitAck(t *testing.T) {
+	errHandler := errors.New("handler failed")
+	tests := []struct {
+		name           string
+		args           SubscriberArgs
+		handlerErr     error
+		numDelivered   uint64
+		want           recordingAcker
+		wantMaxDeliver bool
+	}{
+		{name: "success is acknowledged", want: recordingAcker{acked: true}},
+		{name: "error is NAKed", handlerErr: errHandler, numDelivered: 1, want: recordingAcker{nakDelay: defaultNakDelay}},
+		{name: "NAK delay of handler", handlerErr: &nakDelayError{delay: time.Minute}, want: recordingAcker{nakDelay: time.Minute}},
+		{
+			name:         "error before MaxDeliver is NAKed",
+			args:         SubscriberArgs{MaxDeliver: 3},
+			handlerErr:   errHandler,
+			numDelivered: 2,
+			want:         recordingAcker{nakDelay: defaultNakDelay},
+		},
+		{
+			name:         "error is NAKed with backoff of delivery",
+			args:         SubscriberArgs{Backoff: []time.Duration{time.Second, time.Second * 10, time.Minute}},
+			handlerErr:   errHandler,
+			numDelivered: 2,
+			want:         recordingAcker{nakDelay: time.Second * 10},
+		},
+		{
+			name:         "error after last backoff is NAKed with last backoff",
+			args:         SubscriberArgs{Backoff: []time.Duration{time.Second, time.Second * 10, time.Minute}},
+			handlerErr:   errHandler,
+			numDelivered: 7,
+			want:         recordingAcker{nakDelay: time.Minute},
+		},
+		{
+			name:           "error at MaxDeliver is terminated",
+			args:           SubscriberArgs{MaxDeliver: 3},
+			handlerErr:     errHandler,
+			numDelivered:   3,
+			want:           recordingAcker{terminated: true},
+			wantMaxDeliver: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var maxDeliverErr error
+			tt.args.OnMaxDeliverExceeded = func(_ Msg, err error) {
+				maxDeliverErr = err
+			}
+			sub := &Subscriber{logger: slog.Default(), args: tt.args}
+
+			acker := &recordingAcker{}
+			handler := sub.implicitAck(func(_ context.Context, _ Msg) error {
+				return tt.handlerErr
+			})
+			handler(context.Background(), Msg{Metadata: Metadata{NumDelivered: tt.numDelivered}}, acker)
+
+			if *acker != tt.want {
+				t.Errorf("acker = %+v, want %+v", *acker, tt.want)
+			}
+			if (maxDeliverErr != nil) != tt.wantMaxDeliver {
+				t.Errorf("OnMaxDeliverExceeded called with %v, want called %v", maxDeliverErr, tt.wantMaxDeliver)
+			}
+		})
+	}
+}
+
+func TestSubscriber_callHandlerWithTimeout(t *testing.T) {
+	var timedOut []string
+	conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+	conn.hooks.OnHandlerTimeout = func(consumerName string, msg Msg) {
+		timedOut = append(timedOut, consumerName+" "+msg.MsgID)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	sub := &Subscriber{
+		conn:         conn,
+		logger:       slog.Default(),
+		consumerName: "stuck",
+		handler: func(_ context.Context, _ Msg, _ Acker) {
+			<-release
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	acker := &recordingAcker{}
+	start := time.Now()
+	sub.callHandlerWithTimeout(ctx, Msg{MsgID: "msg-001"}, acker)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("callHandlerWithTimeout() waited %s for the stuck handler", elapsed)
+	}
+	if acker.nakDelay != defaultNakDelay {
+		t.Errorf("message was not NAKed, acker %+v", acker)
+	}
+	if len(timedOut) != 1 || timedOut[0] != "stuck msg-001" {
+		t.Errorf("OnHandlerTimeout calls = %v", timedOut)
+	}
+}
+
+func TestSubscriber_callHandlerWithTimeout_StuckHandlers(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		wantBlocked int // the call, which must wait for a stuck handler
+	}{
+		{name: "sequential", wantBlocked: 2},
+		{name: "concurrent", concurrency: 2, wantBlocked: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			sub := &Subscriber{
+				conn:   makeTestConnection(t, "MESSAGES", 0, nil, "", nil),
+				logger: slog.Default(),
+				handler: func(_ context.Context, _ Msg, _ Acker) {
+					<-release
+				},
+			}
+			if tt.concurrency > 0 {
+				sub.workers = make(chan struct{}, tt.concurrency)
+			}
+
+			for i := 1; i <= tt.wantBlocked; i++ {
+				returned := make(chan struct{})
+				if sub.workers != nil { // like the worker go-routines of processMessages
+					sub.inFlight.Add(1)
+				}
+				go func() {
+					defer close(returned)
+					if sub.workers != nil {
+						defer sub.inFlight.Done()
+					}
+					ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+					defer cancel()
+					sub.callHandlerWithTimeout(ctx, Msg{MsgID: "msg-001"}, &recordingAcker{})
+				}()
+
+				select {
+				case <-returned:
+					if i == tt.wantBlocked {
+						t.Fatalf("call %d returned while a handler is stuck", i)
+					}
+				case <-time.After(time.Millisecond * 200):
+					if i < tt.wantBlocked {
+						t.Fatalf("call %d did not return after the timeout", i)
+					}
+				}
+			}
+
+			inFlight := make(chan struct{})
+			go func() {
+				defer close(inFlight)
+				sub.inFlight.Wait()
+			}()
+			select {
+			case <-inFlight:
+				t.Fatal("stuck handlers are not counted as in flight")
+			case <-time.After(time.Millisecond * 50):
+			}
+			close(release)
+			select {
+			case <-inFlight:
+			case <-time.After(time.Second):
+				t.Fatal("handlers are still in flight after they returned")
+			}
+		})
+	}
+}
@@ -1,53 +1,153 @@
 package vnats
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
+	"sync"
+	"time"
 
-	natsServer "github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 )
 
 type natsBridge struct {
 	connection       *nats.Conn
 	jetStreamContext nats.JetStreamContext
 	logger           *slog.Logger
+
+	// external is true if the connection is managed by the caller, it won't be drained by the bridge.
+	external bool
+
+	// domain is the JetStream domain set by WithJetStreamDomain.
+	domain string
+
+	// allowDirect caches per stream name, whether the stream allows direct get.
+	allowDirect sync.Map
 }
 
-func newNATSBridge(servers []string, logger *slog.Logger) (*natsBridge, error) {
+func newNATSBridge(servers []string, options []nats.Option, jsOptions []nats.JSOpt, domain string, hooks ConnectionHooks, logger *slog.Logger) (*natsBridge, error) {
 	nb := &natsBridge{
 		logger: logger,
+		domain: domain,
 	}
 
 	var err error
 	url := strings.Join(servers, ",")
 
-	nb.connection, err = nats.Connect(url,
+	options = append(connectionHandlers(hooks, logger), options...)
+
+	nb.connection, err = nats.Connect(url, options...)
+	if err != nil {
+		return nil, fmt.Errorf("could not make NATS Connection to %s: %w", url, err)
+	}
+
+	nb.jetStreamContext, err = nb.connection.JetStream(jsOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return nb, nil
+}
+
+// connectionHandlers returns the NATS handlers, which log changes of the connectivity and call the matching hooks.
+func connectionHandlers(hooks ConnectionHooks, logger *slog.Logger) []nats.Option {
+	return []nats.Option{
 		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
-			logger.Error("Got disconnected", slog.String("error", err.Error()))
+			defer recoverPanic(logger, hooks, "DisconnectErrHandler")
+			logger.Error("Got disconnected", slog.Any("error", err))
+			if hooks.OnDisconnect != nil {
+				hooks.OnDisconnect(err)
+			}
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
+			defer recoverPanic(logger, hooks, "ReconnectHandler")
 			logger.Error("Got reconnected to!", slog.String("url", nc.ConnectedUrl()))
+			if hooks.OnReconnect != nil {
+				hooks.OnReconnect(nc.ConnectedUrl())
+			}
 		}),
 		nats.ClosedHandler(func(nc *nats.Conn) {
-			logger.Error("Connection closed", slog.String("error", nc.LastError().Error()))
-		}))
-	if err != nil {
-		return nil, fmt.Errorf("could not make NATS Connection to %s: %w", url, err)
+			defer recoverPanic(logger, hooks, "ClosedHandler")
+			logger.Error("Connection closed", slog.Any("error", nc.LastError()))
+			if hooks.OnClosed != nil {
+				hooks.OnClosed()
+			}
+		}),
+		nats.LameDuckModeHandler(func(nc *nats.Conn) {
+			defer recoverPanic(logger, hooks, "LameDuckModeHandler")
+			logger.Warn("Server entered lame duck mode", slog.String("url", nc.ConnectedUrl()))
+			if hooks.OnLameDuck != nil {
+				hooks.OnLameDuck(nc.ConnectedUrl())
+			}
+			// Reconnect proactively to another server before the lame duck server closes the connection.
+			// Subscriptions are bound to durable consumers, so they continue on the new server.
+			if len(nc.Servers()) > 1 {
+				if err := nc.ForceReconnect(); err != nil {
+					logger.Error("Could not reconnect to another server", slog.Any("error", err))
+				}
+			}
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			defer recoverPanic(logger, hooks, "ErrorHandler")
+			if sub != nil {
+				logger.Error("Asynchronous error", slog.String("subject", sub.Subject), slog.Any("error", err))
+			} else {
+				logger.Error("Asynchronous error", slog.Any("error", err))
+			}
+			if hooks.OnError != nil {
+				hooks.OnError(err)
+			}
+		}),
 	}
+}
 
-	nb.jetStreamContext, err = nb.connection.JetStream()
+func newNATSBridgeFromConn(nc *nats.Conn, jsOptions []nats.JSOpt, domain string, logger *slog.Logger) (*natsBridge, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("NATS Connection cannot be nil")
+	}
+
+	js, err := nc.JetStream(jsOptions...)
 	if err != nil {
 		return nil, err
 	}
 
-	return nb, nil
+	return &natsBridge{
+		connection:       nc,
+		jetStreamContext: js,
+		logger:           logger,
+		domain:           domain,
+		external:         true,
+	}, nil
 }
 
-func (b *natsBridge) PublishMsg(msg *nats.Msg, msgID string) error {
-	_, err := b.jetStreamContext.PublishMsg(msg, nats.MsgId(msgID))
-	return err
+func (b *natsBridge) PublishMsg(ctx context.Context, msg *nats.Msg, msgID string) (*nats.PubAck, error) {
+	options := []nats.PubOpt{nats.MsgId(msgID)}
+	if ctx.Done() != nil { // ctx can be cancelled, otherwise the default timeout of the JetStream context is used
+		options = append(options, nats.Context(ctx))
+	}
+	return b.jetStreamContext.PublishMsg(msg, options...)
+}
+
+func (b *natsBridge) PublishMsgAsync(msg *nats.Msg, msgID string) (nats.PubAckFuture, error) {
+	return b.jetStreamContext.PublishMsgAsync(msg, nats.MsgId(msgID))
+}
+
+func (b *natsBridge) PublishAsyncComplete() <-chan struct{} {
+	return b.jetStreamContext.PublishAsyncComplete()
+}
+
+func (b *natsBridge) PublishAsyncPending() int {
+	return b.jetStreamContext.PublishAsyncPending()
+}
+
+func (b *natsBridge) PublishCore(msg *nats.Msg) error {
+	return b.connection.PublishMsg(msg)
 }
 
 func (b *natsBridge) EnsureStreamExists(streamConfig *nats.StreamConfig) error {
@@ -55,39 +155,409 @@ func (b *natsBridge) EnsureStreamExists(streamConfig *nats.StreamConfig) error {
 		if err != nats.ErrStreamNotFound {
 			return fmt.Errorf("NATS streamInfo-info could not be fetched: %w", err)
 		}
-		b.logger.Info("Stream not found, about to add stream.", slog.String("name", streamConfig.Name))
+		b.logger.Info("Stream not found, about to add stream.", slog.String("stream", streamConfig.Name))
 
 		_, err = b.jetStreamContext.AddStream(streamConfig)
 		if err != nil {
 			return fmt.Errorf("streamInfo %s could not be added: %w", streamConfig.Name, err)
 		}
-		b.logger.Info("Added new NATS streamInfo", slog.String("name", streamConfig.Name))
+		b.logger.Info("Added new NATS streamInfo", slog.String("stream", streamConfig.Name))
 	}
 	return nil
 }
 
-func (b *natsBridge) Subscribe(subject, consumerName string, mode SubscriptionMode) (*nats.Subscription, error) {
-	var maxAckPending int
-	switch mode {
-	case MultipleSubscribersAllowed:
-		maxAckPending = natsServer.JsDefaultMaxAckPending
-	case SingleSubscriberStrictMessageOrder:
-		maxAckPending = 1
-	default:
-		maxAckPending = natsServer.JsDefaultMaxAckPending
+func (b *natsBridge) StreamInfo(streamName string) (*nats.StreamInfo, error) {
+	return b.jetStreamContext.StreamInfo(streamName)
+}
+
+func (b *natsBridge) UpdateStream(streamConfig *nats.StreamConfig) error {
+	_, err := b.jetStreamContext.UpdateStream(streamConfig)
+	return err
+}
+
+func (b *natsBridge) DeleteStream(streamName string) error {
+	return b.jetStreamContext.DeleteStream(streamName)
+}
+
+func (b *natsBridge) PurgeStream(streamName string, request *nats.StreamPurgeRequest) error {
+	return b.jetStreamContext.PurgeStream(streamName, request)
+}
+
+// GetMsg uses direct get, which is answered by all replicas of a stream. If the stream does not allow direct get,
+// the message is requested from the stream leader instead.
+func (b *natsBridge) GetMsg(streamName string, seq uint64) (*nats.RawStreamMsg, error) {
+	opts, err := b.getMsgOpts(streamName)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := b.jetStreamContext.GetMsg(streamName, seq, opts...)
+	b.checkGetMsgErr(streamName, err)
+	return msg, err
+}
+
+// GetLastMsg uses direct get like GetMsg.
+func (b *natsBridge) GetLastMsg(streamName, subject string) (*nats.RawStreamMsg, error) {
+	opts, err := b.getMsgOpts(streamName)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := b.jetStreamContext.GetLastMsg(streamName, subject, opts...)
+	b.checkGetMsgErr(streamName, err)
+	return msg, err
+}
+
+// GetNextMsg uses direct get like GetMsg. If the stream does not allow direct get, the message is requested from
+// the stream leader with a JetStream API request, since the JetStream context filters by subject only for direct get.
+func (b *natsBridge) GetNextMsg(streamName, subject string, seq uint64) (*nats.RawStreamMsg, error) {
+	opts, err := b.getMsgOpts(streamName)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts) > 0 {
+		msg, err := b.jetStreamContext.GetMsg(streamName, seq, append(opts, nats.DirectGetNext(subject))...)
+		b.checkGetMsgErr(streamName, err)
+		return msg, err
+	}
+
+	var resp struct {
+		Message struct {
+			Subject  string    `json:"subject"`
+			Sequence uint64    `json:"seq"`
+			Header   []byte    `json:"hdrs"`
+			Data     []byte    `json:"data"`
+			Time     time.Time `json:"time"`
+		} `json:"message"`
 	}
+	req := map[string]any{"seq": seq, "next_by_subj": subject}
+	if err := b.apiRequest(b.apiSubject("STREAM.MSG.GET."+streamName), req, &resp, defaultAPITimeout); err != nil {
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			return nil, nats.ErrMsgNotFound
+		}
+		b.checkGetMsgErr(streamName, err)
+		return nil, err
+	}
+	msg := &nats.RawStreamMsg{
+		Subject:  resp.Message.Subject,
+		Sequence: resp.Message.Sequence,
+		Data:     resp.Message.Data,
+		Time:     resp.Message.Time,
+	}
+	if len(resp.Message.Header) > 0 {
+		if msg.Header, err = nats.DecodeHeadersMsg(resp.Message.Header); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
 
-	return b.jetStreamContext.PullSubscribe(subject, consumerName,
+// apiSubject returns the subject of a JetStream API request like "STREAM.SNAPSHOT.ORDERS" in the domain of
+// the bridge.
+func (b *natsBridge) apiSubject(request string) string {
+	if b.domain != "" {
+		return "$JS." + b.domain + ".API." + request
+	}
+	return "$JS.API." + request
+}
+
+// apiRequest sends a JetStream API request and decodes the response into resp. It returns the error of the
+// response, if any.
+func (b *natsBridge) apiRequest(subject string, req any, resp any, timeout time.Duration) error {
+	var data []byte
+	if req != nil {
+		var err error
+		if data, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+	msg, err := b.connection.Request(subject, data, timeout)
+	if err != nil {
+		return err
+	}
+	var apiResp struct {
+		Error *nats.APIError `json:"error"`
+	}
+	if err := json.Unmarshal(msg.Data, &apiResp); err != nil {
+		return fmt.Errorf("invalid response of %s: %w", subject, err)
+	}
+	if apiResp.Error != nil {
+		return apiResp.Error
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.Unmarshal(msg.Data, resp)
+}
+
+// streamBackup is the first line of a backup written by SnapshotStream, followed by the snapshot data.
+type streamBackup struct {
+	Config json.RawMessage `json:"config"`
+	State  json.RawMessage `json:"state"`
+}
+
+// SnapshotStream requests a snapshot, which the server sends in chunks to an inbox. Each chunk is acknowledged
+// for flow control, and an empty message marks the end of the snapshot.
+func (b *natsBridge) SnapshotStream(streamName string, w io.Writer, timeout time.Duration) error {
+	inbox := b.connection.NewInbox()
+	sub, err := b.connection.SubscribeSync(inbox)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	var backup streamBackup
+	req := map[string]any{"deliver_subject": inbox}
+	if err := b.apiRequest(b.apiSubject("STREAM.SNAPSHOT."+streamName), req, &backup, timeout); err != nil {
+		return err
+	}
+	header, err := json.Marshal(backup)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(header, '\n')); err != nil {
+		return err
+	}
+
+	for {
+		chunk, err := sub.NextMsg(timeout)
+		if err != nil {
+			return fmt.Errorf("snapshot chunk could not be received: %w", err)
+		}
+		if len(chunk.Data) == 0 {
+			return nil
+		}
+		if _, err := w.Write(chunk.Data); err != nil {
+			return err
+		}
+		if chunk.Reply != "" {
+			if err := chunk.Respond(nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// restoreChunkSize is the size of the chunks sent to the server by RestoreStream.
+const restoreChunkSize = 128 * 1024
+
+// RestoreStream requests a restore and sends the snapshot in chunks to the subject returned by the server.
+// Each chunk is acknowledged by the server, and an empty message completes the restore.
+func (b *natsBridge) RestoreStream(streamName string, r io.Reader, timeout time.Duration) error {
+	reader := bufio.NewReader(r)
+	header, err := reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("backup header could not be read: %w", err)
+	}
+	var backup streamBackup
+	if err := json.Unmarshal(header, &backup); err != nil {
+		return fmt.Errorf("backup header could not be read: %w", err)
+	}
+	var config struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(backup.Config, &config); err != nil {
+		return fmt.Errorf("stream config of backup could not be read: %w", err)
+	}
+	if config.Name != streamName { // the snapshot data contains the stream name, so it can't be renamed
+		return fmt.Errorf("backup contains stream %s", config.Name)
+	}
+
+	var resp struct {
+		DeliverSubject string `json:"deliver_subject"`
+	}
+	if err := b.apiRequest(b.apiSubject("STREAM.RESTORE."+streamName), backup, &resp, timeout); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, restoreChunkSize)
+	for {
+		n, err := io.ReadFull(reader, chunk)
+		if n > 0 {
+			if _, err := b.connection.Request(resp.DeliverSubject, chunk[:n], timeout); err != nil {
+				return fmt.Errorf("snapshot chunk could not be sent: %w", err)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	// The server validates and stores the stream after the last chunk, which may take a while.
+	return b.apiRequest(resp.DeliverSubject, nil, nil, timeout*10)
+}
+
+// jsErrCodeStreamMsgDeleteFailed is returned by the server, if a message could not be deleted.
+const jsErrCodeStreamMsgDeleteFailed nats.ErrorCode = 10057
+
+func (b *natsBridge) DeleteMsg(streamName string, seq uint64, secure bool) error {
+	var err error
+	if secure {
+		err = b.jetStreamContext.SecureDeleteMsg(streamName, seq)
+	} else {
+		err = b.jetStreamContext.DeleteMsg(streamName, seq)
+	}
+	// The server reports a missing message with a generic error code, unlike GetMsg.
+	var apiErr *nats.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode == jsErrCodeStreamMsgDeleteFailed && apiErr.Description == "no message found" {
+		return fmt.Errorf("%w: %w", nats.ErrMsgNotFound, err)
+	}
+	return err
+}
+
+// getMsgOpts returns nats.DirectGet, if the stream allows it. A direct get of a stream, which does not allow it,
+// is not answered until the request times out.
+func (b *natsBridge) getMsgOpts(streamName string) ([]nats.JSOpt, error) {
+	allowDirect, ok := b.allowDirect.Load(streamName)
+	if !ok {
+		info, err := b.jetStreamContext.StreamInfo(streamName)
+		if err != nil {
+			return nil, err
+		}
+		allowDirect = info.Config.AllowDirect
+		b.allowDirect.Store(streamName, allowDirect)
+	}
+	if allowDirect.(bool) {
+		return []nats.JSOpt{nats.DirectGet()}, nil
+	}
+	return nil, nil
+}
+
+// checkGetMsgErr forgets whether the stream allows direct get after an unexpected error, since the stream
+// might have been updated or deleted.
+func (b *natsBridge) checkGetMsgErr(streamName string, err error) {
+	if err != nil && !errors.Is(err, nats.ErrMsgNotFound) {
+		b.allowDirect.Delete(streamName)
+	}
+}
+
+func (b *natsBridge) Consumers(streamName string) ([]*nats.ConsumerInfo, error) {
+	// Consumers does not report errors, so a missing stream would result in an empty list.
+	if _, err := b.jetStreamContext.StreamInfo(streamName); err != nil {
+		return nil, err
+	}
+	var consumers []*nats.ConsumerInfo
+	for info := range b.jetStreamContext.Consumers(streamName) {
+		consumers = append(consumers, info)
+	}
+	return consumers, nil
+}
+
+func (b *natsBridge) ConsumerInfo(streamName, consumerName string) (*nats.ConsumerInfo, error) {
+	return b.jetStreamContext.ConsumerInfo(streamName, consumerName)
+}
+
+func (b *natsBridge) EnsureConsumer(streamName string, consumerConfig *nats.ConsumerConfig) error {
+	_, err := b.jetStreamContext.ConsumerInfo(streamName, consumerConfig.Durable)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		_, err = b.jetStreamContext.AddConsumer(streamName, consumerConfig)
+		return err
+	} else if err != nil {
+		return err
+	}
+	_, err = b.jetStreamContext.UpdateConsumer(streamName, consumerConfig)
+	return err
+}
+
+// PauseConsumer uses the jetstream package, since nats.JetStreamContext does not support pausing consumers.
+func (b *natsBridge) PauseConsumer(streamName, consumerName string, until time.Time) error {
+	var js jetstream.JetStream
+	var err error
+	if b.domain != "" {
+		js, err = jetstream.NewWithDomain(b.connection, b.domain)
+	} else {
+		js, err = jetstream.New(b.connection)
+	}
+	if err != nil {
+		return err
+	}
+
+	if until.IsZero() {
+		_, err = js.ResumeConsumer(context.Background(), streamName, consumerName)
+	} else {
+		_, err = js.PauseConsumer(context.Background(), streamName, consumerName, until)
+	}
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return fmt.Errorf("%w: %w", nats.ErrConsumerNotFound, err)
+	}
+	return err
+}
+
+func (b *natsBridge) DeleteConsumer(streamName, consumerName string) error {
+	return b.jetStreamContext.DeleteConsumer(streamName, consumerName)
+}
+
+func (b *natsBridge) Subscribe(args SubscriberArgs) (*nats.Subscription, error) {
+	options := []nats.SubOpt{
 		nats.AckExplicit(),
-		nats.MaxAckPending(maxAckPending),
-		nats.AckWait(defaultAckWait),
-	)
+		nats.MaxAckPending(args.MaxAckPending),
+		nats.AckWait(args.AckWait),
+	}
+	if args.MaxDeliver > 0 {
+		options = append(options, nats.MaxDeliver(args.MaxDeliver))
+	}
+	if args.InactiveThreshold > 0 {
+		options = append(options, nats.InactiveThreshold(args.InactiveThreshold))
+	}
+	// an unset policy is not passed, so existing consumers are bound regardless of their policy
+	switch args.DeliverPolicy {
+	case DeliverAll:
+		options = append(options, nats.DeliverAll())
+	case DeliverNew:
+		options = append(options, nats.DeliverNew())
+	case DeliverLastPerSubject:
+		options = append(options, nats.DeliverLastPerSubject())
+	case DeliverByStartSequence:
+		options = append(options, nats.StartSequence(args.StartSequence))
+	case DeliverByStartTime:
+		options = append(options, nats.StartTime(args.StartTime))
+	}
+	if len(args.Subjects) > 0 {
+		options = append(options, nats.ConsumerFilterSubjects(args.Subjects...), nats.BindStream(args.streamName()))
+	}
+	return b.jetStreamContext.PullSubscribe(args.Subject, args.ConsumerName, options...)
 }
 
 func (b *natsBridge) Servers() []string {
 	return b.connection.Servers()
 }
 
+func (b *natsBridge) MaxPayload() int64 {
+	return b.connection.MaxPayload()
+}
+
 func (b *natsBridge) Drain() error {
+	if b.external {
+		return nil
+	}
 	return b.connection.Drain()
 }
+
+// Close closes the connection forcefully, even if it is external, since it is only called when draining timed out.
+func (b *natsBridge) Close() {
+	b.connection.Close()
+}
+
+func (b *natsBridge) Status() nats.Status {
+	return b.connection.Status()
+}
+
+func (b *natsBridge) Ping(ctx context.Context) (time.Duration, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultPingTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	if err := b.connection.FlushWithContext(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+func (b *natsBridge) JetStream() (nats.JetStreamContext, error) {
+	if b.jetStreamContext == nil {
+		return nil, fmt.Errorf("JetStream context is not available")
+	}
+	return b.jetStreamContext, nil
+}
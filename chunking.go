@@ -0,0 +1,145 @@
+package vnats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+)
+
+const (
+	headerChunkID    = "Vnats-Chunk-Id"
+	headerChunkIndex = "Vnats-Chunk-Index"
+	headerChunkCount = "Vnats-Chunk-Count"
+
+	// chunkHeaderReserve is the number of bytes of the max payload reserved for the NATS protocol and chunk headers.
+	chunkHeaderReserve = 512
+)
+
+// chunk is a NATS message to be published with its MsgID.
+type chunk struct {
+	msg   *nats.Msg
+	msgID string
+}
+
+// splitChunks splits natsMsg into ordered chunks, if its data exceeds the max payload of the server.
+// Otherwise, natsMsg is returned as the only chunk. Each chunk contains the original header, expectations
+// are only applied to the first chunk.
+func (p *Publisher) splitChunks(natsMsg *nats.Msg, msgID string) []chunk {
+	chunkSize := int(p.conn.nats.MaxPayload()) - headerSize(natsMsg.Header) - chunkHeaderReserve
+	if chunkSize <= 0 || len(natsMsg.Data) <= chunkSize {
+		return []chunk{{msg: natsMsg, msgID: msgID}}
+	}
+
+	chunkID := msgID
+	if chunkID == "" {
+		chunkID = nuid.Next()
+	}
+	count := (len(natsMsg.Data) + chunkSize - 1) / chunkSize
+
+	chunks := make([]chunk, 0, count)
+	for i := 0; i < count; i++ {
+		header := nats.Header{}
+		for key, values := range natsMsg.Header {
+			header[key] = append([]string(nil), values...)
+		}
+		if i > 0 {
+			header.Del(nats.ExpectedLastMsgIdHdr)
+			header.Del(nats.ExpectedLastSeqHdr)
+			header.Del(nats.ExpectedLastSubjSeqHdr)
+		}
+		header.Set(headerChunkID, chunkID)
+		header.Set(headerChunkIndex, strconv.Itoa(i))
+		header.Set(headerChunkCount, strconv.Itoa(count))
+
+		chunkMsgID := ""
+		if msgID != "" {
+			chunkMsgID = fmt.Sprintf("%s-chunk-%d", msgID, i)
+		}
+
+		end := min((i+1)*chunkSize, len(natsMsg.Data))
+		chunks = append(chunks, chunk{
+			msg: &nats.Msg{
+				Subject: natsMsg.Subject,
+				Reply:   natsMsg.Reply,
+				Data:    natsMsg.Data[i*chunkSize : end],
+				Header:  header,
+			},
+			msgID: chunkMsgID,
+		})
+	}
+	return chunks
+}
+
+func headerSize(h nats.Header) int {
+	size := 0
+	for key, values := range h {
+		for _, value := range values {
+			size += len(key) + len(value) + 4 // ": " and "\r\n"
+		}
+	}
+	return size
+}
+
+// reassembleChunks reassembles the chunked message, whose first chunk is first. The remaining chunks are read
+// from the stream, since they are delivered to the consumer only after the first chunk is acknowledged: with
+// SingleSubscriberStrictMessageOrder, at most one message is pending. So no chunk is acknowledged before the
+// reassembled message, which survives a restart of the Subscriber. Only messages with the subject of the first
+// chunk are read, until all chunks were found.
+func (s *Subscriber) reassembleChunks(first Msg) (Msg, error) {
+	return reassembleChunks(first, func(seq uint64) (*nats.RawStreamMsg, error) {
+		return s.conn.nats.GetNextMsg(first.Metadata.Stream, first.Subject, seq)
+	})
+}
+
+// reassembleChunks reassembles the chunked message, whose first chunk is first, with the remaining chunks
+// returned by getNextMsg, which returns the next message with the subject of first from the stream sequence seq.
+func reassembleChunks(first Msg, getNextMsg func(seq uint64) (*nats.RawStreamMsg, error)) (Msg, error) {
+	chunkID := first.Header.Get(headerChunkID)
+	count, err := strconv.Atoi(first.Header.Get(headerChunkCount))
+	if err != nil || count < 1 {
+		return Msg{}, fmt.Errorf("chunk %s has invalid count %q", chunkID, first.Header.Get(headerChunkCount))
+	}
+
+	parts := make([][]byte, count)
+	parts[0] = first.Data
+	received := 1
+	for seq := first.Metadata.StreamSequence + 1; received < count; {
+		rawMsg, err := getNextMsg(seq)
+		if errors.Is(err, nats.ErrMsgNotFound) { // not stored yet
+			break
+		} else if err != nil {
+			return Msg{}, fmt.Errorf("chunk %s could not be fetched: %w", chunkID, err)
+		}
+		seq = rawMsg.Sequence + 1
+		if rawMsg.Header.Get(headerChunkID) != chunkID {
+			continue
+		}
+		index, err := strconv.Atoi(rawMsg.Header.Get(headerChunkIndex))
+		if err != nil || index < 1 || index >= count {
+			return Msg{}, fmt.Errorf("chunk %s has invalid index %q", chunkID, rawMsg.Header.Get(headerChunkIndex))
+		}
+		if parts[index] == nil {
+			parts[index] = rawMsg.Data
+			received++
+		}
+	}
+	if received < count {
+		return Msg{}, fmt.Errorf("chunk %s is incomplete, %d of %d chunks are stored", chunkID, received, count)
+	}
+
+	complete := first
+	complete.Data = bytes.Join(parts, nil)
+	complete.Header = complete.Header.clone()
+	complete.Header.Del(headerChunkID)
+	complete.Header.Del(headerChunkIndex)
+	complete.Header.Del(headerChunkCount)
+	if complete.MsgID != "" { // chunks have a MsgID, if the message has one, which is used as chunkID
+		complete.MsgID = chunkID
+		complete.Header.Set(nats.MsgIdHdr, chunkID)
+	}
+	return complete, nil
+}
@@ -0,0 +1,243 @@
+package vnats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPublisher_splitChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		dataSize  int
+		msgID     string
+		wantCount int
+	}{
+		{name: "fits into max payload", dataSize: 100, msgID: "msg-001", wantCount: 1},
+		{name: "chunked", dataSize: 5000, msgID: "msg-001", wantCount: 4},
+		{name: "chunked without msgID", dataSize: 5000, wantCount: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "MESSAGES", 1, nil, "", nil)
+			conn.nats.(*testBridge).maxPayload = 2048
+			pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+
+			data := bytes.Repeat([]byte("0123456789"), tt.dataSize/10)
+			msg := NewMsg("MESSAGES.large", tt.msgID, data)
+			msg.Header = Header{"Trace-Id": []string{"trace-001"}}
+			chunks := pub.splitChunks(msg.toNATS(), msg.MsgID)
+			if len(chunks) != tt.wantCount {
+				t.Fatalf("splitChunks() returned %d chunks, want %d", len(chunks), tt.wantCount)
+			}
+			if tt.wantCount == 1 {
+				return
+			}
+
+			var stored []*nats.RawStreamMsg
+			for i, c := range chunks {
+				if tt.msgID != "" && c.msgID == tt.msgID {
+					t.Errorf("chunk %d must not reuse the msgID of the message", i)
+				}
+				c.msg.Header.Set(nats.MsgIdHdr, c.msgID) // set by the server on received messages
+				stored = append(stored, &nats.RawStreamMsg{Sequence: uint64(i + 1), Header: c.msg.Header, Data: c.msg.Data})
+			}
+			first := makeMsg(chunks[0].msg)
+			first.Metadata.StreamSequence = 1
+
+			got, err := reassembleChunks(first, func(seq uint64) (*nats.RawStreamMsg, error) {
+				if seq > uint64(len(stored)) {
+					return nil, nats.ErrMsgNotFound
+				}
+				return stored[seq-1], nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Data, data) {
+				t.Error("reassembled data differs from the published data")
+			}
+			if got.MsgID != tt.msgID {
+				t.Errorf("reassembled MsgID = %q, want %q", got.MsgID, tt.msgID)
+			}
+			if got.Header.Get("Trace-Id") != "trace-001" || got.Header.Get(headerChunkID) != "" {
+				t.Errorf("reassembled header = %v", got.Header)
+			}
+		})
+	}
+}
+
+func Test_reassembleChunks(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 1, nil, "", nil)
+	conn.nats.(*testBridge).maxPayload = 2048
+	pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+	msg := NewMsg("MESSAGES.large", "msg-001", bytes.Repeat([]byte("a"), 2000))
+	chunks := pub.splitChunks(msg.toNATS(), msg.MsgID)
+	if len(chunks) != 2 {
+		t.Fatalf("splitChunks() returned %d chunks, want 2", len(chunks))
+	}
+	first := makeMsg(chunks[0].msg)
+	first.Metadata.StreamSequence = 10
+	other := &nats.RawStreamMsg{Subject: "MESSAGES.large", Data: []byte("other")}
+	otherSubject := &nats.RawStreamMsg{Subject: "MESSAGES.other", Data: []byte("other")}
+	last := &nats.RawStreamMsg{Subject: "MESSAGES.large", Header: chunks[1].msg.Header, Data: chunks[1].msg.Data}
+
+	tests := []struct {
+		name        string
+		stored      map[uint64]*nats.RawStreamMsg
+		wantFetches int
+		wantErr     bool
+	}{
+		{name: "next message", stored: map[uint64]*nats.RawStreamMsg{11: last}, wantFetches: 1},
+		{
+			name:        "messages of other publishers and deleted messages in between",
+			stored:      map[uint64]*nats.RawStreamMsg{11: other, 12: otherSubject, 14: last, 15: other},
+			wantFetches: 2,
+		},
+		{name: "last chunk not stored yet", stored: map[uint64]*nats.RawStreamMsg{11: other}, wantFetches: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches int
+			got, err := reassembleChunks(first, func(seq uint64) (*nats.RawStreamMsg, error) {
+				fetches++
+				for next := seq; next <= seq+10; next++ {
+					if msg, ok := tt.stored[next]; ok && msg.Subject == first.Subject {
+						msg.Sequence = next
+						return msg, nil
+					}
+				}
+				return nil, nats.ErrMsgNotFound
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reassembleChunks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got.Data, msg.Data) {
+				t.Error("reassembled data differs from the published data")
+			}
+			if fetches != tt.wantFetches {
+				t.Errorf("reassembleChunks() fetched %d messages, want %d", fetches, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestPublisher_Publish_Chunking(t *testing.T) {
+	tests := []struct {
+		name          string
+		chunking      bool
+		wantPublishes uint64
+	}{
+		{name: "disabled", wantPublishes: 1},
+		{name: "enabled", chunking: true, wantPublishes: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bridge := &recordingBridge{testBridge: testBridge{TB: t, streamName: "MESSAGES", maxPayload: 2048}}
+			pub := &Publisher{conn: &Connection{nats: bridge, logger: slog.Default()}, streamName: "MESSAGES", chunking: tt.chunking}
+
+			if _, err := pub.Publish(NewMsg("MESSAGES.large", "msg-001", bytes.Repeat([]byte("a"), 5000))); err != nil {
+				t.Fatal(err)
+			}
+			if bridge.sequenceNumber != tt.wantPublishes {
+				t.Errorf("Publish() published %d messages, want %d", bridge.sequenceNumber, tt.wantPublishes)
+			}
+		})
+	}
+}
+
+func TestPublisher_Publish_Chunked(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".chunked"
+	conn := makeIntegrationTestConn(t)
+	data := bytes.Repeat([]byte("large payload "), int(conn.nats.MaxPayload())/5)
+	unchunked, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unchunked.Publish(NewMsg(subject, "large-msg-000", data)); err == nil {
+		t.Fatal("Publish() without Chunking succeeded, want the server to reject the message")
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName, Chunking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pub.Publish(NewMsg(subject, "large-msg-001", data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pub.Publish(NewMsg(subject, "small-msg-002", []byte("small"))); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: "TestPublishChunked",
+		Subject:      subject,
+		Mode:         SingleSubscriberStrictMessageOrder,
+		Backoff:      []time.Duration{time.Millisecond * 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan Msg, 3)
+	if err := sub.Start(func(msg Msg) error {
+		received <- msg
+		if msg.Metadata.NumDelivered == 1 && msg.MsgID == "large-msg-001" {
+			return errors.New("redeliver the chunked message")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, wantMsgID := range []string{"large-msg-001", "large-msg-001", "small-msg-002"} {
+		select {
+		case msg := <-received:
+			if msg.MsgID != wantMsgID {
+				t.Fatalf("received %s, want %s", msg.MsgID, wantMsgID)
+			}
+			if wantMsgID == "large-msg-001" && !bytes.Equal(msg.Data, data) {
+				t.Errorf("received %d bytes, want %d bytes", len(msg.Data), len(data))
+			}
+		case <-time.After(time.Second * 10):
+			t.Fatalf("%s was not received", wantMsgID)
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriber_Chunked_MultipleSubscribersAllowed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".chunkedmulti"
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName, Chunking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("large payload "), int(conn.nats.MaxPayload())/10)
+	if _, err := pub.Publish(NewMsg(subject, "large-msg-001", data)); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := createSubscriber(t, conn, "TestChunkedMulti", subject, MultipleSubscribersAllowed)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	if msg, _, err := sub.Next(ctx); err == nil {
+		t.Fatalf("Next() returned %s, chunks must be terminated without SingleSubscriberStrictMessageOrder", msg.MsgID)
+	}
+	if stats := sub.Stats(); stats.Termed != 2 {
+		t.Errorf("Stats() Termed = %d, want 2 chunks", stats.Termed)
+	}
+}
@@ -0,0 +1,155 @@
+package vnats
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/nats-io/nats.go"
+)
+
+// HeaderContentEncoding is the header key of the compression codec of the message data. It is prefixed like the
+// other control headers of vnats, so a Content-Encoding header of other producers is passed to the handler as is.
+const HeaderContentEncoding = "Vnats-Content-Encoding"
+
+// CompressionCodec is the algorithm used to compress message data.
+type CompressionCodec string
+
+const (
+	// CompressionGzip compresses with gzip, which is widely supported, but slower than the other codecs.
+	CompressionGzip CompressionCodec = "gzip"
+	// CompressionS2 compresses with S2, which is very fast with a moderate compression ratio.
+	CompressionS2 CompressionCodec = "s2"
+	// CompressionZstd compresses with Zstandard, which has a high compression ratio.
+	CompressionZstd CompressionCodec = "zstd"
+)
+
+// errDecompressedTooLarge is returned by decompress, if the decompressed data exceeds the limit.
+var errDecompressedTooLarge = errors.New("decompressed data exceeds the limit")
+
+type compressionConfig struct {
+	codec     CompressionCodec
+	threshold int
+
+	// maxDecompressedSize is set by WithMaxDecompressedSize, defaultMaxDecompressedSize if zero.
+	maxDecompressedSize int
+}
+
+func (cfg compressionConfig) decompressionLimit() int {
+	return cmp.Or(cfg.maxDecompressedSize, defaultMaxDecompressedSize)
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+)
+
+// sharedZstdEncoder returns the shared zstd encoder, which is safe for concurrent use with EncodeAll.
+func sharedZstdEncoder() *zstd.Encoder {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+	})
+	return zstdEncoder
+}
+
+func compress(codec CompressionCodec, data []byte) ([]byte, error) {
+	switch codec {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionS2:
+		return s2.Encode(nil, data), nil
+	case CompressionZstd:
+		return sharedZstdEncoder().EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown compression codec %q", codec)
+	}
+}
+
+// decompress decompresses data, which must not exceed limit bytes after decompression.
+func decompress(codec CompressionCodec, data []byte, limit int) ([]byte, error) {
+	switch codec {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readLimited(r, limit)
+	case CompressionS2:
+		n, err := s2.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if n > limit {
+			return nil, errDecompressedTooLarge
+		}
+		return s2.Decode(nil, data)
+	case CompressionZstd:
+		r, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readLimited(r, limit)
+	default:
+		return nil, fmt.Errorf("unknown compression codec %q", codec)
+	}
+}
+
+// readLimited reads r until EOF, but at most limit bytes.
+func readLimited(r io.Reader, limit int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, errDecompressedTooLarge
+	}
+	return data, nil
+}
+
+// compressMsg compresses the data of natsMsg, if compression is enabled and the data reaches the threshold.
+func compressMsg(cfg compressionConfig, natsMsg *nats.Msg, msgID string) error {
+	if cfg.codec == "" || len(natsMsg.Data) < cfg.threshold {
+		return nil
+	}
+
+	data, err := compress(cfg.codec, natsMsg.Data)
+	if err != nil {
+		return fmt.Errorf("message with msgID: %s @ %s could not be compressed: %w", msgID, natsMsg.Subject, err)
+	}
+	natsMsg.Data = data
+	natsMsg.Header.Set(HeaderContentEncoding, string(cfg.codec))
+	return nil
+}
+
+// decompressMsg decompresses the data of msg, if it was compressed by a Publisher, and removes the
+// HeaderContentEncoding header. The error is permanent, since the data is the same on each delivery.
+func decompressMsg(cfg compressionConfig, msg *Msg) error {
+	codec := msg.Header.Get(HeaderContentEncoding)
+	if codec == "" {
+		return nil
+	}
+
+	data, err := decompress(CompressionCodec(codec), msg.Data, cfg.decompressionLimit())
+	if err != nil {
+		return fmt.Errorf("message with msgID: %s @ %s could not be decompressed: %w", msg.MsgID, msg.Subject, err)
+	}
+	msg.Data = data
+	msg.Header.Del(HeaderContentEncoding)
+	return nil
+}
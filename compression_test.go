@@ -0,0 +1,151 @@
+package vnats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestCompression_RoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("compressible payload "), 100)
+
+	for _, codec := range []CompressionCodec{CompressionGzip, CompressionS2, CompressionZstd} {
+		t.Run(string(codec), func(t *testing.T) {
+			compressed, err := compress(codec, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(compressed) >= len(data) {
+				t.Errorf("compress() len = %d, want less than %d", len(compressed), len(data))
+			}
+
+			got, err := decompress(codec, compressed, len(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("decompress() does not return the original data")
+			}
+			if _, err := decompress(codec, compressed, len(data)-1); !errors.Is(err, errDecompressedTooLarge) {
+				t.Errorf("decompress() exceeding the limit returned error %v, want errDecompressedTooLarge", err)
+			}
+		})
+	}
+}
+
+func Test_decompressMsg_UnknownCodec(t *testing.T) {
+	msg := NewMsg("MESSAGES.compressed", "msg-001", []byte("data"))
+	msg.Header = Header{}
+	msg.Header.Set(HeaderContentEncoding, "br")
+	if err := decompressMsg(compressionConfig{}, msg); err == nil {
+		t.Error("decompressMsg() with unknown HeaderContentEncoding should return an error")
+	}
+}
+
+func Test_decompressMsg_ForeignContentEncoding(t *testing.T) {
+	msg := NewMsg("MESSAGES.compressed", "msg-001", []byte("data"))
+	msg.Header = Header{}
+	msg.Header.Set("Content-Encoding", "br")
+	if err := decompressMsg(compressionConfig{}, msg); err != nil {
+		t.Fatalf("decompressMsg() of a message of another producer returned error %v", err)
+	}
+	if string(msg.Data) != "data" || msg.Header.Get("Content-Encoding") != "br" {
+		t.Errorf("decompressMsg() changed the message of another producer: %+v", msg)
+	}
+}
+
+func TestWithMaxDecompressedSize(t *testing.T) {
+	conn := &Connection{}
+	WithMaxDecompressedSize(1024)(conn)
+	WithCompression(CompressionS2, 512)(conn)
+	if got := conn.compression.decompressionLimit(); got != 1024 {
+		t.Errorf("decompressionLimit() = %d, want 1024", got)
+	}
+	if got := (compressionConfig{}).decompressionLimit(); got != defaultMaxDecompressedSize {
+		t.Errorf("decompressionLimit() without option = %d, want %d", got, defaultMaxDecompressedSize)
+	}
+
+	WithMaxDecompressedSize(0)(conn)
+	if len(conn.optionErrs) == 0 {
+		t.Error("WithMaxDecompressedSize() with zero size should record an option error")
+	}
+}
+
+func TestSubscriber_UnknownContentEncoding(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".encoded"
+	conn := makeIntegrationTestConn(t)
+	msg := &nats.Msg{Subject: subject, Data: []byte("data"), Header: nats.Header{}}
+	msg.Header.Set(HeaderContentEncoding, "br")
+	if _, err := conn.nats.PublishMsg(context.Background(), msg, "encoded-001"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := createSubscriber(t, conn, "TestUnknownContentEncoding", subject, MultipleSubscribersAllowed)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	if got, _, err := sub.Next(ctx); err == nil {
+		t.Fatalf("Next() returned %s, messages with unknown HeaderContentEncoding must be terminated", got.MsgID)
+	}
+	if stats := sub.Stats(); stats.Termed != 1 || stats.Naked != 0 {
+		t.Errorf("Stats() Termed = %d, Naked = %d, want the message terminated once", stats.Termed, stats.Naked)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPublisher_natsMsg_Compression(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           []byte
+		wantCompressed bool
+	}{
+		{name: "below threshold", data: []byte("small"), wantCompressed: false},
+		{name: "above threshold", data: bytes.Repeat([]byte("a"), 1024), wantCompressed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "MESSAGES", 1, nil, "", nil)
+			WithCompression(CompressionS2, 512)(conn)
+			pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+
+			msg := NewMsg("MESSAGES.compressed", "msg-001", tt.data)
+			natsMsg, err := pub.natsMsg(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := natsMsg.Header.Get(HeaderContentEncoding) != ""; got != tt.wantCompressed {
+				t.Errorf("natsMsg() compressed = %v, want %v", got, tt.wantCompressed)
+			}
+			if !bytes.Equal(msg.Data, tt.data) {
+				t.Error("natsMsg() must not modify the data of msg")
+			}
+
+			received := makeMsg(natsMsg)
+			if err := decompressMsg(conn.compression, &received); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(received.Data, tt.data) {
+				t.Error("decompressMsg() does not return the original data")
+			}
+			if received.Header.Get(HeaderContentEncoding) != "" {
+				t.Error("decompressMsg() must remove the HeaderContentEncoding header")
+			}
+		})
+	}
+}
+
+func TestWithCompression_UnknownCodec(t *testing.T) {
+	conn := &Connection{}
+	WithCompression("brotli", 0)(conn)
+	if len(conn.optionErrs) == 0 {
+		t.Error("WithCompression() with unknown codec should record an option error")
+	}
+}
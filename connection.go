@@ -1,12 +1,19 @@
 package vnats
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"golang.org/x/time/rate"
 )
 
 // SubscriptionMode defines how the consumer and its Subscriber are configured. This mode must be set accordingly
@@ -23,6 +30,38 @@ const (
 	// message returns error, the Subscriber of consumer will retry the failed message until resolved. This blocks the
 	// entire consumer, so that horizontal scaling is not effectively possible.
 	SingleSubscriberStrictMessageOrder
+
+	// SingleSubscriberOrderedPerSubject mode keeps the order of messages per subject, but handles messages of
+	// different subjects concurrently, see SubscriberArgs.Concurrency. If a message is NAKed, later messages of its
+	// subject are NAKed as well until it is redelivered. Each of these NAKs counts as a delivery towards
+	// SubscriberArgs.MaxDeliver, so MaxDeliver must leave room for the redeliveries of earlier messages of the
+	// subject, or be unlimited. Only one Subscriber of the consumer may be started, since the order can't be kept
+	// across Subscribers. NewSubscriber rejects a second Subscriber of the consumer on the same Connection, but
+	// Subscribers in other processes can't be detected.
+	SingleSubscriberOrderedPerSubject
+)
+
+// DeliverPolicy defines the first message delivered to a new consumer. An existing consumer continues where it
+// stopped. The zero value leaves the policy unset: a new consumer delivers all messages like DeliverAll, and a
+// Subscriber binds to an existing consumer regardless of its policy. A Subscriber with an explicitly set policy
+// can only bind to an existing consumer, which was created with the same policy.
+type DeliverPolicy int
+
+const (
+	// DeliverAll (default) delivers all messages of the stream, starting with the oldest.
+	DeliverAll DeliverPolicy = iota + 1
+
+	// DeliverNew delivers only messages, which are published after the consumer was created.
+	DeliverNew
+
+	// DeliverLastPerSubject delivers the last message of each subject and all messages published afterwards.
+	DeliverLastPerSubject
+
+	// DeliverByStartSequence delivers messages starting with the stream sequence SubscriberArgs.StartSequence.
+	DeliverByStartSequence
+
+	// DeliverByStartTime delivers messages published at or after SubscriberArgs.StartTime, e.g. for backfills.
+	DeliverByStartTime
 )
 
 // Config is a struct to hold the configuration of a NATS connection.
@@ -31,14 +70,72 @@ type Config struct {
 	Username string
 	Hosts    string
 	Port     int
+
+	// Scheme of the server URLs, like "nats", "tls", "ws" or "wss". Default is "nats".
+	Scheme string
 }
 
 // Connection is the main entry point for the library. It is used to create Publishers and Subscribers.
 // It is also used to close the connection to the NATS server/ cluster.
 type Connection struct {
-	nats        bridge
-	logger      *slog.Logger
-	subscribers []*Subscriber
+	nats         bridge
+	logger       *slog.Logger
+	mu           sync.Mutex // guards subscribers
+	subscribers  []*Subscriber
+	natsOptions  []nats.Option
+	jsOptions    []nats.JSOpt
+	jsDomain     string
+	streamPrefix string
+	hooks        ConnectionHooks
+	timeouts     TimeoutConfig
+
+	registeredOptions map[string]bool
+	optionErrs        []error
+
+	publishLimiter *rate.Limiter
+	msgIDGenerator MsgIDGenerator
+	compression    compressionConfig
+
+	publishValidator func(msg *Msg) error
+	publishedMsgIDs  *msgIDCache
+	strictTopology   bool
+	metrics          Metrics
+	tracer           Tracer
+
+	registrationMu sync.Mutex // guards registrations
+	registrations  []*subscriberRegistration
+}
+
+// ConnectionHooks contains optional callbacks, which are called on changes of the connectivity to the
+// NATS server/ cluster. All callbacks are called asynchronously and may be nil.
+type ConnectionHooks struct {
+	// OnDisconnect is called when the connection to the server is lost. err may be nil.
+	OnDisconnect func(err error)
+
+	// OnReconnect is called after the connection was re-established, url is the server connected to.
+	OnReconnect func(url string)
+
+	// OnClosed is called when the connection is closed permanently and no reconnect will be attempted.
+	OnClosed func()
+
+	// OnError is called for asynchronous errors, like slow consumers or permission violations.
+	OnError func(err error)
+
+	// OnConsumerRecreated is called after reconnecting, if the consumer of a Subscriber was not found on the server
+	// anymore and had to be recreated.
+	OnConsumerRecreated func(consumerName string)
+
+	// OnPanic is called after a panic in a go-routine of the library, e.g. in a MsgHandler or in one of the hooks,
+	// was recovered. source describes where the panic occurred, recovered is the value passed to panic.
+	OnPanic func(source string, recovered any)
+
+	// OnHandlerTimeout is called, if the handler of the Subscriber of consumerName exceeded the HandlerTimeout
+	// for msg, which was NAKed therefore.
+	OnHandlerTimeout func(consumerName string, msg Msg)
+
+	// OnLameDuck is called when the server connected to (url) enters lame duck mode, e.g. during a rolling upgrade.
+	// If other servers are known, the Connection reconnects to one of them afterwards.
+	OnLameDuck func(url string)
 }
 
 // bridge is required to use a mock for the nats functions in unit tests
@@ -47,15 +144,82 @@ type bridge interface {
 	// If not it will be added.
 	EnsureStreamExists(streamConfig *nats.StreamConfig) error
 
+	// StreamInfo returns the configuration and state of a stream, or nats.ErrStreamNotFound.
+	StreamInfo(streamName string) (*nats.StreamInfo, error)
+
+	// UpdateStream updates the configuration of an existing stream.
+	UpdateStream(streamConfig *nats.StreamConfig) error
+
+	// DeleteStream deletes a stream with all its messages and consumers.
+	DeleteStream(streamName string) error
+
+	// PurgeStream removes the messages of a stream selected by request, or all messages if request is nil.
+	PurgeStream(streamName string, request *nats.StreamPurgeRequest) error
+
+	// GetMsg returns the message of a stream with the stream sequence seq, or nats.ErrMsgNotFound.
+	GetMsg(streamName string, seq uint64) (*nats.RawStreamMsg, error)
+
+	// GetLastMsg returns the last message of a stream with the subject, or nats.ErrMsgNotFound.
+	GetLastMsg(streamName, subject string) (*nats.RawStreamMsg, error)
+
+	// GetNextMsg returns the first message of a stream with the subject and a stream sequence of at least seq,
+	// or nats.ErrMsgNotFound.
+	GetNextMsg(streamName, subject string, seq uint64) (*nats.RawStreamMsg, error)
+
+	// SnapshotStream writes the configuration, state and a snapshot of the data of a stream to w.
+	// timeout bounds each request and each chunk of the snapshot.
+	SnapshotStream(streamName string, w io.Writer, timeout time.Duration) error
+
+	// RestoreStream creates a stream from a snapshot written by SnapshotStream.
+	RestoreStream(streamName string, r io.Reader, timeout time.Duration) error
+
+	// DeleteMsg deletes the message of a stream with the stream sequence seq. If secure is true, the stored data
+	// is overwritten.
+	DeleteMsg(streamName string, seq uint64, secure bool) error
+
+	// Consumers returns the consumers of a stream, or nats.ErrStreamNotFound.
+	Consumers(streamName string) ([]*nats.ConsumerInfo, error)
+
+	// ConsumerInfo returns the configuration and state of a consumer, or nats.ErrConsumerNotFound.
+	ConsumerInfo(streamName, consumerName string) (*nats.ConsumerInfo, error)
+
+	// EnsureConsumer creates a durable consumer of a stream or updates it, if it already exists.
+	EnsureConsumer(streamName string, consumerConfig *nats.ConsumerConfig) error
+
+	// PauseConsumer pauses the delivery of messages by a consumer until the given time, or resumes it if until
+	// is zero.
+	PauseConsumer(streamName, consumerName string, until time.Time) error
+
+	// DeleteConsumer deletes a consumer of a stream.
+	DeleteConsumer(streamName, consumerName string) error
+
 	// Subscribe creates a natsSubscription, that can fetch messages from a specified subject.
 	// The first token, separated by dots, of a subject will be interpreted as the streamName.
-	Subscribe(subject, consumerName string, mode SubscriptionMode) (*nats.Subscription, error)
+	// The defaults of args must be applied before.
+	Subscribe(args SubscriberArgs) (*nats.Subscription, error)
 
 	// Servers returns the list of NATS servers.
 	Servers() []string
 
+	// MaxPayload returns the maximum size of a message payload allowed by the server.
+	MaxPayload() int64
+
 	// PublishMsg publishes a message with a context-dependent msgID to a subject.
-	PublishMsg(msg *nats.Msg, msgID string) error
+	// It waits for the acknowledgment until ctx is done and returns it.
+	PublishMsg(ctx context.Context, msg *nats.Msg, msgID string) (*nats.PubAck, error)
+
+	// PublishMsgAsync publishes a message like PublishMsg, but does not wait for the acknowledgment.
+	PublishMsgAsync(msg *nats.Msg, msgID string) (nats.PubAckFuture, error)
+
+	// PublishAsyncComplete returns a channel, which is closed when all asynchronously published messages
+	// are acknowledged.
+	PublishAsyncComplete() <-chan struct{}
+
+	// PublishAsyncPending returns the number of asynchronously published messages, which are not acknowledged yet.
+	PublishAsyncPending() int
+
+	// PublishCore publishes a message with core NATS, bypassing JetStream.
+	PublishCore(msg *nats.Msg) error
 
 	// Drain will put a Connection into a drain state. All subscriptions will
 	// immediately be put into a drain state. Upon completion, the publishers
@@ -64,29 +228,136 @@ type bridge interface {
 	//
 	// See notes for nats.Conn.Drain
 	Drain() error
-}
 
-// Option is an optional configuration argument for the Connect() function.
-type Option func(*Connection)
+	// Close closes the Connection immediately without draining subscriptions and publishers.
+	Close()
+
+	// Status returns the current state of the Connection.
+	Status() nats.Status
+
+	// Ping does a round trip to the server and returns the round trip time.
+	Ping(ctx context.Context) (time.Duration, error)
+
+	// JetStream returns the underlying nats.JetStreamContext.
+	JetStream() (nats.JetStreamContext, error)
+}
 
 // Connect returns Connection to a NATS server/ cluster and enables Publisher and Subscriber creation.
 func Connect(servers []string, options ...Option) (*Connection, error) {
+	return ConnectWithContext(context.Background(), servers, options...)
+}
+
+// ConnectWithContext returns Connection to a NATS server/ cluster like Connect, but aborts the connection
+// establishment when ctx is cancelled. If ctx has a deadline, it is used as connect timeout.
+func ConnectWithContext(ctx context.Context, servers []string, options ...Option) (*Connection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("NATS Connection could not be created: %w", err)
+	}
+
 	conn := &Connection{
 		logger: slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
 	}
 
 	conn.applyOptions(options...)
-	var err error
-	if conn.nats, err = newNATSBridge(servers, conn.logger); err != nil {
-		return nil, fmt.Errorf("NATS Connection could not be created: %w", err)
+	if err := conn.validateOptions(); err != nil {
+		return nil, err
+	}
+	if err := conn.validateServers(servers); err != nil {
+		return nil, err
+	}
+
+	natsOptions := conn.natsOptions
+	if deadline, ok := ctx.Deadline(); ok {
+		natsOptions = append(natsOptions, nats.Timeout(time.Until(deadline)))
+	}
+	ready := make(chan struct{}) // closed when the connection establishment returned
+	delayedConnect := false      // the connection is established in the background with WithRetryOnFailedConnect
+	natsOptions = append(natsOptions, nats.ConnectHandler(func(_ *nats.Conn) {
+		go func() {
+			defer recoverPanic(conn.logger, conn.hooks, "ConnectHandler")
+			<-ready
+			if delayedConnect {
+				conn.startRegisteredSubscribersAsync()
+			}
+		}()
+	}))
+
+	type result struct {
+		bridge *natsBridge
+		err    error
+	}
+	done := make(chan result, 1)
+	hooks := conn.hooks
+	hooks.OnReconnect = func(url string) {
+		if conn.hooks.OnReconnect != nil {
+			conn.hooks.OnReconnect(url)
+		}
+		if conn.metrics != nil {
+			conn.metrics.ObserveReconnect()
+		}
+		conn.auditSubscribers()
+		conn.startRegisteredSubscribersAsync()
+	}
+
+	go func() {
+		defer recoverPanic(conn.logger, conn.hooks, "connect")
+		nb, err := newNATSBridge(servers, natsOptions, conn.jsOptions, conn.jsDomain, hooks, conn.logger)
+		done <- result{bridge: nb, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		go func() {
+			// The connection may still be established after ctx is done, close it to not leak it.
+			if r := <-done; r.err == nil {
+				r.bridge.connection.Close()
+			}
+			close(ready)
+		}()
+		return nil, fmt.Errorf("NATS Connection could not be created: %w", ctx.Err())
+	case r := <-done:
+		if r.err != nil {
+			close(ready)
+			return nil, fmt.Errorf("NATS Connection could not be created: %w", r.err)
+		}
+		conn.nats = conn.prefixed(r.bridge)
+		delayedConnect = !r.bridge.connection.IsConnected()
+		close(ready)
+		if delayedConnect { // the registered Subscribers are started by the ConnectHandler
+			return conn, nil
+		}
+		if err := conn.startRegisteredSubscribers(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return conn, nil
 	}
-	return conn, nil
 }
 
-func (c *Connection) applyOptions(options ...Option) {
-	for _, option := range options {
-		option(c)
+// ConnectWithNATSConn returns Connection using an existing NATS connection, which is managed by the caller
+// (e.g. with its own authentication and TLS setup). Options configuring the NATS connection itself, like WithTLS
+// or WithConnectionHooks, have no effect. Close drains the subscriptions of the Connection,
+// but the NATS connection stays open and must be closed by the caller, unless CloseWithTimeout timed out.
+func ConnectWithNATSConn(nc *nats.Conn, options ...Option) (*Connection, error) {
+	conn := &Connection{
+		logger: slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
+	}
+
+	conn.applyOptions(options...)
+	if err := conn.validateOptions(); err != nil {
+		return nil, err
+	}
+
+	nb, err := newNATSBridgeFromConn(nc, conn.jsOptions, conn.jsDomain, conn.logger)
+	if err != nil {
+		return nil, fmt.Errorf("NATS Connection could not be created: %w", err)
+	}
+	conn.nats = conn.prefixed(nb)
+	if err := conn.startRegisteredSubscribers(); err != nil {
+		_ = conn.Close()
+		return nil, err
 	}
+	return conn, nil
 }
 
 // PublisherArgs contains the arguments for creating a new Publisher.
@@ -96,6 +367,35 @@ type PublisherArgs struct {
 	// StreamName is the name of the stream like "PRODUCTS" or "ORDERS".
 	// If it does not exist, the stream will be created.
 	StreamName string
+
+	// StreamConfig configures the stream, if it does not exist and is created by NewPublisher. Its Name is set
+	// to StreamName. Default is the default of each field of StreamConfig.
+	StreamConfig StreamConfig
+
+	// Retry defines whether publishing a message with MsgID is retried after transient errors. Default is no retry.
+	Retry RetryPolicy
+
+	// DefaultHeader is merged into the header of every message published by the Publisher, e.g. the service name
+	// or the schema version. Values set in the header of a message take precedence.
+	DefaultHeader Header
+
+	// DeadLetters stores messages, which could not be published after all retries because of a transient error.
+	// Publish returns ErrDeadLettered for them, and they can be published later with ReplayDeadLetters.
+	DeadLetters DeadLetterSink
+
+	// Partitions is the number of partitions used by PublishPartitioned.
+	Partitions int
+
+	// DelayedMessages enables PublishAfter. Delayed messages are stored in the stream "<StreamName>_DELAYED"
+	// and forwarded by a Subscriber of this Publisher, once they are due. Any Publisher of the stream with
+	// DelayedMessages enabled forwards due messages, so they are also delivered after a restart.
+	DelayedMessages bool
+
+	// Chunking enables splitting messages, whose data exceeds the max payload of the server, into ordered chunks,
+	// which are reassembled by the Subscriber before the handler is called. Chunked messages can only be received
+	// with SingleSubscriberStrictMessageOrder, other Subscribers terminate them. Without Chunking, the server
+	// rejects such messages.
+	Chunking bool
 }
 
 // SubscriberArgs contains the arguments for creating a new Subscriber.
@@ -114,19 +414,116 @@ type SubscriberArgs struct {
 	//                  but not "ORDERS.new.error".
 	Subject string
 
+	// Subjects defines multiple subjects of one stream to subscribe instead of Subject, like "ORDERS.created" and
+	// "ORDERS.cancelled", without a wildcard that matches other subjects as well. The subjects must not overlap.
+	// Requires NATS server 2.10 or later.
+	Subjects []string
+
 	// Mode defines the constraints of the subscription. Default is MultipleSubscribersAllowed.
 	// See SubscriptionMode for details.
 	Mode SubscriptionMode
+
+	// DeliverPolicy defines the first message delivered to a new consumer. If it is not set, new consumers deliver
+	// all messages and existing consumers are bound regardless of their policy. See DeliverPolicy for details.
+	DeliverPolicy DeliverPolicy
+
+	// StartSequence is the stream sequence of the first message delivered with DeliverByStartSequence.
+	StartSequence uint64
+
+	// StartTime is the time of the first message delivered with DeliverByStartTime.
+	StartTime time.Time
+
+	// FetchBatchSize is the maximum number of messages fetched per pull request. The messages of a batch are handled
+	// one after another. Default is 1. With SingleSubscriberStrictMessageOrder, only one message is fetched at once,
+	// since only one message can be pending.
+	FetchBatchSize int
+
+	// Concurrency is the number of messages handled concurrently by the Subscriber. Messages are handled in no
+	// particular order with MultipleSubscribersAllowed, and in order per subject with SingleSubscriberOrderedPerSubject.
+	// It is not allowed with SingleSubscriberStrictMessageOrder. Default is 1. If FetchBatchSize is not set,
+	// it defaults to Concurrency.
+	Concurrency int
+
+	// AckWait is the time the server waits for the acknowledgment of a message before it is redelivered.
+	// It must be longer than the handler takes for a message. Default is 30 seconds.
+	AckWait time.Duration
+
+	// InProgressInterval enables heartbeats for long-running handlers: while the handler runs, the AckWait duration
+	// of the message is reset with InProgress in this interval, so it is not redelivered. The context of the handler
+	// has no deadline then. It must be shorter than AckWait. Default is no heartbeats.
+	InProgressInterval time.Duration
+
+	// MaxAckPending is the maximum number of delivered messages, which are not acknowledged yet. The server stops
+	// delivering messages, if it is reached. Default is 1000, or 1 with SingleSubscriberStrictMessageOrder,
+	// which does not allow any other value.
+	MaxAckPending int
+
+	// Backoff defines the delays of redeliveries of a failed message. The n-th delivery of a message is NAKed with
+	// the n-th delay; the last delay is used for all further deliveries. Default is a constant delay of 3 seconds.
+	Backoff []time.Duration
+
+	// MaxDeliver is the maximum number of deliveries of a message. A message, which fails at the last delivery,
+	// is passed to OnMaxDeliverExceeded and terminated, so poison messages do not cycle forever.
+	// Default is unlimited.
+	MaxDeliver int
+
+	// InactiveThreshold is the time after which the server deletes the consumer, if no Subscriber fetched messages
+	// from it, so durable consumers of decommissioned services don't hold back the retention of the stream.
+	// Running Subscribers recreate a deleted consumer after a reconnect. Subscriber.Pause is not allowed with it, and
+	// Subscriber.PauseUntil must be shorter. Default is no deletion.
+	InactiveThreshold time.Duration
+
+	// OnMaxDeliverExceeded is called with the message and the error of the handler, if the message failed at
+	// its last delivery, e.g. to store it for manual inspection. It is not called by StartWithAcker.
+	OnMaxDeliverExceeded func(msg Msg, err error)
+
+	// HandlerTimeout is the maximum time the handler may take for a message. If it is exceeded, the context of the
+	// handler is cancelled, the message is NAKed, the OnHandlerTimeout hook is called, and the next message is
+	// handled without waiting for the handler to return. The handler still occupies its slot of Concurrency until
+	// it returns; without Concurrency, at most one such handler keeps running besides the next one. Close and
+	// Unsubscribe wait for them. Default is no timeout, but the context of the handler is done after AckWait.
+	HandlerTimeout time.Duration
+
+	// MaxPanics is the number of panics of the handler for a message, after which the message is terminated.
+	// A panic is recovered and reported to the OnPanic hook, and the message is NAKed before.
+	// Default is unlimited.
+	MaxPanics int
+
+	// MaxWait is the maximum time a pull request waits for messages, so it is the poll interval of an idle
+	// Subscriber. Default is the Fetch timeout of the Connection, or else its Publish timeout, see WithTimeouts,
+	// or else 5s.
+	MaxWait time.Duration
+
+	// MaxMsgs is the number of messages, after which the Subscriber stops and is removed from the Connection,
+	// e.g. for batch jobs, which handle a bounded number of messages. Redeliveries are counted as well.
+	// Subscriber.Done is closed afterwards. Default is unlimited.
+	MaxMsgs int
+
+	// Until is the time, after which the Subscriber stops and is removed from the Connection, like MaxMsgs.
+	// Default is unlimited.
+	Until time.Time
+
+	// IdleBackoff is the pause before the next pull request, if a pull request returned no messages or failed,
+	// so low-traffic Subscribers don't keep a pull request open all the time. It must be shorter than AckWait.
+	// Default is no pause.
+	IdleBackoff time.Duration
+
+	// IdleHeartbeat lets the server send heartbeats while a pull request waits for messages, so a lost connection
+	// or a deleted consumer is detected before MaxWait elapsed. It must be shorter than half of MaxWait, or of its
+	// default, if MaxWait is not set.
+	// Default is no heartbeats.
+	IdleHeartbeat time.Duration
 }
 
 // Close closes the NATS Connection and drains all subscriptions.
 func (c *Connection) Close() error {
-	for _, sub := range c.subscribers {
-		if err := sub.subscription.Drain(); err != nil {
-			return err
-		}
-		sub.quitSignal <- true
-		close(sub.quitSignal)
+	return c.close(context.Background())
+}
+
+// close drains all subscriptions and the NATS Connection. Waiting for the running handlers stops when ctx is done.
+func (c *Connection) close(ctx context.Context) error {
+	if err := c.drainSubscriptions(ctx); err != nil {
+		return err
 	}
 	if err := c.nats.Drain(); err != nil {
 		return fmt.Errorf("NATS Connection could not be closed: %w", err)
@@ -135,17 +532,214 @@ func (c *Connection) Close() error {
 	return nil
 }
 
-// WithLogger sets the logger
-// This option can be passed in the Connect function.
-// Without this option, the default logger is a slog instance with level ERROR
-func WithLogger(logger *slog.Logger) Option {
-	return func(c *Connection) {
-		c.logger = logger
+// DrainSubscriptions drains all subscriptions and stops their Subscribers, but keeps the Connection and its
+// Publishers alive. Messages already fetched are handled, but no new messages are fetched afterwards.
+// This can be used to stop consuming before a deployment, while still publishing.
+func (c *Connection) DrainSubscriptions() error {
+	return c.drainSubscriptions(context.Background())
+}
+
+// drainSubscriptions drains the subscriptions like DrainSubscriptions, but stops waiting for the running handlers
+// when ctx is done. The Subscriber, whose handlers are still running, is removed from the Connection anyway.
+func (c *Connection) drainSubscriptions(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.subscribers) > 0 {
+		sub := c.subscribers[0]
+		if err := sub.drain(ctx); err != nil {
+			if ctx.Err() != nil { // the Subscriber quits after its running handlers, it must not be drained again
+				c.subscribers = c.subscribers[1:]
+			}
+			return fmt.Errorf("subscriber %s could not be drained: %w", sub.consumerName, err)
+		}
+		c.subscribers = c.subscribers[1:]
+	}
+	c.logger.Info("NATS subscriptions drained.")
+	return nil
+}
+
+// CloseWithTimeout closes the NATS Connection like Close, but bounds the time draining may take.
+// If draining did not finish within timeout, the NATS connection is closed forcefully and an error is returned.
+// This closes a NATS connection passed to ConnectWithNATSConn as well, since the running handlers could not be
+// stopped otherwise.
+func (c *Connection) CloseWithTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := c.close(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	c.nats.Close()
+	c.logger.Error("NATS Connection closed forcefully, draining timed out.", slog.Duration("timeout", timeout))
+	return fmt.Errorf("NATS Connection could not be drained within %s and was closed forcefully", timeout)
+}
+
+// JetStream returns the underlying nats.JetStreamContext of the Connection. This is an escape hatch for advanced
+// use cases, which are not covered by vnats. Streams and consumers used by Publishers and Subscribers should not be
+// modified through it.
+func (c *Connection) JetStream() (nats.JetStreamContext, error) {
+	return c.nats.JetStream()
+}
+
+// Respond publishes data with core NATS to the Reply subject of the received msg.
+func (c *Connection) Respond(msg Msg, data []byte) error {
+	if msg.Reply == "" {
+		return fmt.Errorf("message with msgID: %s @ %s has no reply subject", msg.MsgID, msg.Subject)
+	}
+	return c.PublishCore(&Msg{Subject: msg.Reply, Data: data})
+}
+
+// PublishCore publishes the message with core NATS instead of JetStream. The message is not persisted and not
+// acknowledged, so it is lost if no subscriber is listening. It is meant for high-frequency data like telemetry,
+// where the latency of JetStream acknowledgments is prohibitive. The subject is not required to belong to a stream
+// and the MsgID is only passed as header.
+func (c *Connection) PublishCore(msg *Msg) error {
+	if msg.Subject == "" {
+		return fmt.Errorf("message with msgID: %s could not be published: subject is empty", msg.MsgID)
+	}
+
+	natsMsg := msg.toNATS()
+	natsMsg.Reply = msg.Reply
+	if msg.MsgID != "" {
+		if natsMsg.Header == nil {
+			natsMsg.Header = nats.Header{}
+		}
+		natsMsg.Header.Set(nats.MsgIdHdr, msg.MsgID)
+	}
+
+	if err := c.nats.PublishCore(natsMsg); err != nil {
+		return fmt.Errorf("message with msgID: %s @ %s could not be published: %w", msg.MsgID, msg.Subject, err)
+	}
+	return nil
+}
+
+// ConnectionStatus describes the state of a Connection and its subscribers.
+type ConnectionStatus struct {
+	// State of the NATS connection, like "CONNECTED" or "RECONNECTING".
+	State string
+
+	// Connected is true, if the NATS connection is established.
+	Connected bool
+
+	// RTT is the round trip time to the server, it is zero if the server could not be reached.
+	RTT time.Duration
+
+	// Subscribers contains the liveness of all subscribers of the Connection.
+	Subscribers []SubscriberStatus
+}
+
+// Status returns the current ConnectionStatus, including the round trip time to the server and the liveness
+// of all subscribers.
+func (c *Connection) Status() ConnectionStatus {
+	state := c.nats.Status()
+	status := ConnectionStatus{
+		State:     state.String(),
+		Connected: state == nats.CONNECTED,
+	}
+
+	if rtt, err := c.nats.Ping(context.Background()); err == nil {
+		status.RTT = rtt
+	}
+
+	for _, sub := range c.subscriberList() {
+		status.Subscribers = append(status.Subscribers, sub.status())
+	}
+	return status
+}
+
+// Healthy returns an error if the Connection is not connected, the server does not respond within the deadline of ctx
+// or a started Subscriber is not alive anymore. It is meant to be used for readiness and liveness probes.
+func (c *Connection) Healthy(ctx context.Context) error {
+	if state := c.nats.Status(); state != nats.CONNECTED {
+		return fmt.Errorf("NATS Connection is not connected, state: %s", state)
+	}
+	if _, err := c.nats.Ping(ctx); err != nil {
+		return fmt.Errorf("NATS server did not respond: %w", err)
+	}
+	for _, sub := range c.subscriberList() {
+		if status := sub.status(); status.Running && !status.Alive {
+			return fmt.Errorf("subscriber %s made no progress since %s", status.ConsumerName, status.LastProgress)
+		}
+	}
+	return nil
+}
+
+// auditSubscribers recreates the consumers of all Subscribers, which do not exist on the server anymore.
+func (c *Connection) auditSubscribers() {
+	for _, sub := range c.subscriberList() {
+		recreated, err := sub.ensureConsumer()
+		if err != nil {
+			c.logger.Error("Consumer could not be verified", slog.String("consumer", sub.consumerName), slog.Any("error", err))
+			continue
+		}
+		if !recreated {
+			continue
+		}
+		c.logger.Warn("Consumer was not found after reconnect and has been recreated", slog.String("consumer", sub.consumerName))
+		if c.hooks.OnConsumerRecreated != nil {
+			c.hooks.OnConsumerRecreated(sub.consumerName)
+		}
+	}
+}
+
+// removeSubscriber removes sub from the subscribers of the Connection and reports whether it was found.
+func (c *Connection) removeSubscriber(sub *Subscriber) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.subscribers)
+	c.subscribers = slices.DeleteFunc(c.subscribers, func(s *Subscriber) bool { return s == sub })
+	return len(c.subscribers) < n
+}
+
+// subscriberRegistration is a Subscriber registered with WithSubscriber, which is started once connected.
+type subscriberRegistration struct {
+	args    SubscriberArgs
+	handler MsgHandlerWithContext
+	started bool
+}
+
+// startRegisteredSubscribers creates and starts the Subscribers registered with WithSubscriber,
+// which were not started yet.
+func (c *Connection) startRegisteredSubscribers() error {
+	c.registrationMu.Lock()
+	defer c.registrationMu.Unlock()
+
+	var errs []error
+	for _, registration := range c.registrations {
+		if registration.started {
+			continue
+		}
+		sub, err := c.NewSubscriber(registration.args)
+		if err == nil {
+			err = sub.StartWithContext(registration.handler)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("subscriber %s could not be started: %w", registration.args.ConsumerName, err))
+			continue
+		}
+		registration.started = true
 	}
+	return errors.Join(errs...)
+}
+
+// startRegisteredSubscribersAsync starts the registered Subscribers like startRegisteredSubscribers, but logs errors,
+// since it is called by connection handlers. Failed Subscribers are started again after the next reconnect.
+func (c *Connection) startRegisteredSubscribersAsync() {
+	if err := c.startRegisteredSubscribers(); err != nil {
+		c.logger.Error("Registered subscribers could not be started", slog.String("error", err.Error()))
+	}
+}
+
+func (c *Connection) subscriberList() []*Subscriber {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Subscriber(nil), c.subscribers...)
 }
 
 // MustConnectToNATS to NATS Server. This function panics if the connection could not be established.
-// servers: List of NATS servers in the form of "nats://<user:password>@<host>:<port>"
+// servers: List of NATS servers in the form of "<scheme>://<user:password>@<host>:<port>"
 // logger: an optional slog.Logger instance
 func MustConnectToNATS(config *Config, logger *slog.Logger) *Connection {
 	if logger == nil {
@@ -162,8 +756,13 @@ func servers(cfg *Config) []string {
 	parsedServers := trimSpaceSlice(strings.Split(cfg.Hosts, ","))
 	servers := make([]string, 0, len(parsedServers))
 
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "nats"
+	}
+
 	for _, server := range parsedServers {
-		s := fmt.Sprintf("nats://%s:%s@%s:%d", cfg.Username, cfg.Password, server, cfg.Port)
+		s := fmt.Sprintf("%s://%s:%s@%s:%d", scheme, cfg.Username, cfg.Password, server, cfg.Port)
 		servers = append(servers, s)
 	}
 
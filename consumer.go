@@ -0,0 +1,274 @@
+package vnats
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrConsumerNotFound is returned by NewSubscriber with WithStrictTopology, if the consumer does not exist.
+var ErrConsumerNotFound = errors.New("consumer not found")
+
+// ConsumerInfo contains the configuration and state of a consumer of a stream.
+type ConsumerInfo struct {
+	// Name is the name of the consumer, which is the ConsumerName of its Subscriber.
+	Name string
+
+	// Stream is the name of the stream of the consumer.
+	Stream string
+
+	// Subjects are the subjects filtered by the consumer. Empty if it receives all messages of the stream.
+	Subjects []string
+
+	// Created is the time the consumer was created.
+	Created time.Time
+
+	// Delivered is the stream sequence of the last message delivered to the consumer.
+	Delivered uint64
+
+	// AckFloor is the stream sequence up to which all messages are acknowledged.
+	AckFloor uint64
+
+	// NumPending is the number of messages, which are not delivered to the consumer yet.
+	NumPending uint64
+
+	// NumAckPending is the number of delivered messages, which are not acknowledged yet.
+	NumAckPending int
+
+	// NumRedelivered is the number of messages, which were delivered more than once.
+	NumRedelivered int
+
+	// NumWaiting is the number of pull requests waiting for messages.
+	NumWaiting int
+
+	// LastActive is the time of the last delivery or acknowledgment of a message, or Created if there was none.
+	LastActive time.Time
+
+	// InactiveThreshold is the time after which the server deletes the consumer, if it is inactive.
+	// Zero if it is never deleted.
+	InactiveThreshold time.Duration
+
+	// Metadata are the key/values set by ConsumerConfig.Metadata.
+	Metadata map[string]string
+}
+
+// ConsumerConfig contains the configuration of a durable consumer, which can be created ahead of its Subscriber
+// by ApplyTopology. A Subscriber with the same ConsumerName binds to the consumer, so AckWait, MaxAckPending and
+// MaxDeliver must match its SubscriberArgs.
+type ConsumerConfig struct {
+	// Stream is the name of the stream of the consumer.
+	Stream string
+
+	// Name is the name of the consumer, which is the ConsumerName of its Subscriber.
+	Name string
+
+	// Subjects are the subjects filtered by the consumer, like the Subject of its Subscriber.
+	// Default is all subjects of the stream.
+	Subjects []string
+
+	// DeliverPolicy defines the first message delivered to the consumer. Default is DeliverAll.
+	DeliverPolicy DeliverPolicy
+
+	// StartSequence is the stream sequence of the first message delivered with DeliverByStartSequence.
+	StartSequence uint64
+
+	// StartTime is the time of the first message delivered with DeliverByStartTime.
+	StartTime time.Time
+
+	// AckWait is the time a message must be acknowledged in, before it is redelivered. Default is 30 seconds.
+	AckWait time.Duration
+
+	// MaxAckPending is the maximum number of delivered messages, which are not acknowledged yet.
+	// Default is 1000.
+	MaxAckPending int
+
+	// MaxDeliver is the maximum number of deliveries of a message. Default is unlimited.
+	MaxDeliver int
+
+	// InactiveThreshold is the time after which the server deletes the consumer, if no Subscriber fetched messages
+	// from it. Default is no deletion.
+	InactiveThreshold time.Duration
+
+	// Metadata are application-defined key/values of the consumer, like the owning service, which are returned
+	// by ConsumerInfo and ListConsumers. Keys starting with "_nats." are reserved for the server.
+	// Requires NATS server 2.10 or newer.
+	Metadata map[string]string
+}
+
+// ListConsumers returns the consumers of the stream streamName sorted by their name.
+func (c *Connection) ListConsumers(streamName string) ([]ConsumerInfo, error) {
+	if err := validateStreamName(streamName); err != nil {
+		return nil, err
+	}
+	infos, err := c.nats.Consumers(streamName)
+	if err != nil {
+		return nil, fmt.Errorf("consumers of stream %s could not be listed: %w", streamName, err)
+	}
+
+	consumers := make([]ConsumerInfo, 0, len(infos))
+	for _, info := range infos {
+		consumers = append(consumers, makeConsumerInfo(info))
+	}
+	slices.SortFunc(consumers, func(a, b ConsumerInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return consumers, nil
+}
+
+// ConsumerInfo returns the state of the consumer consumerName of the stream streamName.
+// The returned error wraps nats.ErrConsumerNotFound, if the consumer does not exist.
+func (c *Connection) ConsumerInfo(streamName, consumerName string) (ConsumerInfo, error) {
+	if err := validateStreamName(streamName); err != nil {
+		return ConsumerInfo{}, err
+	}
+	info, err := c.nats.ConsumerInfo(streamName, consumerName)
+	if err != nil {
+		return ConsumerInfo{}, fmt.Errorf("consumer %s could not be fetched: %w", consumerName, err)
+	}
+	return makeConsumerInfo(info), nil
+}
+
+// DeleteConsumer deletes the consumer consumerName of the stream streamName, e.g. an obsolete durable consumer,
+// whose Subscriber was removed from the service.
+func (c *Connection) DeleteConsumer(streamName, consumerName string) error {
+	if err := validateStreamName(streamName); err != nil {
+		return err
+	}
+	if err := c.nats.DeleteConsumer(streamName, consumerName); err != nil {
+		return fmt.Errorf("consumer %s could not be deleted: %w", consumerName, err)
+	}
+	return nil
+}
+
+// CleanupStaleConsumers deletes the consumers of the stream streamName, which were not active for olderThan,
+// e.g. abandoned durable consumers of decommissioned services, which hold back the retention of the stream.
+// A consumer is active, if a message was delivered or acknowledged, or a Subscriber is waiting for messages.
+// Consumers of the running Subscribers of the Connection are never deleted. It returns the names of the deleted
+// consumers, even if deleting some of the consumers failed.
+func (c *Connection) CleanupStaleConsumers(streamName string, olderThan time.Duration) ([]string, error) {
+	consumers, err := c.ListConsumers(streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	inUse := make(map[string]bool, len(c.subscribers))
+	for _, sub := range c.subscribers {
+		inUse[sub.consumerName] = true
+	}
+	c.mu.Unlock()
+
+	var deleted []string
+	var errs []error
+	for _, consumer := range consumers {
+		if inUse[consumer.Name] || consumer.NumWaiting > 0 || time.Since(consumer.LastActive) < olderThan {
+			continue
+		}
+		if err := c.DeleteConsumer(streamName, consumer.Name); err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+			errs = append(errs, err)
+			continue
+		}
+		c.logger.Info("Deleted stale consumer", slog.String("stream", streamName), slog.String("consumer", consumer.Name),
+			slog.Time("lastActive", consumer.LastActive))
+		deleted = append(deleted, consumer.Name)
+	}
+	return deleted, errors.Join(errs...)
+}
+
+func makeConsumerInfo(info *nats.ConsumerInfo) ConsumerInfo {
+	subjects := info.Config.FilterSubjects
+	if info.Config.FilterSubject != "" {
+		subjects = append([]string{info.Config.FilterSubject}, subjects...)
+	}
+	lastActive := info.Created
+	for _, last := range []*time.Time{info.Delivered.Last, info.AckFloor.Last} {
+		if last != nil && last.After(lastActive) {
+			lastActive = *last
+		}
+	}
+	return ConsumerInfo{
+		Name:              info.Name,
+		Stream:            info.Stream,
+		Subjects:          subjects,
+		Created:           info.Created,
+		Delivered:         info.Delivered.Stream,
+		AckFloor:          info.AckFloor.Stream,
+		NumPending:        info.NumPending,
+		NumAckPending:     info.NumAckPending,
+		NumRedelivered:    info.NumRedelivered,
+		NumWaiting:        info.NumWaiting,
+		LastActive:        lastActive,
+		InactiveThreshold: info.Config.InactiveThreshold,
+		Metadata:          userMetadata(info.Config.Metadata),
+	}
+}
+
+// requireConsumer returns ErrStreamNotFound or ErrConsumerNotFound, if the consumer consumerName of the stream
+// streamName does not exist.
+func (c *Connection) requireConsumer(streamName, consumerName string) error {
+	_, err := c.nats.ConsumerInfo(streamName, consumerName)
+	switch {
+	case errors.Is(err, nats.ErrStreamNotFound):
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamName)
+	case errors.Is(err, nats.ErrConsumerNotFound):
+		return fmt.Errorf("%w: %s", ErrConsumerNotFound, consumerName)
+	}
+	return err
+}
+
+func validateConsumerConfig(config ConsumerConfig) error {
+	if err := validateStreamName(config.Stream); err != nil {
+		return err
+	}
+	if config.Name == "" {
+		return fmt.Errorf("consumer name cannot be empty")
+	}
+	if config.DeliverPolicy == DeliverByStartSequence && config.StartSequence == 0 {
+		return fmt.Errorf("DeliverByStartSequence requires StartSequence")
+	}
+	if config.DeliverPolicy == DeliverByStartTime && config.StartTime.IsZero() {
+		return fmt.Errorf("DeliverByStartTime requires StartTime")
+	}
+	return nil
+}
+
+// natsConsumerConfig converts config to a nats.ConsumerConfig with the defaults applied.
+func natsConsumerConfig(config ConsumerConfig) *nats.ConsumerConfig {
+	natsConfig := &nats.ConsumerConfig{
+		Durable:           config.Name,
+		AckPolicy:         nats.AckExplicitPolicy,
+		AckWait:           cmp.Or(config.AckWait, defaultAckWait),
+		MaxAckPending:     cmp.Or(config.MaxAckPending, defaultMaxAckPending),
+		MaxDeliver:        config.MaxDeliver,
+		InactiveThreshold: config.InactiveThreshold,
+		Metadata:          config.Metadata,
+	}
+	switch len(config.Subjects) {
+	case 0:
+	case 1:
+		natsConfig.FilterSubject = config.Subjects[0]
+	default:
+		natsConfig.FilterSubjects = config.Subjects
+	}
+	switch config.DeliverPolicy {
+	case DeliverAll:
+		natsConfig.DeliverPolicy = nats.DeliverAllPolicy
+	case DeliverNew:
+		natsConfig.DeliverPolicy = nats.DeliverNewPolicy
+	case DeliverLastPerSubject:
+		natsConfig.DeliverPolicy = nats.DeliverLastPerSubjectPolicy
+	case DeliverByStartSequence:
+		natsConfig.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		natsConfig.OptStartSeq = config.StartSequence
+	case DeliverByStartTime:
+		natsConfig.DeliverPolicy = nats.DeliverByStartTimePolicy
+		natsConfig.OptStartTime = &config.StartTime
+	}
+	return natsConfig
+}
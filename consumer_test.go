@@ -0,0 +1,205 @@
+package vnats
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+)
+
+func TestConnection_ListConsumers(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastDelivered := created.Add(time.Hour)
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+	b := conn.nats.(*testBridge)
+	b.consumers = []*nats.ConsumerInfo{
+		{
+			Name:   "shipping",
+			Stream: "ORDERS",
+			Config: nats.ConsumerConfig{
+				FilterSubject:     "ORDERS.created",
+				InactiveThreshold: 24 * time.Hour,
+				Metadata:          map[string]string{"owner": "shipping-service", "_nats.level": "1"},
+			},
+			Created:        created,
+			Delivered:      nats.SequenceInfo{Stream: 10, Last: &lastDelivered},
+			AckFloor:       nats.SequenceInfo{Stream: 8},
+			NumAckPending:  2,
+			NumRedelivered: 1,
+			NumWaiting:     1,
+			NumPending:     5,
+		},
+		{Name: "billing", Stream: "ORDERS", Created: created},
+	}
+
+	got, err := conn.ListConsumers("ORDERS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ConsumerInfo{
+		{Name: "billing", Stream: "ORDERS", Created: created, LastActive: created},
+		{
+			Name:              "shipping",
+			Stream:            "ORDERS",
+			Subjects:          []string{"ORDERS.created"},
+			Created:           created,
+			Delivered:         10,
+			AckFloor:          8,
+			NumPending:        5,
+			NumAckPending:     2,
+			NumRedelivered:    1,
+			NumWaiting:        1,
+			LastActive:        lastDelivered,
+			InactiveThreshold: 24 * time.Hour,
+			Metadata:          map[string]string{"owner": "shipping-service"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListConsumers() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := conn.DeleteConsumer("ORDERS", "billing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ConsumerInfo("ORDERS", "billing"); !errors.Is(err, nats.ErrConsumerNotFound) {
+		t.Errorf("ConsumerInfo() of deleted consumer returned error %v", err)
+	}
+}
+
+func TestConnection_CleanupStaleConsumers(t *testing.T) {
+	now := time.Now()
+	lastWeek := now.Add(-7 * 24 * time.Hour)
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", []*Subscriber{{consumerName: "running"}})
+	b := conn.nats.(*testBridge)
+	b.consumers = []*nats.ConsumerInfo{
+		{Name: "abandoned", Stream: "ORDERS", Created: lastWeek},
+		{Name: "delivered", Stream: "ORDERS", Created: lastWeek, Delivered: nats.SequenceInfo{Last: &now}},
+		{Name: "acknowledged", Stream: "ORDERS", Created: lastWeek, AckFloor: nats.SequenceInfo{Last: &now}},
+		{Name: "waiting", Stream: "ORDERS", Created: lastWeek, NumWaiting: 1},
+		{Name: "running", Stream: "ORDERS", Created: lastWeek},
+		{Name: "new", Stream: "ORDERS", Created: now},
+	}
+
+	deleted, err := conn.CleanupStaleConsumers("ORDERS", 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"abandoned"}, deleted); diff != "" {
+		t.Errorf("CleanupStaleConsumers() mismatch (-want +got):\n%s", diff)
+	}
+	if len(b.consumers) != 5 {
+		t.Errorf("CleanupStaleConsumers() left %d consumers, want 5", len(b.consumers))
+	}
+}
+
+func TestConnection_ConsumerInfo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_CONSUMERS"
+	consumerName := "TestConsumerInfo"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if _, err := pub.Publish(&Msg{
+			Subject: streamName + ".created",
+			MsgID:   fmt.Sprintf("msg-%d", i),
+			Data:    []byte("data"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = createSubscriber(t, conn, consumerName, streamName+".>", MultipleSubscribersAllowed)
+
+	info, err := conn.ConsumerInfo(streamName, consumerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NumPending != 3 || info.Stream != streamName {
+		t.Errorf("ConsumerInfo() = %+v, want 3 pending messages of stream %s", info, streamName)
+	}
+	consumers, err := conn.ListConsumers(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumers) != 1 || consumers[0].Name != consumerName {
+		t.Errorf("ListConsumers() = %+v, want consumer %s", consumers, consumerName)
+	}
+	if _, err := conn.ListConsumers(streamName + "_MISSING"); !errors.Is(err, nats.ErrStreamNotFound) {
+		t.Errorf("ListConsumers() of missing stream returned error %v", err)
+	}
+
+	if err := conn.DeleteConsumer(streamName, consumerName); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ConsumerInfo(streamName, consumerName); !errors.Is(err, nats.ErrConsumerNotFound) {
+		t.Errorf("ConsumerInfo() of deleted consumer returned error %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_CleanupStaleConsumers_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_STALE"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	if err := conn.ApplyTopology(Topology{
+		Streams:   []StreamConfig{{Name: streamName}},
+		Consumers: []ConsumerConfig{{Stream: streamName, Name: "abandoned", InactiveThreshold: time.Hour}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:      "TestCleanupStaleConsumers",
+		Subject:           streamName + ".>",
+		InactiveThreshold: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := conn.ConsumerInfo(streamName, sub.consumerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.InactiveThreshold != time.Hour {
+		t.Errorf("ConsumerInfo() returned InactiveThreshold %s, want 1h", info.InactiveThreshold)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	deleted, err := conn.CleanupStaleConsumers(streamName, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"abandoned"}, deleted); diff != "" {
+		t.Errorf("CleanupStaleConsumers() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := conn.ConsumerInfo(streamName, sub.consumerName); err != nil {
+		t.Errorf("consumer of running Subscriber was deleted: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
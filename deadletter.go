@@ -0,0 +1,222 @@
+package vnats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	natsServer "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// ErrDeadLettered is returned by Publish, if the message could not be published after all retries and was written
+// to the DeadLetterSink of the Publisher instead. It can be published later with ReplayDeadLetters.
+var ErrDeadLettered = errors.New("message was written to the dead-letter sink")
+
+// DeadLetterSink stores messages, which could not be published because of a transient error like a lost
+// connection, until they are replayed. Use DiskSpool or a custom implementation, e.g. a secondary stream.
+type DeadLetterSink interface {
+	// Store stores msg, which could not be published because of cause.
+	Store(msg *Msg, cause error) error
+
+	// Replay calls publish for the stored messages in the order they were stored, and removes each message
+	// published successfully. It stops at the first error and returns the number of published messages.
+	Replay(ctx context.Context, publish func(ctx context.Context, msg *Msg) error) (int, error)
+}
+
+// deadLetter writes msg to the DeadLetterSink of the Publisher, if err is transient.
+func (p *Publisher) deadLetter(msg *Msg, err error) error {
+	if p.deadLetters == nil || !(p.retry.isRetryable(err) || errors.Is(err, nats.ErrConnectionClosed)) {
+		return err
+	}
+	if storeErr := p.deadLetters.Store(msg, err); storeErr != nil {
+		return errors.Join(err, fmt.Errorf("message with msgID: %s @ %s could not be dead-lettered: %w", msg.MsgID, msg.Subject, storeErr))
+	}
+	return fmt.Errorf("%w: %w", ErrDeadLettered, err)
+}
+
+// ReplayDeadLetters publishes the messages of the DeadLetterSink of the Publisher, e.g. after the connectivity
+// returned, and returns the number of published messages.
+func (p *Publisher) ReplayDeadLetters(ctx context.Context) (int, error) {
+	if p.deadLetters == nil {
+		return 0, fmt.Errorf("no dead-letter sink is set for stream %s", p.streamName)
+	}
+	return p.deadLetters.Replay(ctx, func(ctx context.Context, msg *Msg) error {
+		_, err := p.intercept(p.publish)(ctx, msg)
+		return err
+	})
+}
+
+// DiskSpool is a DeadLetterSink, which stores each message as file in a local directory.
+type DiskSpool struct {
+	dir string
+
+	mu  sync.Mutex // guards seq and serializes Replay
+	seq uint64
+}
+
+// spooledMsg is the file format of a message in the DiskSpool.
+type spooledMsg struct {
+	Subject string `json:"subject"`
+	Reply   string `json:"reply,omitempty"`
+	MsgID   string `json:"msgID"`
+	Data    []byte `json:"data"`
+	Header  Header `json:"header,omitempty"`
+	Cause   string `json:"cause"`
+}
+
+// NewDiskSpool creates a DiskSpool, which stores messages in dir. The directory is created, if it does not exist.
+func NewDiskSpool(dir string) (*DiskSpool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("spool directory %s could not be created: %w", dir, err)
+	}
+	return &DiskSpool{dir: dir}, nil
+}
+
+// Store writes msg to a new file in the spool directory.
+func (s *DiskSpool) Store(msg *Msg, cause error) error {
+	data, err := json.Marshal(spooledMsg{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		MsgID:   msg.MsgID,
+		Data:    msg.Data,
+		Header:  msg.Header,
+		Cause:   cause.Error(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d.json", time.Now().UnixNano(), s.seq)
+	s.mu.Unlock()
+
+	// write to a temporary file first, so Replay never reads a partially written message
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+// Replay publishes the spooled messages in the order they were stored and deletes the file of each published one.
+func (s *DiskSpool) Replay(ctx context.Context, publish func(ctx context.Context, msg *Msg) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("spool directory %s could not be read: %w", s.dir, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+
+	published := 0
+	for _, name := range names {
+		path := filepath.Join(s.dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return published, err
+		}
+		var spooled spooledMsg
+		if err := json.Unmarshal(data, &spooled); err != nil {
+			return published, fmt.Errorf("spooled message %s could not be read: %w", path, err)
+		}
+
+		if err := publish(ctx, &Msg{
+			Subject: spooled.Subject,
+			Reply:   spooled.Reply,
+			MsgID:   spooled.MsgID,
+			Data:    spooled.Data,
+			Header:  spooled.Header,
+		}); err != nil {
+			return published, err
+		}
+		if err := os.Remove(path); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+const (
+	// HeaderDeadLetterCause is the header key of the error, which caused a message to be dead-lettered by
+	// DeadLetterHandler.
+	HeaderDeadLetterCause = "Vnats-Dead-Letter-Cause"
+
+	// HeaderDeadLetterSequence is the header key of the stream sequence of a dead-lettered message in its
+	// original stream.
+	HeaderDeadLetterSequence = "Vnats-Dead-Letter-Sequence"
+)
+
+// DeadLetterStreamName returns the name of the dead-letter stream of the stream forStream, like "ORDERS_DLQ".
+// Stream names can't contain dots, so the suffix is separated by an underscore.
+func DeadLetterStreamName(forStream string) string {
+	return forStream + "_DLQ"
+}
+
+// EnsureDeadLetterStream creates the dead-letter stream of the stream forStream named by DeadLetterStreamName,
+// if it does not exist yet. It stores the messages published by DeadLetterHandler, and the advisories the server
+// publishes for messages of forStream, which exceeded MaxDeliver or were terminated. An optional config sets
+// the limits of the dead-letter stream; its Name and Subjects are ignored.
+func (c *Connection) EnsureDeadLetterStream(forStream string, config ...StreamConfig) error {
+	if err := validateStreamName(forStream); err != nil {
+		return err
+	}
+	var streamConfig StreamConfig
+	if len(config) > 0 {
+		streamConfig = config[0]
+	}
+	streamConfig.Name = DeadLetterStreamName(forStream)
+	advisoryStream := c.streamPrefix + forStream // advisory subjects are not prefixed, but contain the real name
+	streamConfig.Subjects = []string{
+		streamConfig.Name + ".>",
+		natsServer.JSAdvisoryConsumerMaxDeliveryExceedPre + "." + advisoryStream + ".*",
+		natsServer.JSAdvisoryConsumerMsgTerminatedPre + "." + advisoryStream + ".*",
+	}
+	if err := c.EnsureStream(streamConfig); err != nil {
+		return fmt.Errorf("dead-letter stream for %s could not be created: %w", forStream, err)
+	}
+	return nil
+}
+
+// DeadLetterHandler returns a function for SubscriberArgs.OnMaxDeliverExceeded, which publishes a copy of each
+// failed message to the dead-letter stream of the stream forStream, created by EnsureDeadLetterStream.
+// The copy is published to the original subject prefixed with the dead-letter stream name, like
+// "ORDERS_DLQ.ORDERS.created", with the headers HeaderDeadLetterCause and HeaderDeadLetterSequence.
+func (c *Connection) DeadLetterHandler(forStream string) func(msg Msg, err error) {
+	return func(msg Msg, err error) {
+		header := msg.Header.clone()
+		if header == nil {
+			header = Header{}
+		}
+		header.Set(HeaderDeadLetterCause, err.Error())
+		header.Set(HeaderDeadLetterSequence, strconv.FormatUint(msg.Metadata.StreamSequence, 10))
+		natsMsg := &nats.Msg{
+			Subject: DeadLetterStreamName(forStream) + "." + msg.Subject,
+			Data:    msg.Data,
+			Header:  nats.Header(header),
+		}
+		msgID := fmt.Sprintf("%s-%d", forStream, msg.Metadata.StreamSequence)
+		if _, err := c.nats.PublishMsg(context.Background(), natsMsg, msgID); err != nil {
+			c.logger.Error("Message could not be dead-lettered", slog.String("subject", msg.Subject),
+				slog.String("msgID", msg.MsgID), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPublisher_Publish_DeadLetters(t *testing.T) {
+	tests := []struct {
+		name         string
+		publishErr   error
+		wantErr      error
+		wantSpooled  int
+		wantReplayed int
+	}{
+		{name: "transient error is dead-lettered", publishErr: nats.ErrTimeout, wantErr: ErrDeadLettered, wantSpooled: 1, wantReplayed: 1},
+		{name: "closed connection is dead-lettered", publishErr: nats.ErrConnectionClosed, wantErr: ErrDeadLettered, wantSpooled: 1, wantReplayed: 1},
+		{name: "permanent error is returned", publishErr: nats.ErrBadSubject, wantErr: nats.ErrBadSubject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spool, err := NewDiskSpool(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn := makeTestConnection(t, "MESSAGES", 0, []byte("important"), "msg-001", nil)
+			bridge := conn.nats.(*testBridge)
+			bridge.publishErrs = []error{tt.publishErr}
+			pub := &Publisher{conn: conn, streamName: "MESSAGES", deadLetters: spool}
+
+			msg := NewMsg("MESSAGES.important", "msg-001", []byte("important"))
+			msg.Header = Header{"Trace-Id": []string{"trace-001"}}
+			if _, err := pub.Publish(msg); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Publish() error = %v, want %v", err, tt.wantErr)
+			}
+
+			entries, err := os.ReadDir(spool.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantSpooled {
+				t.Fatalf("spool contains %d messages, want %d", len(entries), tt.wantSpooled)
+			}
+
+			var replayedHeader Header
+			pub.Use(func(next PublishFunc) PublishFunc {
+				return func(ctx context.Context, msg *Msg) (PubAck, error) {
+					replayedHeader = msg.Header
+					return next(ctx, msg)
+				}
+			})
+			replayed, err := pub.ReplayDeadLetters(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if replayed != tt.wantReplayed {
+				t.Errorf("ReplayDeadLetters() = %d, want %d", replayed, tt.wantReplayed)
+			}
+			if replayed > 0 && replayedHeader.Get("Trace-Id") != "trace-001" {
+				t.Errorf("replayed message has header %v", replayedHeader)
+			}
+			if entries, _ := os.ReadDir(spool.dir); len(entries) != 0 {
+				t.Errorf("spool contains %d messages after replay", len(entries))
+			}
+		})
+	}
+}
+
+func TestDiskSpool_Replay_StopsAtFailure(t *testing.T) {
+	spool, err := NewDiskSpool(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msgID := range []string{"msg-001", "msg-002", "msg-003"} {
+		if err := spool.Store(NewMsg("MESSAGES.important", msgID, nil), nats.ErrTimeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var replayed []string
+	published, err := spool.Replay(context.Background(), func(_ context.Context, msg *Msg) error {
+		if msg.MsgID == "msg-002" {
+			return nats.ErrTimeout
+		}
+		replayed = append(replayed, msg.MsgID)
+		return nil
+	})
+	if !errors.Is(err, nats.ErrTimeout) || published != 1 || len(replayed) != 1 || replayed[0] != "msg-001" {
+		t.Errorf("Replay() = %d, %v, replayed %v", published, err, replayed)
+	}
+
+	if entries, _ := os.ReadDir(spool.dir); len(entries) != 2 {
+		t.Errorf("spool contains %d messages, want the 2 not published", len(entries))
+	}
+}
+
+func TestConnection_DeadLetterHandler(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_POISON"
+	dlqName := DeadLetterStreamName(streamName)
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{streamName, dlqName} {
+		_ = js.DeleteStream(name)
+		t.Cleanup(func() { _ = js.DeleteStream(name) })
+	}
+
+	if err := conn.EnsureDeadLetterStream(streamName, StreamConfig{MaxMsgs: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pub.Publish(&Msg{Subject: streamName + ".created", MsgID: "msg-0", Data: []byte("poison")}); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:         "TestDeadLetterHandler",
+		Subject:              streamName + ".>",
+		Backoff:              []time.Duration{time.Millisecond * 10},
+		MaxDeliver:           2,
+		OnMaxDeliverExceeded: conn.DeadLetterHandler(streamName),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Start(func(_ Msg) error {
+		return errors.New("invalid data")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg Msg
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if msg, err = conn.GetLastMsgForSubject(dlqName, dlqName+"."+streamName+".created"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Data) != "poison" || msg.Header.Get(HeaderDeadLetterCause) != "invalid data" ||
+		msg.Header.Get(HeaderDeadLetterSequence) != "1" {
+		t.Errorf("dead-lettered message %+v", msg)
+	}
+	if _, err := conn.GetLastMsgForSubject(dlqName, "$JS.EVENT.ADVISORY.CONSUMER.MSG_TERMINATED."+streamName+".*"); err != nil {
+		t.Errorf("advisory of terminated message was not stored: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,76 @@
+package vnats
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// msgIDCache is an LRU cache of the PubAcks of recently published messages, keyed by stream and MsgID.
+// All methods can be called on a nil cache, which caches nothing.
+type msgIDCache struct {
+	size int
+
+	mu      sync.Mutex // guards entries and order
+	entries map[string]*list.Element
+	order   *list.List // of *msgIDCacheEntry, most recently used first
+}
+
+type msgIDCacheEntry struct {
+	key       string
+	ack       PubAck
+	expiresAt time.Time // when the server stops deduplicating the MsgID
+}
+
+func newMsgIDCache(size int) *msgIDCache {
+	return &msgIDCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// get returns the PubAck of the message with msgID, if it was published to streamName within the duplicate window
+// of the stream.
+func (c *msgIDCache) get(streamName, msgID string) (PubAck, bool) {
+	if c == nil || msgID == "" {
+		return PubAck{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[streamName+"\x00"+msgID]
+	if !ok {
+		return PubAck{}, false
+	}
+	entry := elem.Value.(*msgIDCacheEntry)
+	if time.Now().After(entry.expiresAt) { // the server does not deduplicate it anymore
+		c.order.Remove(elem)
+		delete(c.entries, entry.key)
+		return PubAck{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.ack, true
+}
+
+// add caches the PubAck of the message with msgID for the duplicate window of the stream and evicts the least
+// recently used entry, if the cache is full.
+func (c *msgIDCache) add(streamName, msgID string, ack PubAck, window time.Duration) {
+	if c == nil || msgID == "" || window <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := streamName + "\x00" + msgID
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&msgIDCacheEntry{key: key, ack: ack, expiresAt: time.Now().Add(window)})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*msgIDCacheEntry).key)
+	}
+}
@@ -0,0 +1,105 @@
+package vnats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func Test_msgIDCache(t *testing.T) {
+	cache := newMsgIDCache(2)
+	cache.add("ORDERS", "msg-001", PubAck{Sequence: 1}, time.Hour)
+	cache.add("ORDERS", "msg-002", PubAck{Sequence: 2}, time.Hour)
+
+	if ack, ok := cache.get("ORDERS", "msg-001"); !ok || ack.Sequence != 1 {
+		t.Errorf("get(msg-001) = %+v, %v", ack, ok)
+	}
+	if _, ok := cache.get("PRODUCTS", "msg-001"); ok {
+		t.Error("MsgIDs must be cached per stream")
+	}
+
+	cache.add("ORDERS", "msg-003", PubAck{Sequence: 3}, time.Hour) // evicts msg-002, since msg-001 was used recently
+	if _, ok := cache.get("ORDERS", "msg-002"); ok {
+		t.Error("least recently used msg-002 should be evicted")
+	}
+	if _, ok := cache.get("ORDERS", "msg-001"); !ok {
+		t.Error("msg-001 should still be cached")
+	}
+
+	var disabled *msgIDCache
+	disabled.add("ORDERS", "msg-001", PubAck{}, time.Hour)
+	if _, ok := disabled.get("ORDERS", "msg-001"); ok {
+		t.Error("nil cache must not cache anything")
+	}
+}
+
+func Test_msgIDCache_Expired(t *testing.T) {
+	cache := newMsgIDCache(10)
+	cache.add("ORDERS", "msg-001", PubAck{Sequence: 1}, time.Millisecond)
+	cache.add("PRODUCTS", "msg-001", PubAck{Sequence: 1}, time.Hour)
+	cache.add("PRODUCTS", "msg-002", PubAck{Sequence: 2}, 0) // the stream does not deduplicate
+	time.Sleep(time.Millisecond * 5)
+
+	if _, ok := cache.get("ORDERS", "msg-001"); ok {
+		t.Error("expired MsgID should not be returned")
+	}
+	if _, ok := cache.get("PRODUCTS", "msg-001"); !ok {
+		t.Error("MsgID within the duplicate window of its stream should be returned")
+	}
+	if _, ok := cache.get("PRODUCTS", "msg-002"); ok {
+		t.Error("MsgID without duplicate window must not be cached")
+	}
+}
+
+func TestPublisher_Publish_LocalDeduplication(t *testing.T) {
+	conn := makeTestConnection(t, "ORDERS", 0, []byte("order"), "order-001", nil)
+	conn.nats.(*testBridge).streamInfo = &nats.StreamInfo{Config: nats.StreamConfig{Duplicates: time.Minute}}
+	WithLocalDeduplication(100)(conn)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: "ORDERS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := pub.Publish(NewMsg("ORDERS.created", "order-001", []byte("order")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pub.Publish(NewMsg("ORDERS.created", "order-001", []byte("order")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !second.Duplicate || second.Sequence != first.Sequence {
+		t.Errorf("second Publish() = %+v, want duplicate of %+v", second, first)
+	}
+	if published := conn.nats.(*testBridge).sequenceNumber; published != 1 {
+		t.Errorf("%d messages were sent to the server, want 1", published)
+	}
+}
+
+func TestPublisher_Publish_LocalDeduplication_DuplicateWindow(t *testing.T) {
+	conn := makeTestConnection(t, "ORDERS", 0, []byte("order"), "order-001", nil)
+	conn.nats.(*testBridge).streamInfo = &nats.StreamInfo{Config: nats.StreamConfig{Duplicates: time.Millisecond * 20}}
+	WithLocalDeduplication(100)(conn)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: "ORDERS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pub.Publish(NewMsg("ORDERS.created", "order-001", []byte("order"))); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 30) // the server accepts the MsgID again after the duplicate window of the stream
+	second, err := pub.Publish(NewMsg("ORDERS.created", "order-001", []byte("order")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.Duplicate {
+		t.Error("second Publish() after the duplicate window of the stream must not be a local duplicate")
+	}
+	if published := conn.nats.(*testBridge).sequenceNumber; published != 2 {
+		t.Errorf("%d messages were sent to the server, want 2", published)
+	}
+}
@@ -3,6 +3,7 @@ package vnats
 import (
 	"time"
 
+	natsServer "github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
 )
 
@@ -10,6 +11,14 @@ const (
 	defaultStorageType       = nats.FileStorage
 	defaultDuplicationWindow = time.Minute * 30
 	defaultAckWait           = time.Second * 30
+	defaultMaxAckPending     = natsServer.JsDefaultMaxAckPending
 	defaultNakDelay          = time.Second * 3
 	defaultMaxAge            = time.Hour * 24 * 30
+	defaultPingTimeout       = time.Second * 5
+	defaultAPITimeout        = time.Second * 5
+	defaultFetchMaxWait      = time.Second * 5 // of nats.go, if neither MaxWait nor a fetch or publish timeout is set
+	defaultOutboxInterval    = time.Second
+	defaultOutboxBatchSize   = 100
+
+	defaultMaxDecompressedSize = 64 << 20 // of the data of a received message, see WithMaxDecompressedSize
 )
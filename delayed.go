@@ -0,0 +1,118 @@
+package vnats
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// headerDeliverAt contains the time a delayed message is due, formatted as RFC 3339.
+const headerDeliverAt = "Vnats-Deliver-At"
+
+func delayedStreamName(streamName string) string {
+	return streamName + "_DELAYED"
+}
+
+// PublishAfter publishes the message to the given subject once delay has passed. The message is stored in the
+// delay stream until it is due, so it is not lost if the service restarts. Storing it waits for the rate limit and
+// is retried like Publish, but data exceeding the max payload of the server is not split into chunks.
+// Expectations of msg are ignored. The Publisher must be created with DelayedMessages enabled. The delay must be
+// shorter than the MaxAge of the delay stream, 30 days by default, otherwise the message would expire before it is due.
+func (p *Publisher) PublishAfter(delay time.Duration, msg *Msg) error {
+	if !p.delayedMessages {
+		return fmt.Errorf("delayed messages are not enabled for stream %s", p.streamName)
+	}
+	if p.delayedMaxAge > 0 && delay >= p.delayedMaxAge {
+		return fmt.Errorf("delay %s must be shorter than the MaxAge %s of stream %s", delay, p.delayedMaxAge, delayedStreamName(p.streamName))
+	}
+	if err := p.validate(msg); err != nil {
+		return err
+	}
+	p.ensureMsgID(msg)
+
+	_, err := p.intercept(p.publishDelayed(delay))(context.Background(), msg)
+	return err
+}
+
+// publishDelayed returns a PublishFunc, which stores the validated msg in the delay stream until delay has passed.
+func (p *Publisher) publishDelayed(delay time.Duration) PublishFunc {
+	return func(ctx context.Context, msg *Msg) (PubAck, error) {
+		delayed := &Msg{
+			Subject: delayedStreamName(p.streamName) + "." + msg.Subject,
+			Reply:   msg.Reply,
+			MsgID:   msg.MsgID,
+			Data:    msg.Data,
+			Header:  msg.Header.clone(),
+		}
+		if delayed.Header == nil {
+			delayed.Header = Header{}
+		}
+		delayed.Header.Set(headerDeliverAt, time.Now().Add(delay).Format(time.RFC3339Nano))
+
+		// not chunked, since the forwarder of the delay stream allows multiple Subscribers
+		ack, err := p.publishChunked(ctx, delayed, false)
+		if err != nil {
+			return PubAck{}, fmt.Errorf("delayed message with msgID: %s @ %s could not be stored: %w", msg.MsgID, msg.Subject, err)
+		}
+		return ack, nil
+	}
+}
+
+// startDelayedForwarder creates the delay stream and starts a Subscriber, which publishes due messages
+// to their target subject.
+func (p *Publisher) startDelayedForwarder() error {
+	streamName := delayedStreamName(p.streamName)
+	if err := p.conn.ensureStream(streamName); err != nil {
+		return err
+	}
+	info, err := p.conn.nats.StreamInfo(streamName)
+	if err != nil {
+		return fmt.Errorf("stream %s could not be fetched: %w", streamName, err)
+	}
+	p.delayedMaxAge = info.Config.MaxAge
+
+	sub, err := p.conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: streamName + "_FORWARDER",
+		Subject:      streamName + ".>",
+		Mode:         MultipleSubscribersAllowed,
+	})
+	if err != nil {
+		return err
+	}
+	if err := sub.Start(p.forwardDelayed); err != nil {
+		return err
+	}
+
+	p.delayedMessages = true
+	return nil
+}
+
+// forwardDelayed publishes msg to its target subject, if it is due. Otherwise, it is NAKed until it is due.
+func (p *Publisher) forwardDelayed(msg Msg) error {
+	deliverAt, err := time.Parse(time.RFC3339Nano, msg.Header.Get(headerDeliverAt))
+	if err != nil {
+		p.logger.Error("Delayed message has invalid due date and is dropped",
+			slog.String("subject", msg.Subject), slog.String("msgID", msg.MsgID), slog.Any("error", err))
+		return nil
+	}
+	if remaining := time.Until(deliverAt); remaining > 0 {
+		return &nakDelayError{delay: remaining}
+	}
+
+	due := &Msg{
+		Subject: strings.TrimPrefix(msg.Subject, delayedStreamName(p.streamName)+"."),
+		Reply:   msg.Reply,
+		MsgID:   msg.MsgID,
+		Data:    msg.Data,
+		Header:  msg.Header.clone(),
+	}
+	due.Header.Del(headerDeliverAt)
+	due.Header.Del(nats.MsgIdHdr)
+
+	_, err = p.publishMsg(context.Background(), due, p.publish) // intercepted by PublishAfter already
+	return err
+}
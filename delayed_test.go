@@ -0,0 +1,134 @@
+package vnats
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPublisher_PublishAfter_NotEnabled(t *testing.T) {
+	pub := &Publisher{
+		conn:       makeTestConnection(t, "MESSAGES", 1, nil, "", nil),
+		logger:     slog.Default(),
+		streamName: "MESSAGES",
+	}
+
+	if err := pub.PublishAfter(time.Second, NewMsg("MESSAGES.reminder", "msg-001", nil)); err == nil {
+		t.Error("PublishAfter() without DelayedMessages enabled should fail")
+	}
+}
+
+func TestPublisher_PublishAfter_MaxAge(t *testing.T) {
+	pub := &Publisher{
+		conn:            makeTestConnection(t, "MESSAGES", 1, []byte("reminder"), "msg-001", nil),
+		logger:          slog.Default(),
+		streamName:      "MESSAGES",
+		delayedMessages: true,
+		delayedMaxAge:   time.Hour,
+	}
+
+	if err := pub.PublishAfter(time.Hour, NewMsg("MESSAGES.reminder", "msg-001", []byte("reminder"))); err == nil {
+		t.Error("PublishAfter() with a delay of the MaxAge of the delay stream should fail")
+	}
+	if err := pub.PublishAfter(time.Minute, NewMsg("MESSAGES.reminder", "msg-001", []byte("reminder"))); err != nil {
+		t.Errorf("PublishAfter() with a delay shorter than MaxAge failed: %v", err)
+	}
+}
+
+func TestPublisher_PublishAfter_RetryAndRateLimit(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 1, []byte("reminder"), "msg-001", nil)
+	conn.applyOptions(WithPublishRateLimit(10, 1))
+	conn.nats.(*testBridge).publishErrs = []error{nats.ErrNoResponders}
+	pub := &Publisher{
+		conn:            conn,
+		logger:          slog.Default(),
+		streamName:      "MESSAGES",
+		retry:           RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		delayedMessages: true,
+	}
+
+	if err := pub.PublishAfter(time.Second, NewMsg("MESSAGES.reminder", "msg-001", []byte("reminder"))); err != nil {
+		t.Fatalf("PublishAfter() should retry the transient error: %v", err)
+	}
+	start := time.Now()
+	if err := pub.PublishAfter(time.Second, NewMsg("MESSAGES.reminder", "msg-001", []byte("reminder"))); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*50 {
+		t.Errorf("second PublishAfter() took %s, it should wait for the rate limit", elapsed)
+	}
+}
+
+func TestPublisher_forwardDelayed_NotDue(t *testing.T) {
+	pub := &Publisher{
+		conn:       makeTestConnection(t, "MESSAGES", 1, nil, "", nil),
+		logger:     slog.Default(),
+		streamName: "MESSAGES",
+	}
+
+	msg := Msg{
+		Subject: "MESSAGES_DELAYED.MESSAGES.reminder",
+		MsgID:   "msg-001",
+		Header:  Header{headerDeliverAt: []string{time.Now().Add(time.Minute).Format(time.RFC3339Nano)}},
+	}
+
+	err := pub.forwardDelayed(msg)
+	nakDelay, ok := err.(*nakDelayError)
+	if !ok {
+		t.Fatalf("forwardDelayed() error = %v, want nakDelayError", err)
+	}
+	if nakDelay.delay <= 0 || nakDelay.delay > time.Minute {
+		t.Errorf("forwardDelayed() NAK delay = %s", nakDelay.delay)
+	}
+}
+
+func TestPublisher_PublishAfter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".delayed"
+	conn := makeIntegrationTestConn(t)
+	if js, err := conn.JetStream(); err == nil {
+		_ = js.DeleteStream(delayedStreamName(integrationTestStreamName))
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{
+		StreamName:      integrationTestStreamName,
+		DelayedMessages: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pub.PublishAfter(defaultMaxAge, NewMsg(subject, "msg-000", []byte("too late"))); err == nil {
+		t.Error("PublishAfter() with a delay of the MaxAge of the delay stream should fail")
+	}
+
+	published := time.Now()
+	if err := pub.PublishAfter(time.Second, NewMsg(subject, "msg-001", []byte("reminder"))); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := createSubscriber(t, conn, "TestPublishAfter", subject, MultipleSubscribersAllowed)
+	received := make(chan time.Time, 1)
+	if err := sub.Start(func(_ Msg) error {
+		received <- time.Now()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case at := <-received:
+		if at.Sub(published) < time.Second {
+			t.Errorf("Delayed message was received after %s, before the delay passed", at.Sub(published))
+		}
+	case <-time.After(time.Second * 10):
+		t.Error("Delayed message was not received")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
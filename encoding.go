@@ -0,0 +1,46 @@
+package vnats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// HeaderContentType is the header key of the content type of the message data.
+	HeaderContentType = "Content-Type"
+
+	// ContentTypeJSON is the content type of messages published with PublishJSON.
+	ContentTypeJSON = "application/json"
+
+	// ContentTypeProto is the content type of messages published with PublishProto.
+	ContentTypeProto = "application/protobuf"
+)
+
+// PublishJSON marshals v to JSON and publishes it to the given subject with the Content-Type header set.
+func (p *Publisher) PublishJSON(subject, msgID string, v any) (PubAck, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return PubAck{}, fmt.Errorf("message with msgID: %s @ %s could not be marshaled to JSON: %w", msgID, subject, err)
+	}
+	return p.publishEncoded(subject, msgID, data, ContentTypeJSON)
+}
+
+// PublishProto marshals m to the protobuf wire format and publishes it to the given subject with the
+// Content-Type header set.
+func (p *Publisher) PublishProto(subject, msgID string, m proto.Message) (PubAck, error) {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return PubAck{}, fmt.Errorf("message with msgID: %s @ %s could not be marshaled to protobuf: %w", msgID, subject, err)
+	}
+	return p.publishEncoded(subject, msgID, data, ContentTypeProto)
+}
+
+func (p *Publisher) publishEncoded(subject, msgID string, data []byte, contentType string) (PubAck, error) {
+	msg := NewMsg(subject, msgID, data)
+	msg.Header = Header{}
+	msg.Header.Set(HeaderContentType, contentType)
+	return p.PublishWithContext(context.Background(), msg)
+}
@@ -0,0 +1,70 @@
+package vnats
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type contentTypeBridge struct {
+	testBridge
+	gotContentType string
+}
+
+func (b *contentTypeBridge) PublishMsg(ctx context.Context, msg *nats.Msg, msgID string) (*nats.PubAck, error) {
+	b.gotContentType = msg.Header.Get(HeaderContentType)
+	return b.testBridge.PublishMsg(ctx, msg, msgID)
+}
+
+func TestPublisher_PublishJSON(t *testing.T) {
+	payload := testMessagePayload{Message: "hello"}
+	wantData, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &contentTypeBridge{testBridge: testBridge{TB: t, wantData: wantData, wantMessageID: "msg-001"}}
+	pub := &Publisher{
+		conn:       &Connection{nats: b, logger: slog.Default()},
+		logger:     slog.Default(),
+		streamName: "MESSAGES",
+	}
+
+	if _, err := pub.PublishJSON("MESSAGES.json", "msg-001", payload); err != nil {
+		t.Fatal(err)
+	}
+	if b.gotContentType != ContentTypeJSON {
+		t.Errorf("Content-Type got = %s, want %s", b.gotContentType, ContentTypeJSON)
+	}
+
+	if _, err := pub.PublishJSON("MESSAGES.json", "msg-001", make(chan int)); err == nil {
+		t.Error("PublishJSON() with unsupported type should fail")
+	}
+}
+
+func TestPublisher_PublishProto(t *testing.T) {
+	payload := wrapperspb.String("hello")
+	wantData, err := proto.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &contentTypeBridge{testBridge: testBridge{TB: t, wantData: wantData, wantMessageID: "msg-001"}}
+	pub := &Publisher{
+		conn:       &Connection{nats: b, logger: slog.Default()},
+		logger:     slog.Default(),
+		streamName: "MESSAGES",
+	}
+
+	if _, err := pub.PublishProto("MESSAGES.proto", "msg-001", payload); err != nil {
+		t.Fatal(err)
+	}
+	if b.gotContentType != ContentTypeProto {
+		t.Errorf("Content-Type got = %s, want %s", b.gotContentType, ContentTypeProto)
+	}
+}
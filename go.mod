@@ -1,22 +1,21 @@
 module github.com/fond-of-vertigo/vnats
 
-go 1.23
+go 1.24.0
 
 require (
-	github.com/google/go-cmp v0.5.5
+	github.com/google/go-cmp v0.7.0
+	github.com/klauspost/compress v1.18.2
 	github.com/nats-io/nats-server/v2 v2.9.15
-	github.com/nats-io/nats.go v1.25.0
+	github.com/nats-io/nats.go v1.49.0
+	github.com/nats-io/nuid v1.0.1
+	golang.org/x/time v0.3.0
+	google.golang.org/protobuf v1.36.12
 )
 
 require (
-	github.com/golang/protobuf v1.5.2 // indirect
-	github.com/klauspost/compress v1.16.3 // indirect
 	github.com/minio/highwayhash v1.0.2 // indirect
 	github.com/nats-io/jwt/v2 v2.4.1 // indirect
-	github.com/nats-io/nkeys v0.4.4 // indirect
-	github.com/nats-io/nuid v1.0.1 // indirect
-	golang.org/x/crypto v0.7.0 // indirect
-	golang.org/x/sys v0.6.0 // indirect
-	golang.org/x/time v0.3.0 // indirect
-	google.golang.org/protobuf v1.27.1 // indirect
+	github.com/nats-io/nkeys v0.4.12 // indirect
+	golang.org/x/crypto v0.46.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
 )
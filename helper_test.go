@@ -1,12 +1,15 @@
 package vnats
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -22,17 +25,123 @@ type testBridge struct {
 	sequenceNumber uint64
 	wantData       []byte
 	wantMessageID  string
+	publishErrs    []error                  // returned by PublishMsg in order before publishing succeeds
+	maxPayload     int64                    // defaults to the NATS default of 1 MB
+	asyncPending   int                      // PublishAsyncComplete never completes, if set
+	streamInfo     *nats.StreamInfo         // returned by StreamInfo, nats.ErrStreamNotFound if nil
+	updatedStream  *nats.StreamConfig       // passed to UpdateStream
+	purgeRequest   *nats.StreamPurgeRequest // passed to PurgeStream
+	consumers      []*nats.ConsumerInfo     // returned by Consumers and ConsumerInfo, removed by DeleteConsumer
+	storedMsgs     []*nats.RawStreamMsg     // returned by GetMsg, GetLastMsg and GetNextMsg, ordered by sequence
+	pausedUntil    map[string]time.Time     // set by PauseConsumer per consumer name
 }
 
 func (b *testBridge) EnsureStreamExists(_ *nats.StreamConfig) error {
 	return nil
 }
 
+func (b *testBridge) StreamInfo(_ string) (*nats.StreamInfo, error) {
+	if b.streamInfo == nil {
+		return nil, nats.ErrStreamNotFound
+	}
+	return b.streamInfo, nil
+}
+
+func (b *testBridge) UpdateStream(streamConfig *nats.StreamConfig) error {
+	b.updatedStream = streamConfig
+	return nil
+}
+
 func (b *testBridge) DeleteStream(_ string) error {
 	return nil
 }
 
-func (b *testBridge) DeleteConsumers(_, _ string) error {
+func (b *testBridge) PurgeStream(_ string, request *nats.StreamPurgeRequest) error {
+	b.purgeRequest = request
+	return nil
+}
+
+func (b *testBridge) GetMsg(_ string, seq uint64) (*nats.RawStreamMsg, error) {
+	for _, msg := range b.storedMsgs {
+		if msg.Sequence == seq {
+			return msg, nil
+		}
+	}
+	return nil, nats.ErrMsgNotFound
+}
+
+func (b *testBridge) GetLastMsg(_, subject string) (*nats.RawStreamMsg, error) {
+	for _, msg := range slices.Backward(b.storedMsgs) {
+		if msg.Subject == subject {
+			return msg, nil
+		}
+	}
+	return nil, nats.ErrMsgNotFound
+}
+
+func (b *testBridge) GetNextMsg(_, subject string, seq uint64) (*nats.RawStreamMsg, error) {
+	for _, msg := range b.storedMsgs {
+		if msg.Sequence >= seq && msg.Subject == subject {
+			return msg, nil
+		}
+	}
+	return nil, nats.ErrMsgNotFound
+}
+
+func (b *testBridge) DeleteMsg(_ string, seq uint64, _ bool) error {
+	if _, err := b.GetMsg("", seq); err != nil {
+		return err
+	}
+	b.storedMsgs = slices.DeleteFunc(b.storedMsgs, func(msg *nats.RawStreamMsg) bool {
+		return msg.Sequence == seq
+	})
+	return nil
+}
+
+func (b *testBridge) SnapshotStream(_ string, _ io.Writer, _ time.Duration) error {
+	return nil
+}
+
+func (b *testBridge) RestoreStream(_ string, _ io.Reader, _ time.Duration) error {
+	return nil
+}
+
+func (b *testBridge) Consumers(_ string) ([]*nats.ConsumerInfo, error) {
+	return b.consumers, nil
+}
+
+func (b *testBridge) ConsumerInfo(_, consumerName string) (*nats.ConsumerInfo, error) {
+	for _, info := range b.consumers {
+		if info.Name == consumerName {
+			return info, nil
+		}
+	}
+	return nil, nats.ErrConsumerNotFound
+}
+
+func (b *testBridge) EnsureConsumer(streamName string, consumerConfig *nats.ConsumerConfig) error {
+	b.consumers = slices.DeleteFunc(b.consumers, func(info *nats.ConsumerInfo) bool {
+		return info.Name == consumerConfig.Durable
+	})
+	b.consumers = append(b.consumers, &nats.ConsumerInfo{Name: consumerConfig.Durable, Stream: streamName, Config: *consumerConfig})
+	return nil
+}
+
+func (b *testBridge) PauseConsumer(_, consumerName string, until time.Time) error {
+	if b.pausedUntil == nil {
+		b.pausedUntil = make(map[string]time.Time)
+	}
+	b.pausedUntil[consumerName] = until
+	return nil
+}
+
+func (b *testBridge) DeleteConsumer(_, consumerName string) error {
+	if _, err := b.ConsumerInfo("", consumerName); err != nil {
+		return err
+	}
+	b.consumers = slices.DeleteFunc(b.consumers, func(info *nats.ConsumerInfo) bool {
+		return info.Name == consumerName
+	})
 	return nil
 }
 
@@ -40,7 +149,19 @@ func (b *testBridge) Servers() []string {
 	return nil
 }
 
-func (b *testBridge) PublishMsg(msg *nats.Msg, msgID string) error {
+func (b *testBridge) MaxPayload() int64 {
+	if b.maxPayload == 0 {
+		return 1024 * 1024
+	}
+	return b.maxPayload
+}
+
+func (b *testBridge) PublishMsg(_ context.Context, msg *nats.Msg, msgID string) (*nats.PubAck, error) {
+	if len(b.publishErrs) > 0 {
+		err := b.publishErrs[0]
+		b.publishErrs = b.publishErrs[1:]
+		return nil, err
+	}
 	b.Logf("%s", string(msg.Data))
 	if diff := cmp.Diff(msg.Data, b.wantData); diff != "" {
 		err := fmt.Errorf("wrong message found=%s (id=%s) want=%s (id=%s)", string(msg.Data), msgID, b.wantData, b.wantMessageID)
@@ -49,10 +170,60 @@ func (b *testBridge) PublishMsg(msg *nats.Msg, msgID string) error {
 	if msgID != b.wantMessageID {
 		b.Fatalf("wrong message ID found=%s want=%s", msgID, b.wantMessageID)
 	}
-	return nil
+	b.sequenceNumber++
+	return &nats.PubAck{Stream: b.streamName, Sequence: b.sequenceNumber}, nil
+}
+
+func (b *testBridge) PublishMsgAsync(msg *nats.Msg, msgID string) (nats.PubAckFuture, error) {
+	ack, err := b.PublishMsg(context.Background(), msg, msgID)
+	if err != nil {
+		return nil, err
+	}
+	future := &testPubAckFuture{
+		ok:  make(chan *nats.PubAck, 1),
+		err: make(chan error, 1),
+		msg: msg,
+	}
+	future.ok <- ack
+	return future, nil
+}
+
+func (b *testBridge) PublishAsyncComplete() <-chan struct{} {
+	done := make(chan struct{})
+	if b.asyncPending == 0 {
+		close(done)
+	}
+	return done
+}
+
+func (b *testBridge) PublishAsyncPending() int {
+	return b.asyncPending
 }
 
-func (b *testBridge) Subscribe(_, _ string, _ SubscriptionMode) (*nats.Subscription, error) {
+func (b *testBridge) PublishCore(msg *nats.Msg) error {
+	_, err := b.PublishMsg(context.Background(), msg, msg.Header.Get(nats.MsgIdHdr))
+	return err
+}
+
+type testPubAckFuture struct {
+	ok  chan *nats.PubAck
+	err chan error
+	msg *nats.Msg
+}
+
+func (f *testPubAckFuture) Ok() <-chan *nats.PubAck {
+	return f.ok
+}
+
+func (f *testPubAckFuture) Err() <-chan error {
+	return f.err
+}
+
+func (f *testPubAckFuture) Msg() *nats.Msg {
+	return f.msg
+}
+
+func (b *testBridge) Subscribe(_ SubscriberArgs) (*nats.Subscription, error) {
 	return nil, nil
 }
 
@@ -60,6 +231,20 @@ func (b *testBridge) Drain() error {
 	return nil
 }
 
+func (b *testBridge) Close() {}
+
+func (b *testBridge) Status() nats.Status {
+	return nats.CONNECTED
+}
+
+func (b *testBridge) Ping(_ context.Context) (time.Duration, error) {
+	return time.Millisecond, nil
+}
+
+func (b *testBridge) JetStream() (nats.JetStreamContext, error) {
+	return nil, nil
+}
+
 func makeTestNATSBridge(t testing.TB, streamName string, currentSequenceNumber uint64, wantData []byte, wantMessageID string) bridge {
 	return &testBridge{
 		TB:             t,
@@ -182,7 +367,7 @@ func publishStringMessages(t *testing.T, conn *Connection, subject string, publi
 		t.Error(err)
 	}
 	for idx, msg := range publishMessages {
-		if err := pub.Publish(&Msg{
+		if _, err := pub.Publish(&Msg{
 			Subject: subject,
 			MsgID:   fmt.Sprintf("msg-%d", idx),
 			Data:    []byte(msg),
@@ -206,7 +391,7 @@ func publishTestMessageStructMessages(t *testing.T, conn *Connection, subject st
 			t.Error(err)
 		}
 
-		if err := pub.Publish(&Msg{
+		if _, err := pub.Publish(&Msg{
 			Subject: subject,
 			MsgID:   fmt.Sprintf("msg-%d", idx),
 			Data:    dataAsBytes,
@@ -282,3 +467,13 @@ func createSubscriber(t *testing.T, conn *Connection, consumerName, subject stri
 	}
 	return sub
 }
+
+func applyNATSOptions(t *testing.T, conn *Connection) nats.Options {
+	opts := nats.GetDefaultOptions()
+	for _, option := range conn.natsOptions {
+		if err := option(&opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,49 @@
+package vnats
+
+import "context"
+
+// PublishFunc publishes a message. It is the signature of PublishWithContext and wrapped by a PublishInterceptor.
+type PublishFunc func(ctx context.Context, msg *Msg) (PubAck, error)
+
+// PublishInterceptor wraps the publishing of a message, e.g. for logging, metrics, tracing or validation.
+// It must call next to publish the message and may modify the message before, or return an error
+// without calling next to reject it.
+type PublishInterceptor func(next PublishFunc) PublishFunc
+
+// Use adds interceptors to the Publisher, which wrap every message published with Publish, PublishWithContext,
+// PublishAsync, PublishBatch, PublishToAll and PublishAfter. The first interceptor is the outermost one.
+// Interceptors are called after the subject is validated and the MsgID is generated. For asynchronous publishes,
+// next returns an empty PubAck as soon as the message was sent, the acknowledgment is resolved by the
+// PublishFuture. Delayed messages are intercepted when PublishAfter is called, not again when they are due.
+// Use must not be called concurrently to publishing.
+func (p *Publisher) Use(interceptors ...PublishInterceptor) {
+	p.interceptors = append(p.interceptors, interceptors...)
+}
+
+// intercept wraps publish with the interceptors of the Publisher.
+func (p *Publisher) intercept(publish PublishFunc) PublishFunc {
+	for i := len(p.interceptors) - 1; i >= 0; i-- {
+		publish = p.interceptors[i](publish)
+	}
+	return publish
+}
+
+// SubscribeInterceptor wraps the handling of a received message, e.g. for recovery, logging, metrics, tracing or
+// decoding the payload. It must call next to handle the message and may modify the message before, or return an
+// error without calling next to NAK it.
+type SubscribeInterceptor func(next MsgHandlerWithContext) MsgHandlerWithContext
+
+// Use adds interceptors to the Subscriber, which wrap the handler passed to Start or StartWithContext.
+// The first interceptor is the outermost one. Handlers passed to StartWithAcker are not intercepted.
+// Use must be called before the Subscriber is started.
+func (s *Subscriber) Use(interceptors ...SubscribeInterceptor) {
+	s.interceptors = append(s.interceptors, interceptors...)
+}
+
+// intercept wraps handler with the interceptors of the Subscriber.
+func (s *Subscriber) intercept(handler MsgHandlerWithContext) MsgHandlerWithContext {
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		handler = s.interceptors[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,191 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPublisher_Use(t *testing.T) {
+	pub := &Publisher{
+		conn:       makeTestConnection(t, "MESSAGES", 1, []byte("intercepted"), "msg-001", nil),
+		streamName: "MESSAGES",
+	}
+
+	var calls []string
+	record := func(name string) PublishInterceptor {
+		return func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, msg *Msg) (PubAck, error) {
+				calls = append(calls, name+" before")
+				ack, err := next(ctx, msg)
+				calls = append(calls, name+" after")
+				return ack, err
+			}
+		}
+	}
+	pub.Use(record("first"), record("second"))
+
+	if _, err := pub.Publish(NewMsg("MESSAGES.intercepted", "msg-001", []byte("intercepted"))); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"first before", "second before", "second after", "first after"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("interceptors were called %v, want %v", calls, want)
+	}
+}
+
+func TestPublisher_Use_Reject(t *testing.T) {
+	pub := &Publisher{
+		conn:       makeTestConnection(t, "MESSAGES", 1, nil, "", nil),
+		streamName: "MESSAGES",
+	}
+
+	errRejected := errors.New("rejected")
+	pub.Use(func(_ PublishFunc) PublishFunc {
+		return func(_ context.Context, _ *Msg) (PubAck, error) {
+			return PubAck{}, errRejected
+		}
+	})
+
+	// the testBridge fails the test, if the message with unexpected data is published
+	if _, err := pub.Publish(NewMsg("MESSAGES.rejected", "msg-001", []byte("rejected"))); !errors.Is(err, errRejected) {
+		t.Errorf("Publish() error = %v, want %v", err, errRejected)
+	}
+}
+
+func TestSubscriber_Use(t *testing.T) {
+	sub := &Subscriber{}
+
+	var calls []string
+	record := func(name string) SubscribeInterceptor {
+		return func(next MsgHandlerWithContext) MsgHandlerWithContext {
+			return func(ctx context.Context, msg Msg) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, msg)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+	sub.Use(record("first"), record("second"))
+
+	handler := sub.intercept(func(_ context.Context, msg Msg) error {
+		calls = append(calls, "handler "+string(msg.Data))
+		return nil
+	})
+	if err := handler(context.Background(), Msg{Data: []byte("intercepted")}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"first before", "second before", "handler intercepted", "second after", "first after"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("interceptors were called %v, want %v", calls, want)
+	}
+}
+
+func TestSubscriber_Use_Reject(t *testing.T) {
+	sub := &Subscriber{}
+
+	errRejected := errors.New("rejected")
+	sub.Use(func(_ MsgHandlerWithContext) MsgHandlerWithContext {
+		return func(_ context.Context, _ Msg) error {
+			return errRejected
+		}
+	})
+
+	handler := sub.intercept(func(_ context.Context, _ Msg) error {
+		t.Error("handler of rejected message was called")
+		return nil
+	})
+	if err := handler(context.Background(), Msg{}); !errors.Is(err, errRejected) {
+		t.Errorf("handler error = %v, want %v", err, errRejected)
+	}
+}
+
+func TestPublisher_Use_AllPublishPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish func(pub *Publisher, msg *Msg) error
+	}{
+		{
+			name: "PublishWithContext",
+			publish: func(pub *Publisher, msg *Msg) error {
+				_, err := pub.PublishWithContext(context.Background(), msg)
+				return err
+			},
+		},
+		{
+			name: "PublishAsync",
+			publish: func(pub *Publisher, msg *Msg) error {
+				future, err := pub.PublishAsync(msg)
+				if err != nil {
+					return err
+				}
+				_, err = future.Wait(context.Background())
+				return err
+			},
+		},
+		{
+			name: "PublishBatch",
+			publish: func(pub *Publisher, msg *Msg) error {
+				_, err := pub.PublishBatch([]*Msg{msg}, BatchAllOrNothing)
+				return err
+			},
+		},
+		{
+			name: "PublishToAll",
+			publish: func(pub *Publisher, msg *Msg) error {
+				_, err := pub.PublishToAll([]string{msg.Subject}, msg)
+				return err
+			},
+		},
+		{
+			name: "PublishAfter",
+			publish: func(pub *Publisher, msg *Msg) error {
+				pub.delayedMessages = true
+				return pub.PublishAfter(time.Minute, msg)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "MESSAGES", 1, []byte("intercepted"), "", nil)
+			pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+			var intercepted []string
+			pub.Use(func(next PublishFunc) PublishFunc {
+				return func(ctx context.Context, msg *Msg) (PubAck, error) {
+					intercepted = append(intercepted, msg.Subject)
+					msg.Data = []byte("intercepted") // the testBridge fails the test, if the data was not replaced
+					return next(ctx, msg)
+				}
+			})
+
+			if err := tt.publish(pub, NewMsg("MESSAGES.original", "", []byte("original"))); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"MESSAGES.original"}; !slices.Equal(intercepted, want) {
+				t.Errorf("interceptor was called for %v, want %v", intercepted, want)
+			}
+		})
+	}
+}
+
+func TestPublisher_Use_RejectAsync(t *testing.T) {
+	pub := &Publisher{
+		conn:       makeTestConnection(t, "MESSAGES", 1, nil, "", nil),
+		streamName: "MESSAGES",
+	}
+	errRejected := errors.New("rejected")
+	pub.Use(func(_ PublishFunc) PublishFunc {
+		return func(_ context.Context, _ *Msg) (PubAck, error) {
+			return PubAck{}, errRejected
+		}
+	})
+
+	if _, err := pub.PublishAsync(NewMsg("MESSAGES.rejected", "msg-001", []byte("rejected"))); !errors.Is(err, errRejected) {
+		t.Errorf("PublishAsync() error = %v, want %v", err, errRejected)
+	}
+}
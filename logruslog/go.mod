@@ -0,0 +1,10 @@
+module github.com/fond-of-vertigo/vnats/logruslog
+
+go 1.23
+
+require (
+	github.com/google/go-cmp v0.7.0
+	github.com/sirupsen/logrus v1.10.2
+)
+
+require golang.org/x/sys v0.13.0 // indirect
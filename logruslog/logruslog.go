@@ -0,0 +1,93 @@
+// Package logruslog adapts a *logrus.Logger to the *slog.Logger used by vnats, so services using logrus don't need
+// their own glue code:
+//
+//	conn, err := vnats.Connect(servers, vnats.WithLogger(logruslog.Logger(logger)))
+package logruslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger returns a *slog.Logger, which writes to logger. The level of logger decides which records are written.
+func Logger(logger *logrus.Logger) *slog.Logger {
+	return slog.New(NewHandler(logger))
+}
+
+// Handler is a slog.Handler, which writes records as entries of a *logrus.Logger. Attributes are converted to
+// logrus fields. Since logrus fields are flat, the keys of groups are prefixed with the group name and a dot,
+// like "msg.subject".
+type Handler struct {
+	entry  *logrus.Entry
+	prefix string // of the keys of the current group
+}
+
+// NewHandler returns a Handler, which writes to logger.
+func NewHandler(logger *logrus.Logger) *Handler {
+	return &Handler{entry: logrus.NewEntry(logger)}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.entry.Logger.IsLevelEnabled(logrusLevel(level))
+}
+
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	fields := make(logrus.Fields, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		addField(fields, h.prefix, attr)
+		return true
+	})
+	h.entry.WithFields(fields).WithTime(record.Time).Log(logrusLevel(record.Level), record.Message)
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(logrus.Fields, len(attrs))
+	for _, attr := range attrs {
+		addField(fields, h.prefix, attr)
+	}
+	return &Handler{entry: h.entry.WithFields(fields), prefix: h.prefix}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{entry: h.entry, prefix: h.prefix + name + "."}
+}
+
+// addField adds attr with its key prefixed by prefix to fields. Empty attributes are dropped and groups without
+// a key are inlined, like slog.Handler requires.
+func addField(fields logrus.Fields, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix += attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			addField(fields, groupPrefix, groupAttr)
+		}
+		return
+	}
+	fields[prefix+attr.Key] = attr.Value.Any()
+}
+
+// logrusLevel maps level to the logrus level, which includes it. Levels between the slog levels are rounded down.
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	default:
+		return logrus.DebugLevel
+	}
+}
@@ -0,0 +1,73 @@
+package logruslog
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogger(t *testing.T) {
+	logrusLogger, hook := test.NewNullLogger()
+	logrusLogger.SetLevel(logrus.InfoLevel)
+	logger := Logger(logrusLogger).With(slog.String("stream", "ORDERS"))
+	handleErr := errors.New("failed")
+
+	logger.Debug("Not written")
+	logger.Error("Message handle error, will be NAKed",
+		slog.Any("error", handleErr),
+		slog.Duration("delay", time.Second),
+		slog.Group("msg", slog.String("subject", "ORDERS.created"), slog.Uint64("seq", 42)),
+		slog.Attr{},
+	)
+	logger.WithGroup("subscriber").Warn("Handler exceeded HandlerTimeout", slog.String("consumer", "shipping"))
+
+	entries := hook.AllEntries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want 2", len(entries))
+	}
+	if entries[0].Level != logrus.ErrorLevel || entries[0].Message != "Message handle error, will be NAKed" {
+		t.Errorf("got entry %s %q", entries[0].Level, entries[0].Message)
+	}
+	want := logrus.Fields{
+		"stream":      "ORDERS",
+		"error":       handleErr,
+		"delay":       time.Second,
+		"msg.subject": "ORDERS.created",
+		"msg.seq":     uint64(42),
+	}
+	if diff := cmp.Diff(want, entries[0].Data, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("fields mismatch (-want +got):\n%s", diff)
+	}
+
+	want = logrus.Fields{"stream": "ORDERS", "subscriber.consumer": "shipping"}
+	if diff := cmp.Diff(want, entries[1].Data); diff != "" {
+		t.Errorf("fields of group mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_logrusLevel(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  logrus.Level
+	}{
+		{level: slog.LevelDebug, want: logrus.DebugLevel},
+		{level: slog.LevelInfo, want: logrus.InfoLevel},
+		{level: slog.LevelInfo + 2, want: logrus.InfoLevel},
+		{level: slog.LevelWarn, want: logrus.WarnLevel},
+		{level: slog.LevelError, want: logrus.ErrorLevel},
+		{level: slog.LevelError + 4, want: logrus.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			if got := logrusLevel(tt.level); got != tt.want {
+				t.Errorf("logrusLevel() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package vnats
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/nats-io/nats.go"
 )
 
@@ -16,6 +19,8 @@ type Msg struct {
 
 	// Reply represents an optional subject name where a reply message should be sent to.
 	// This value is just distributed, whether the response is sent to the specified subject depends on the Subscriber.
+	// Since JetStream uses the reply subject of NATS for acknowledgments, it is transported in a header.
+	// The Subscriber can respond over core NATS with Connection.Respond.
 	Reply string
 
 	// MsgID represents a unique value for the message, like a hash value of Data.
@@ -28,8 +33,91 @@ type Msg struct {
 	// Data represents the raw byte data to send. The data is sent as-is.
 	Data []byte
 
-	// Header represents the optional Header for the message.
+	// Header represents the optional Header for the message, like trace IDs, tenant IDs or the content type.
+	// Header keys starting with "Nats-" are reserved for NATS.
 	Header Header
+
+	// Expect defines optional expectations on the state of the stream, which must be met to store the message.
+	// Only used for publishing.
+	Expect Expectations
+
+	// Metadata contains the delivery information of the JetStream message.
+	// Only set for messages received by a Subscriber.
+	Metadata Metadata
+}
+
+// Metadata contains the delivery information of a received message.
+type Metadata struct {
+	// Stream is the name of the stream the message is stored in.
+	Stream string
+
+	// Consumer is the name of the consumer the message was delivered by.
+	Consumer string
+
+	// StreamSequence is the sequence number of the message in the stream.
+	StreamSequence uint64
+
+	// ConsumerSequence is the sequence number of the delivery by the consumer, including redeliveries.
+	ConsumerSequence uint64
+
+	// Timestamp is the time the message was stored in the stream.
+	Timestamp time.Time
+
+	// NumDelivered is the number of deliveries of the message, starting at 1.
+	// It can be used to give up on messages, which fail repeatedly.
+	NumDelivered uint64
+
+	// NumPending is the number of messages of the consumer, which are not delivered yet.
+	NumPending uint64
+}
+
+func makeMetadata(msg *nats.Msg) Metadata {
+	meta, err := msg.Metadata()
+	if err != nil { // not a JetStream message
+		return Metadata{}
+	}
+	return Metadata{
+		Stream:           meta.Stream,
+		Consumer:         meta.Consumer,
+		StreamSequence:   meta.Sequence.Stream,
+		ConsumerSequence: meta.Sequence.Consumer,
+		Timestamp:        meta.Timestamp,
+		NumDelivered:     meta.NumDelivered,
+		NumPending:       meta.NumPending,
+	}
+}
+
+// Expectations are used for optimistic concurrency control. If an expectation is not met, the message is
+// rejected by the server and publishing fails with ErrExpectationNotMet.
+type Expectations struct {
+	// LastMsgID is the MsgID the last message in the stream must have.
+	LastMsgID string
+
+	// LastSequence is the sequence number the last message in the stream must have.
+	LastSequence *uint64
+
+	// LastSubjectSequence is the sequence number the last message on the subject of the message must have.
+	// Zero means that no message must exist on the subject.
+	LastSubjectSequence *uint64
+}
+
+func (e Expectations) apply(h nats.Header) nats.Header {
+	if e.LastMsgID == "" && e.LastSequence == nil && e.LastSubjectSequence == nil {
+		return h
+	}
+	if h == nil {
+		h = nats.Header{}
+	}
+	if e.LastMsgID != "" {
+		h.Set(nats.ExpectedLastMsgIdHdr, e.LastMsgID)
+	}
+	if e.LastSequence != nil {
+		h.Set(nats.ExpectedLastSeqHdr, strconv.FormatUint(*e.LastSequence, 10))
+	}
+	if e.LastSubjectSequence != nil {
+		h.Set(nats.ExpectedLastSubjSeqHdr, strconv.FormatUint(*e.LastSubjectSequence, 10))
+	}
+	return h
 }
 
 // NewMsg constructs a new Msg with the given data.
@@ -41,22 +129,34 @@ func NewMsg(subject, id string, data []byte) *Msg {
 	}
 }
 
+// headerReplyTo contains the Reply subject of a message, since the reply subject of NATS is used by JetStream.
+const headerReplyTo = "Vnats-Reply-To"
+
 func makeMsg(msg *nats.Msg) Msg {
 	return Msg{
-		Subject: msg.Subject,
-		Reply:   msg.Reply,
-		MsgID:   msg.Header.Get(nats.MsgIdHdr),
-		Data:    msg.Data,
-		Header:  Header(msg.Header),
+		Subject:  msg.Subject,
+		Reply:    msg.Header.Get(headerReplyTo),
+		MsgID:    msg.Header.Get(nats.MsgIdHdr),
+		Data:     msg.Data,
+		Header:   Header(msg.Header),
+		Metadata: makeMetadata(msg),
 	}
 }
 
+// toNATS converts m to a NATS message for JetStream, which transports the Reply subject in a header. The reply
+// subject of the NATS message is left empty, since JetStream rejects it for asynchronous publishes.
 func (m *Msg) toNATS() *nats.Msg {
+	header := m.Expect.apply(nats.Header(m.Header.clone())) // NATS adds its own headers, which must not leak into m
+	if m.Reply != "" {
+		if header == nil {
+			header = nats.Header{}
+		}
+		header.Set(headerReplyTo, m.Reply)
+	}
 	return &nats.Msg{
 		Subject: m.Subject,
-		Reply:   m.Reply,
 		Data:    m.Data,
-		Header:  nats.Header(m.Header),
+		Header:  header,
 	}
 }
 
@@ -64,3 +164,35 @@ func (m *Msg) toNATS() *nats.Msg {
 func (h Header) Get(key string) string {
 	return nats.Header(h).Get(key)
 }
+
+// Values returns all values associated with the given key.
+func (h Header) Values(key string) []string {
+	return nats.Header(h).Values(key)
+}
+
+// Set sets the header entries associated with key to the single element value.
+// It replaces any existing values associated with key.
+func (h Header) Set(key, value string) {
+	nats.Header(h).Set(key, value)
+}
+
+// Add adds the value to the values associated with key.
+func (h Header) Add(key, value string) {
+	nats.Header(h).Add(key, value)
+}
+
+// Del deletes the values associated with key.
+func (h Header) Del(key string) {
+	nats.Header(h).Del(key)
+}
+
+func (h Header) clone() Header {
+	if h == nil {
+		return nil
+	}
+	cloned := make(Header, len(h))
+	for key, values := range h {
+		cloned[key] = append([]string(nil), values...)
+	}
+	return cloned
+}
@@ -0,0 +1,106 @@
+package vnats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+)
+
+func TestHeader(t *testing.T) {
+	h := Header{}
+	h.Set("Trace-Id", "abc")
+	h.Add("Tenant-Id", "tenant-1")
+	h.Add("Tenant-Id", "tenant-2")
+
+	if got := h.Get("Trace-Id"); got != "abc" {
+		t.Errorf("Header.Get() got = %s, want abc", got)
+	}
+	if diff := cmp.Diff(h.Values("Tenant-Id"), []string{"tenant-1", "tenant-2"}); diff != "" {
+		t.Errorf("Header.Values() mismatch: %s", diff)
+	}
+
+	h.Del("Trace-Id")
+	if got := h.Get("Trace-Id"); got != "" {
+		t.Errorf("Header.Get() after Del got = %s, want empty", got)
+	}
+}
+
+func TestMsg_toNATS_DoesNotShareHeader(t *testing.T) {
+	msg := NewMsg("PRODUCTS.new", "msg-001", []byte("data"))
+	msg.Header = Header{"Content-Type": []string{"application/json"}}
+
+	natsMsg := msg.toNATS()
+	natsMsg.Header.Set("Nats-Msg-Id", "msg-001")
+
+	if diff := cmp.Diff(msg.Header, Header{"Content-Type": []string{"application/json"}}); diff != "" {
+		t.Errorf("Header of Msg was modified: %s", diff)
+	}
+}
+
+func TestMsg_toNATS_Expectations(t *testing.T) {
+	lastSequence := uint64(42)
+	noSubjectSequence := uint64(0)
+
+	msg := NewMsg("PRODUCTS.new", "msg-002", []byte("data"))
+	msg.Expect = Expectations{
+		LastMsgID:           "msg-001",
+		LastSequence:        &lastSequence,
+		LastSubjectSequence: &noSubjectSequence,
+	}
+
+	h := msg.toNATS().Header
+	if h.Get(nats.ExpectedLastMsgIdHdr) != "msg-001" ||
+		h.Get(nats.ExpectedLastSeqHdr) != "42" ||
+		h.Get(nats.ExpectedLastSubjSeqHdr) != "0" {
+		t.Errorf("Expectations were not set as header: %v", h)
+	}
+	if msg.Header != nil {
+		t.Errorf("Header of Msg was modified: %v", msg.Header)
+	}
+}
+
+func TestMsg_Reply(t *testing.T) {
+	msg := NewMsg("PRODUCTS.request", "msg-001", nil)
+	msg.Reply = "replies.products"
+
+	// JetStream replaces the reply subject of NATS with its acknowledgment subject
+	natsMsg := msg.toNATS()
+	if natsMsg.Reply != "" {
+		t.Errorf("toNATS() Reply = %q, JetStream rejects it for asynchronous publishes", natsMsg.Reply)
+	}
+	natsMsg.Reply = "$JS.ACK.PRODUCTS.consumer.1.1.1.0.0"
+
+	if got := makeMsg(natsMsg).Reply; got != msg.Reply {
+		t.Errorf("Reply = %q, want %q", got, msg.Reply)
+	}
+}
+
+func Test_makeMsg_Metadata(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	natsMsg := &nats.Msg{
+		Subject: "ORDERS.created",
+		Reply:   fmt.Sprintf("$JS.ACK.ORDERS.order-service.3.42.7.%d.5", timestamp.UnixNano()),
+		Sub:     &nats.Subscription{},
+	}
+
+	want := Metadata{
+		Stream:           "ORDERS",
+		Consumer:         "order-service",
+		StreamSequence:   42,
+		ConsumerSequence: 7,
+		Timestamp:        timestamp,
+		NumDelivered:     3,
+		NumPending:       5,
+	}
+	got := makeMsg(natsMsg).Metadata
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("makeMsg() Metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := makeMsg(&nats.Msg{Subject: "ORDERS.created"}).Metadata; got != (Metadata{}) {
+		t.Errorf("makeMsg() of a core NATS message has Metadata %+v", got)
+	}
+}
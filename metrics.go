@@ -0,0 +1,55 @@
+package vnats
+
+import "time"
+
+// Metrics receives measurements of the Publishers and Subscribers of a Connection, e.g. to export them with the
+// Prometheus implementation of the prommetrics package. Implementations must be safe for concurrent use and must
+// not block, since they are called on the hot path of publishing and handling messages.
+type Metrics interface {
+	// ObservePublish is called for each message published by a Publisher of stream with the result and the time
+	// publishing took, including waiting for the rate limit and retries.
+	ObservePublish(stream string, result PublishResult, latency time.Duration)
+
+	// ObserveDelivery is called for each message delivered to the Subscriber of consumer. redelivered is true, if
+	// the message was delivered before. pending is the number of messages not delivered yet, the lag of the consumer.
+	ObserveDelivery(stream, consumer string, redelivered bool, pending uint64)
+
+	// ObserveAck is called for each acknowledgment of a message by the Subscriber of consumer with the result and
+	// the time since the message was delivered.
+	ObserveAck(stream, consumer string, result AckResult, latency time.Duration)
+
+	// AddInFlight is called with delta 1 when a handler of the Subscriber of consumer starts, and with -1 when it
+	// returns.
+	AddInFlight(stream, consumer string, delta int)
+
+	// ObserveReconnect is called after the Connection reconnected to the NATS server/ cluster.
+	ObserveReconnect()
+}
+
+// PublishResult is the result of publishing a message, which is passed to Metrics.
+type PublishResult string
+
+const (
+	// PublishSucceeded means the message was stored by the server.
+	PublishSucceeded PublishResult = "succeeded"
+
+	// PublishDuplicate means the message was not stored again, since its MsgID was already published.
+	PublishDuplicate PublishResult = "duplicate"
+
+	// PublishFailed means the message could not be published.
+	PublishFailed PublishResult = "failed"
+)
+
+// AckResult is the kind of acknowledgment of a received message, which is passed to Metrics.
+type AckResult string
+
+const (
+	// Acked means the message was acknowledged as processed.
+	Acked AckResult = "ack"
+
+	// Naked means the message was negatively acknowledged, so it is redelivered.
+	Naked AckResult = "nak"
+
+	// Termed means the message was terminated, so it is never redelivered.
+	Termed AckResult = "term"
+)
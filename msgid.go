@@ -0,0 +1,78 @@
+package vnats
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// MsgIDGenerator generates the MsgID for a message, which is published without MsgID.
+type MsgIDGenerator func(msg *Msg) string
+
+// UUIDv7MsgID generates a time-ordered UUID version 7 (RFC 9562) as MsgID.
+func UUIDv7MsgID(_ *Msg) string {
+	var uuid [16]byte
+	putTimestampAndRandom(uuid[:])
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x70 // version 7
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // variant RFC 9562
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+// ULIDMsgID generates a lexicographically sortable ULID as MsgID.
+func ULIDMsgID(_ *Msg) string {
+	var ulid [16]byte
+	putTimestampAndRandom(ulid[:])
+
+	return encodeCrockford(ulid)
+}
+
+// ContentHashMsgID generates the MsgID as SHA-256 hash of the subject, the header and the data of the message.
+// Messages with equal content get the same MsgID, so they are deduplicated within the duplicate window.
+func ContentHashMsgID(msg *Msg) string {
+	hash := sha256.New()
+	hash.Write([]byte(msg.Subject))
+	hash.Write([]byte{0})
+	for _, key := range slices.Sorted(maps.Keys(msg.Header)) {
+		for _, value := range msg.Header[key] {
+			hash.Write([]byte(key + ":" + value))
+			hash.Write([]byte{0})
+		}
+	}
+	hash.Write(msg.Data)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// putTimestampAndRandom puts the unix timestamp in milliseconds into the first 48 bits of id,
+// the remaining bits are random.
+func putTimestampAndRandom(id []byte) {
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(time.Now().UnixMilli()))
+	copy(id[0:6], timestamp[2:8])
+
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic("random bytes could not be read: " + err.Error())
+	}
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// encodeCrockford encodes the 128 bits of id to 26 characters of Crockford's Base32.
+func encodeCrockford(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+
+	var encoded [26]byte
+	for i := len(encoded) - 1; i >= 0; i-- {
+		encoded[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(encoded[:])
+}
@@ -0,0 +1,63 @@
+package vnats
+
+import (
+	"log/slog"
+	"regexp"
+	"testing"
+)
+
+func TestUUIDv7MsgID(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first, second := UUIDv7MsgID(nil), UUIDv7MsgID(nil)
+	if !uuidPattern.MatchString(first) {
+		t.Errorf("UUIDv7MsgID() got = %s, is not a UUIDv7", first)
+	}
+	if first == second {
+		t.Errorf("UUIDv7MsgID() generated the same ID twice: %s", first)
+	}
+}
+
+func TestULIDMsgID(t *testing.T) {
+	ulidPattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	first, second := ULIDMsgID(nil), ULIDMsgID(nil)
+	if !ulidPattern.MatchString(first) {
+		t.Errorf("ULIDMsgID() got = %s, is not a ULID", first)
+	}
+	if first == second {
+		t.Errorf("ULIDMsgID() generated the same ID twice: %s", first)
+	}
+}
+
+func TestContentHashMsgID(t *testing.T) {
+	msg := NewMsg("PRODUCTS.new", "", []byte("data"))
+	sameContent := NewMsg("PRODUCTS.new", "", []byte("data"))
+	otherSubject := NewMsg("PRODUCTS.updated", "", []byte("data"))
+
+	if ContentHashMsgID(msg) != ContentHashMsgID(sameContent) {
+		t.Error("ContentHashMsgID() differs for messages with the same content")
+	}
+	if ContentHashMsgID(msg) == ContentHashMsgID(otherSubject) {
+		t.Error("ContentHashMsgID() is equal for messages with different subjects")
+	}
+}
+
+func TestPublisher_Publish_MsgIDGenerator(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 1, []byte("test message"), "generated-id", nil)
+	conn.applyOptions(WithMsgIDGenerator(func(_ *Msg) string { return "generated-id" }))
+
+	pub := &Publisher{
+		conn:       conn,
+		logger:     slog.Default(),
+		streamName: "MESSAGES",
+	}
+
+	msg := NewMsg("MESSAGES.Important", "", []byte("test message"))
+	if _, err := pub.Publish(msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.MsgID != "generated-id" {
+		t.Errorf("MsgID was not set on the message, got %q", msg.MsgID)
+	}
+}
@@ -0,0 +1,85 @@
+package vnats
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SubscriberMux dispatches messages to handlers by their subject, so one Subscriber of a wildcard subject like
+// "ORDERS.>" can handle each subject with a separate handler:
+//
+//	mux := vnats.NewSubscriberMux()
+//	mux.Handle("ORDERS.created", handleCreated)
+//	mux.Handle("ORDERS.*.cancelled", handleCancelled)
+//	err := sub.StartWithContext(mux.HandleMsg)
+type SubscriberMux struct {
+	mu     sync.RWMutex
+	routes []muxRoute
+}
+
+type muxRoute struct {
+	pattern string
+	handler MsgHandlerWithContext
+}
+
+// NewSubscriberMux creates a new SubscriberMux without handlers.
+func NewSubscriberMux() *SubscriberMux {
+	return &SubscriberMux{}
+}
+
+// Handle registers handler for messages with a subject matching pattern. The pattern may contain the NATS wildcards
+// "*" for a single token and ">" for all remaining tokens. A subject equal to a pattern is dispatched to its handler,
+// otherwise the handler of the first matching pattern is called. Handle panics, if pattern is already registered.
+func (m *SubscriberMux) Handle(pattern string, handler MsgHandlerWithContext) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, route := range m.routes {
+		if route.pattern == pattern {
+			panic("vnats: multiple handlers registered for " + pattern)
+		}
+	}
+	m.routes = append(m.routes, muxRoute{pattern: pattern, handler: handler})
+}
+
+// HandleMsg calls the handler registered for the subject of msg. It returns an error, if no handler is registered
+// for the subject, so the message is NAKed. A handler of the pattern ">" can be used as fallback.
+func (m *SubscriberMux) HandleMsg(ctx context.Context, msg Msg) error {
+	handler := m.handler(msg.Subject)
+	if handler == nil {
+		return fmt.Errorf("no handler registered for subject %s", msg.Subject)
+	}
+	return handler(ctx, msg)
+}
+
+func (m *SubscriberMux) handler(subject string) MsgHandlerWithContext {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var match MsgHandlerWithContext
+	for _, route := range m.routes {
+		if route.pattern == subject {
+			return route.handler
+		}
+		if match == nil && subjectMatches(route.pattern, subject) {
+			match = route.handler
+		}
+	}
+	return match
+}
+
+// subjectMatches reports whether subject matches pattern, which may contain the wildcards "*" and ">".
+func subjectMatches(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
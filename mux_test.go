@@ -0,0 +1,80 @@
+package vnats
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_subjectMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		want    bool
+	}{
+		{pattern: "ORDERS.created", subject: "ORDERS.created", want: true},
+		{pattern: "ORDERS.created", subject: "ORDERS.cancelled", want: false},
+		{pattern: "ORDERS.*", subject: "ORDERS.created", want: true},
+		{pattern: "ORDERS.*", subject: "ORDERS.created.error", want: false},
+		{pattern: "ORDERS.*.error", subject: "ORDERS.created.error", want: true},
+		{pattern: "ORDERS.>", subject: "ORDERS.created.error", want: true},
+		{pattern: "ORDERS.>", subject: "ORDERS", want: false},
+		{pattern: ">", subject: "PRODUCTS.created", want: true},
+		{pattern: "ORDERS.created.error", subject: "ORDERS.created", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.subject, func(t *testing.T) {
+			if got := subjectMatches(tt.pattern, tt.subject); got != tt.want {
+				t.Errorf("subjectMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriberMux_HandleMsg(t *testing.T) {
+	var got string
+	route := func(name string) MsgHandlerWithContext {
+		return func(_ context.Context, _ Msg) error {
+			got = name
+			return nil
+		}
+	}
+	mux := NewSubscriberMux()
+	mux.Handle("ORDERS.*", route("wildcard"))
+	mux.Handle("ORDERS.created", route("created"))
+	mux.Handle("ORDERS.>", route("fallback"))
+
+	tests := []struct {
+		subject string
+		want    string
+		wantErr bool
+	}{
+		{subject: "ORDERS.created", want: "created"},
+		{subject: "ORDERS.cancelled", want: "wildcard"},
+		{subject: "ORDERS.cancelled.error", want: "fallback"},
+		{subject: "PRODUCTS.created", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			got = ""
+			err := mux.HandleMsg(context.Background(), Msg{Subject: tt.subject})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HandleMsg() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HandleMsg() called handler %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriberMux_Handle_Duplicate(t *testing.T) {
+	mux := NewSubscriberMux()
+	mux.Handle("ORDERS.created", func(_ context.Context, _ Msg) error { return nil })
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Handle() with duplicate pattern should panic")
+		}
+	}()
+	mux.Handle("ORDERS.created", func(_ context.Context, _ Msg) error { return nil })
+}
@@ -0,0 +1,506 @@
+package vnats
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrNoServers is returned by Connect, if no NATS server is specified.
+	ErrNoServers = errors.New("no NATS servers specified")
+
+	// ErrInvalidServer is returned by Connect, if a NATS server URL can't be parsed or has an unsupported scheme.
+	ErrInvalidServer = errors.New("invalid NATS server")
+
+	// ErrConflictingOptions is returned by Connect, if an Option is passed multiple times or
+	// Options contradict each other.
+	ErrConflictingOptions = errors.New("conflicting options")
+)
+
+// Option is an optional configuration argument for the Connect() function.
+type Option func(*Connection)
+
+func (c *Connection) applyOptions(options ...Option) {
+	for _, option := range options {
+		option(c)
+	}
+}
+
+func (c *Connection) registerOption(name string) {
+	if c.registeredOptions == nil {
+		c.registeredOptions = make(map[string]bool)
+	}
+	if c.registeredOptions[name] {
+		c.optionErrs = append(c.optionErrs, fmt.Errorf("%w: %s is passed multiple times", ErrConflictingOptions, name))
+	}
+	c.registeredOptions[name] = true
+}
+
+// authOptions can't be combined, only one authentication method can be used.
+var authOptions = []string{"WithCredentials", "WithNKey", "WithUserJWT", "WithUserInfo", "WithToken"}
+
+// validateOptions returns all errors of the applied options, and checks for options contradicting each other.
+func (c *Connection) validateOptions() error {
+	errs := c.optionErrs
+
+	var usedAuthOptions []string
+	for _, name := range authOptions {
+		if c.registeredOptions[name] {
+			usedAuthOptions = append(usedAuthOptions, name)
+		}
+	}
+	if len(usedAuthOptions) > 1 {
+		errs = append(errs, fmt.Errorf("%w: only one authentication method can be used, got %s",
+			ErrConflictingOptions, strings.Join(usedAuthOptions, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateServers checks that at least one server is specified and all servers are valid URLs.
+// WebSocket and non-WebSocket servers can't be mixed, WithWebsocketOptions requires WebSocket servers.
+func (c *Connection) validateServers(servers []string) error {
+	var websocketServers int
+	var validServers int
+	for _, server := range servers {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		if !strings.Contains(server, "://") {
+			server = "nats://" + server
+		}
+
+		u, err := url.Parse(server)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidServer, err)
+		}
+		switch u.Scheme {
+		case "nats", "tls":
+		case "ws", "wss":
+			websocketServers++
+		default:
+			return fmt.Errorf("%w: unsupported scheme %q of %s", ErrInvalidServer, u.Scheme, u.Redacted())
+		}
+		validServers++
+	}
+
+	if validServers == 0 {
+		return ErrNoServers
+	}
+	if websocketServers > 0 && websocketServers != validServers {
+		return fmt.Errorf("%w: WebSocket and non-WebSocket servers can't be mixed", ErrConflictingOptions)
+	}
+	if c.registeredOptions["WithWebsocketOptions"] && websocketServers == 0 {
+		return fmt.Errorf("%w: WithWebsocketOptions requires ws:// or wss:// servers", ErrConflictingOptions)
+	}
+	return nil
+}
+
+// TimeoutConfig contains the deadlines of the operations of a Connection. Zero values keep the defaults of NATS.
+type TimeoutConfig struct {
+	// Connect bounds the establishment of the connection to a server.
+	Connect time.Duration
+
+	// Publish bounds waiting for the acknowledgment of a published message and other JetStream API requests.
+	Publish time.Duration
+
+	// Fetch bounds a single pull of messages by a Subscriber, if no message is available.
+	Fetch time.Duration
+
+	// Drain bounds draining subscriptions and publishers when the Connection is closed.
+	Drain time.Duration
+}
+
+// WebsocketOptions contains settings for connections using the WebSocket transport (ws:// or wss:// server URLs).
+type WebsocketOptions struct {
+	// ProxyPath is appended to the server URLs, if the NATS server is reachable behind a proxy/ ingress
+	// only under a specific path, like "/nats".
+	ProxyPath string
+
+	// Compression enables compression of WebSocket frames, if the server supports it.
+	Compression bool
+}
+
+// WithLogger sets the logger. All logs are structured: the logs of Publishers and Subscribers carry the fields
+// "stream" and "consumer", and logs about a message carry its "subject" and "msgID", so they can be filtered by
+// the slog.Handler of the logger, e.g. a JSON handler for log aggregation.
+// This option can be passed in the Connect function.
+// Without this option, the default logger is a slog instance with level ERROR
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Connection) {
+		c.registerOption("WithLogger")
+		c.logger = logger
+	}
+}
+
+// WithConnectionHooks sets callbacks to react on connectivity changes, e.g. to flip readiness probes.
+// This option can be passed in the Connect function.
+func WithConnectionHooks(hooks ConnectionHooks) Option {
+	return func(c *Connection) {
+		c.registerOption("WithConnectionHooks")
+		c.hooks = hooks
+	}
+}
+
+// WithTimeouts sets the deadlines of the operations of the Connection.
+// This option can be passed in the Connect function.
+func WithTimeouts(timeouts TimeoutConfig) Option {
+	return func(c *Connection) {
+		c.registerOption("WithTimeouts")
+		c.timeouts = timeouts
+		if timeouts.Connect > 0 {
+			c.natsOptions = append(c.natsOptions, nats.Timeout(timeouts.Connect))
+		}
+		if timeouts.Drain > 0 {
+			c.natsOptions = append(c.natsOptions, nats.DrainTimeout(timeouts.Drain))
+		}
+		if timeouts.Publish > 0 {
+			c.jsOptions = append(c.jsOptions, nats.MaxWait(timeouts.Publish))
+		}
+	}
+}
+
+// WithPublishRateLimit limits the messages published by all Publishers of the Connection to perSecond,
+// allowing bursts of up to burst messages. Publishing blocks until it is permitted by the limit.
+// The Connections of a ConnectionPool share the limit.
+// This option can be passed in the Connect function.
+func WithPublishRateLimit(perSecond, burst int) Option {
+	return func(c *Connection) {
+		c.registerOption("WithPublishRateLimit")
+		if perSecond <= 0 || burst <= 0 {
+			c.optionErrs = append(c.optionErrs, fmt.Errorf("publish rate limit must be positive, got %d per second with burst %d", perSecond, burst))
+			return
+		}
+		c.publishLimiter = rate.NewLimiter(rate.Limit(perSecond), burst)
+	}
+}
+
+// WithPublishAsyncMaxPending sets the maximum number of asynchronously published messages, which are not yet
+// acknowledged. PublishAsync blocks if the limit is reached.
+// This option can be passed in the Connect function.
+func WithPublishAsyncMaxPending(maxPending int) Option {
+	return func(c *Connection) {
+		c.registerOption("WithPublishAsyncMaxPending")
+		c.jsOptions = append(c.jsOptions, nats.PublishAsyncMaxPending(maxPending))
+	}
+}
+
+// WithPublishValidator sets a validator, which is called for every message published by the Publishers of the
+// Connection, e.g. to validate the data against a JSON schema. Messages are rejected with the error of the
+// validator before they are sent to the stream.
+// This option can be passed in the Connect function.
+func WithPublishValidator(validator func(msg *Msg) error) Option {
+	return func(c *Connection) {
+		c.registerOption("WithPublishValidator")
+		c.publishValidator = validator
+	}
+}
+
+// WithLocalDeduplication keeps the MsgIDs of the last size messages published by Publish and PublishWithContext.
+// Publishing a message with a cached MsgID again returns its PubAck marked as Duplicate without a round trip to
+// the server. MsgIDs are cached as long as the server deduplicates them, which is the DuplicateWindow of the stream
+// when the Publisher was created.
+// This option can be passed in the Connect function.
+func WithLocalDeduplication(size int) Option {
+	return func(c *Connection) {
+		c.registerOption("WithLocalDeduplication")
+		c.publishedMsgIDs = newMsgIDCache(size)
+	}
+}
+
+// WithMsgIDGenerator sets the generator used for the MsgID of messages, which are published without MsgID.
+// The generated MsgID is set on the message, so retrying to publish the same message reuses it.
+// Use one of UUIDv7MsgID, ULIDMsgID or ContentHashMsgID, or a custom MsgIDGenerator.
+// This option can be passed in the Connect function.
+func WithMsgIDGenerator(generator MsgIDGenerator) Option {
+	return func(c *Connection) {
+		c.registerOption("WithMsgIDGenerator")
+		c.msgIDGenerator = generator
+	}
+}
+
+// WithCompression compresses the data of messages published by all Publishers of the Connection with codec,
+// if the data has at least threshold bytes. The codec is stored in the HeaderContentEncoding header, so Subscribers
+// decompress the data automatically, independent of this option.
+// This option can be passed in the Connect function.
+func WithCompression(codec CompressionCodec, threshold int) Option {
+	return func(c *Connection) {
+		c.registerOption("WithCompression")
+		if _, err := compress(codec, nil); err != nil {
+			c.optionErrs = append(c.optionErrs, err)
+			return
+		}
+		c.compression.codec = codec
+		c.compression.threshold = threshold
+	}
+}
+
+// WithMaxDecompressedSize limits the size of the data of received messages after decompression, so a small
+// compressed message can't exhaust the memory of the Subscriber. Messages exceeding it are terminated, like
+// messages with an unknown HeaderContentEncoding. Default is 64 MiB.
+// This option can be passed in the Connect function.
+func WithMaxDecompressedSize(size int) Option {
+	return func(c *Connection) {
+		c.registerOption("WithMaxDecompressedSize")
+		if size <= 0 {
+			c.optionErrs = append(c.optionErrs, errors.New("max decompressed size must be positive"))
+			return
+		}
+		c.compression.maxDecompressedSize = size
+	}
+}
+
+// WithTLS sets the TLS configuration used to establish a secured connection to the NATS server/ cluster.
+// This option can be passed in the Connect function.
+func WithTLS(config *tls.Config) Option {
+	return func(c *Connection) {
+		c.registerOption("WithTLS")
+		c.natsOptions = append(c.natsOptions, nats.Secure(config))
+	}
+}
+
+// WithCredentials sets the path to a chained credentials file (JWT and NKey seed) used for authentication.
+// This option can be passed in the Connect function.
+func WithCredentials(path string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithCredentials")
+		c.natsOptions = append(c.natsOptions, nats.UserCredentials(path))
+	}
+}
+
+// WithNKey sets the path to an NKey seed file used for authentication.
+// The seed file is read when the connection is established, errors are returned by Connect.
+// This option can be passed in the Connect function.
+func WithNKey(seedFile string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithNKey")
+		c.natsOptions = append(c.natsOptions, func(o *nats.Options) error {
+			nkeyOption, err := nats.NkeyOptionFromSeed(seedFile)
+			if err != nil {
+				return fmt.Errorf("NKey seed file %s could not be loaded: %w", seedFile, err)
+			}
+			return nkeyOption(o)
+		})
+	}
+}
+
+// WithUserJWT sets callbacks to fetch the user JWT and sign the server nonce on every (re)connect.
+// This allows short-lived credentials, e.g. fetched from Vault, instead of storing them on disk.
+// This option can be passed in the Connect function.
+func WithUserJWT(jwtCB nats.UserJWTHandler, sigCB nats.SignatureHandler) Option {
+	return func(c *Connection) {
+		c.registerOption("WithUserJWT")
+		c.natsOptions = append(c.natsOptions, nats.UserJWT(jwtCB, sigCB))
+	}
+}
+
+// WithUserInfo sets the username and password used for authentication.
+// This option can be passed in the Connect function.
+func WithUserInfo(user, password string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithUserInfo")
+		c.natsOptions = append(c.natsOptions, nats.UserInfo(user, password))
+	}
+}
+
+// WithToken sets the token used for authentication.
+// This option can be passed in the Connect function.
+func WithToken(token string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithToken")
+		c.natsOptions = append(c.natsOptions, nats.Token(token))
+	}
+}
+
+// WithMaxReconnects sets the number of reconnect attempts before the connection is closed.
+// A negative value means the client retries to reconnect forever.
+// This option can be passed in the Connect function.
+func WithMaxReconnects(maxReconnects int) Option {
+	return func(c *Connection) {
+		c.registerOption("WithMaxReconnects")
+		c.natsOptions = append(c.natsOptions, nats.MaxReconnects(maxReconnects))
+	}
+}
+
+// WithReconnectWait sets the time to wait between reconnect attempts to the same server.
+// This option can be passed in the Connect function.
+func WithReconnectWait(wait time.Duration) Option {
+	return func(c *Connection) {
+		c.registerOption("WithReconnectWait")
+		c.natsOptions = append(c.natsOptions, nats.ReconnectWait(wait))
+	}
+}
+
+// WithReconnectJitter sets the upper bound of a random delay added to the reconnect wait,
+// jitterTLS is used instead of jitter for TLS connections.
+// This option can be passed in the Connect function.
+func WithReconnectJitter(jitter, jitterTLS time.Duration) Option {
+	return func(c *Connection) {
+		c.registerOption("WithReconnectJitter")
+		c.natsOptions = append(c.natsOptions, nats.ReconnectJitter(jitter, jitterTLS))
+	}
+}
+
+// WithReconnectBufSize sets the size in bytes of the buffer holding published messages while reconnecting.
+// This option can be passed in the Connect function.
+func WithReconnectBufSize(size int) Option {
+	return func(c *Connection) {
+		c.registerOption("WithReconnectBufSize")
+		c.natsOptions = append(c.natsOptions, nats.ReconnectBufSize(size))
+	}
+}
+
+// WithWebsocketOptions sets the settings for the WebSocket transport.
+// Basic-auth credentials can be passed as part of the server URLs or with WithUserInfo.
+// This option can be passed in the Connect function.
+func WithWebsocketOptions(options WebsocketOptions) Option {
+	return func(c *Connection) {
+		c.registerOption("WithWebsocketOptions")
+		if options.ProxyPath != "" {
+			c.natsOptions = append(c.natsOptions, nats.ProxyPath(options.ProxyPath))
+		}
+		c.natsOptions = append(c.natsOptions, nats.Compression(options.Compression))
+	}
+}
+
+// WithConnectionName sets the client name shown by the NATS server, e.g. in `nats server report connections`.
+// The NATS protocol has no dedicated field for client metadata, so optional tags like "env=prod" are
+// appended to the name, resulting in "name [env=prod,version=1.2.0]".
+// This option can be passed in the Connect function.
+func WithConnectionName(name string, tags ...string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithConnectionName")
+		if len(tags) > 0 {
+			name = fmt.Sprintf("%s [%s]", name, strings.Join(tags, ","))
+		}
+		c.natsOptions = append(c.natsOptions, nats.Name(name))
+	}
+}
+
+// WithJetStreamDomain sets the JetStream domain all stream and consumer operations are sent to.
+// This is required to target JetStream of a specific leaf node/ edge deployment.
+// This option can be passed in the Connect function.
+func WithJetStreamDomain(domain string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithJetStreamDomain")
+		c.jsOptions = append(c.jsOptions, nats.Domain(domain))
+		c.jsDomain = domain
+	}
+}
+
+// WithStreamPrefix prefixes the names of all streams and the subjects of all messages with prefix, like "STG_" for
+// the stream "STG_ORDERS" with the subject "STG_ORDERS.created", so several environments can share a NATS
+// server/ cluster. The prefix is added and removed transparently: Publishers, Subscribers and all stream and
+// consumer operations use the names and subjects without prefix. Subjects starting with "$" are not prefixed, and
+// the nats.JetStreamContext returned by JetStream is not affected.
+// This option can be passed in the Connect function.
+func WithStreamPrefix(prefix string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithStreamPrefix")
+		if strings.ContainsAny(prefix, "*.> ") {
+			c.optionErrs = append(c.optionErrs, fmt.Errorf("stream prefix %q cannot contain any of chars: *.> and spaces", prefix))
+			return
+		}
+		c.streamPrefix = prefix
+	}
+}
+
+// WithInboxPrefix replaces the default "_INBOX" prefix of reply subjects, which is required for accounts
+// where the default inbox is not permitted by exports/ imports.
+// This option can be passed in the Connect function.
+func WithInboxPrefix(prefix string) Option {
+	return func(c *Connection) {
+		c.registerOption("WithInboxPrefix")
+		c.natsOptions = append(c.natsOptions, nats.CustomInboxPrefix(prefix))
+	}
+}
+
+// WithIgnoreDiscoveredServers disables adding servers advertised by the cluster to the server pool,
+// so only the servers passed to Connect are used, e.g. when the cluster is only reachable through a load balancer.
+// This option can be passed in the Connect function.
+func WithIgnoreDiscoveredServers() Option {
+	return func(c *Connection) {
+		c.registerOption("WithIgnoreDiscoveredServers")
+		c.natsOptions = append(c.natsOptions, nats.IgnoreDiscoveredServers())
+	}
+}
+
+// WithRetryOnFailedConnect sets whether the initial connect is retried like a reconnect if the servers are not
+// reachable. If enabled, Connect returns without error and the Connection connects in the background.
+// This option can be passed in the Connect function.
+func WithRetryOnFailedConnect(retry bool) Option {
+	return func(c *Connection) {
+		c.registerOption("WithRetryOnFailedConnect")
+		c.natsOptions = append(c.natsOptions, nats.RetryOnFailedConnect(retry))
+	}
+}
+
+// WithCustomDialer sets the dialer used to establish connections, e.g. to route traffic through a SOCKS5 proxy.
+// This option can be passed in the Connect function.
+func WithCustomDialer(dialer nats.CustomDialer) Option {
+	return func(c *Connection) {
+		c.registerOption("WithCustomDialer")
+		c.natsOptions = append(c.natsOptions, nats.SetCustomDialer(dialer))
+	}
+}
+
+// WithStrictTopology disables the creation of missing streams and consumers by NewPublisher and NewSubscriber, for
+// environments where the topology is managed centrally. They fail with ErrStreamNotFound or ErrConsumerNotFound
+// instead, and consumers deleted on the server are not recreated after a reconnect.
+// EnsureStream and ApplyTopology still create streams and consumers, since they are called explicitly.
+// This option can be passed in the Connect function.
+func WithStrictTopology() Option {
+	return func(c *Connection) {
+		c.registerOption("WithStrictTopology")
+		c.strictTopology = true
+	}
+}
+
+// WithTracer creates spans with tracer around publishing a message and around the handler of each received message.
+// The trace context of the publishing span is sent in the message header and continued by the handling span, so
+// distributed traces don't break at NATS. The context passed to a MsgHandlerWithContext carries the handling span.
+// Use oteltracing.New for OpenTelemetry.
+// This option can be passed in the Connect function.
+func WithTracer(tracer Tracer) Option {
+	return func(c *Connection) {
+		c.registerOption("WithTracer")
+		if tracer == nil {
+			c.optionErrs = append(c.optionErrs, errors.New("tracer must not be nil"))
+			return
+		}
+		c.tracer = tracer
+	}
+}
+
+// WithMetrics passes measurements of all Publishers and Subscribers of the Connection to metrics, like publish
+// latencies, acknowledgments, the lag of consumers and reconnects. Use prommetrics.New to export them to
+// Prometheus. The counters of Publisher.Stats and Subscriber.Stats are independent of this option.
+// This option can be passed in the Connect function.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Connection) {
+		c.registerOption("WithMetrics")
+		c.metrics = metrics
+	}
+}
+
+// WithSubscriber registers a Subscriber, which is created and started with handler once the Connection is
+// established, so services don't have to order their startup around Connect. If the Subscriber can't be started,
+// Connect returns an error. With WithRetryOnFailedConnect, it is started after the delayed connect.
+// Like all Subscribers, its consumer is recreated after a reconnect, if it was deleted in the meantime.
+// With ConnectPool, it is only started by the first Connection of the pool.
+// This option can be passed multiple times with different consumer names in the Connect function.
+func WithSubscriber(args SubscriberArgs, handler MsgHandlerWithContext) Option {
+	return func(c *Connection) {
+		c.registerOption("WithSubscriber " + args.ConsumerName)
+		c.registrations = append(c.registrations, &subscriberRegistration{args: args, handler: handler})
+	}
+}
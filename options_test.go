@@ -0,0 +1,289 @@
+package vnats
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestOptions(t *testing.T) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{Timeout: time.Second}
+	credsFile := filepath.Join(t.TempDir(), "user.creds")
+	if err := os.WriteFile(credsFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		option Option
+		check  func(opts nats.Options) bool
+	}{
+		{
+			name:   "WithTLS sets TLS config",
+			option: WithTLS(tlsConfig),
+			check: func(opts nats.Options) bool {
+				return opts.Secure && opts.TLSConfig == tlsConfig
+			},
+		},
+		{
+			name:   "WithCredentials sets user credentials",
+			option: WithCredentials(credsFile),
+			check: func(opts nats.Options) bool {
+				return opts.UserJWT != nil && opts.SignatureCB != nil
+			},
+		},
+		{
+			name:   "WithUserInfo sets username and password",
+			option: WithUserInfo("user", "secret"),
+			check: func(opts nats.Options) bool {
+				return opts.User == "user" && opts.Password == "secret"
+			},
+		},
+		{
+			name:   "WithToken sets token",
+			option: WithToken("T0k3n"),
+			check: func(opts nats.Options) bool {
+				return opts.Token == "T0k3n"
+			},
+		},
+		{
+			name:   "WithMaxReconnects sets max reconnects",
+			option: WithMaxReconnects(-1),
+			check: func(opts nats.Options) bool {
+				return opts.MaxReconnect == -1
+			},
+		},
+		{
+			name:   "WithReconnectWait sets reconnect wait",
+			option: WithReconnectWait(time.Second * 10),
+			check: func(opts nats.Options) bool {
+				return opts.ReconnectWait == time.Second*10
+			},
+		},
+		{
+			name:   "WithReconnectJitter sets reconnect jitter",
+			option: WithReconnectJitter(time.Second, time.Second*2),
+			check: func(opts nats.Options) bool {
+				return opts.ReconnectJitter == time.Second && opts.ReconnectJitterTLS == time.Second*2
+			},
+		},
+		{
+			name:   "WithReconnectBufSize sets reconnect buffer size",
+			option: WithReconnectBufSize(16 * 1024 * 1024),
+			check: func(opts nats.Options) bool {
+				return opts.ReconnectBufSize == 16*1024*1024
+			},
+		},
+		{
+			name:   "WithWebsocketOptions sets proxy path and compression",
+			option: WithWebsocketOptions(WebsocketOptions{ProxyPath: "/nats", Compression: true}),
+			check: func(opts nats.Options) bool {
+				return opts.ProxyPath == "/nats" && opts.Compression
+			},
+		},
+		{
+			name:   "WithConnectionName sets name",
+			option: WithConnectionName("order-service"),
+			check: func(opts nats.Options) bool {
+				return opts.Name == "order-service"
+			},
+		},
+		{
+			name:   "WithConnectionName sets name with tags",
+			option: WithConnectionName("order-service", "env=prod", "version=1.2.0"),
+			check: func(opts nats.Options) bool {
+				return opts.Name == "order-service [env=prod,version=1.2.0]"
+			},
+		},
+		{
+			name:   "WithInboxPrefix sets inbox prefix",
+			option: WithInboxPrefix("_INBOX_TENANT"),
+			check: func(opts nats.Options) bool {
+				return opts.InboxPrefix == "_INBOX_TENANT"
+			},
+		},
+		{
+			name:   "WithIgnoreDiscoveredServers ignores discovered servers",
+			option: WithIgnoreDiscoveredServers(),
+			check: func(opts nats.Options) bool {
+				return opts.IgnoreDiscoveredServers
+			},
+		},
+		{
+			name:   "WithRetryOnFailedConnect enables retry on failed connect",
+			option: WithRetryOnFailedConnect(true),
+			check: func(opts nats.Options) bool {
+				return opts.RetryOnFailedConnect
+			},
+		},
+		{
+			name:   "WithTimeouts sets connect and drain timeout",
+			option: WithTimeouts(TimeoutConfig{Connect: time.Second * 3, Drain: time.Second * 20}),
+			check: func(opts nats.Options) bool {
+				return opts.Timeout == time.Second*3 && opts.DrainTimeout == time.Second*20
+			},
+		},
+		{
+			name: "WithUserJWT sets JWT and signature callbacks",
+			option: WithUserJWT(
+				func() (string, error) { return "jwt", nil },
+				func(nonce []byte) ([]byte, error) { return nonce, nil },
+			),
+			check: func(opts nats.Options) bool {
+				return opts.UserJWT != nil && opts.SignatureCB != nil
+			},
+		},
+		{
+			name:   "WithCustomDialer sets dialer",
+			option: WithCustomDialer(dialer),
+			check: func(opts nats.Options) bool {
+				return opts.CustomDialer == dialer
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &Connection{}
+			conn.applyOptions(tt.option)
+
+			if opts := applyNATSOptions(t, conn); !tt.check(opts) {
+				t.Errorf("option was not applied to NATS options: %+v", opts)
+			}
+		})
+	}
+}
+
+func TestWithNKey_MissingSeedFile(t *testing.T) {
+	conn := &Connection{}
+	conn.applyOptions(WithNKey("does-not-exist.nk"))
+
+	opts := nats.GetDefaultOptions()
+	if err := conn.natsOptions[0](&opts); err == nil {
+		t.Error("expected error for missing seed file, got nil")
+	}
+}
+
+func TestConnection_validateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr error
+	}{
+		{
+			name:    "No options",
+			options: nil,
+			wantErr: nil,
+		},
+		{
+			name:    "Single authentication method",
+			options: []Option{WithToken("T0k3n"), WithTLS(&tls.Config{MinVersion: tls.VersionTLS12})},
+			wantErr: nil,
+		},
+		{
+			name:    "Option passed multiple times",
+			options: []Option{WithReconnectWait(time.Second), WithReconnectWait(time.Second * 2)},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name: "Subscribers with different consumer names",
+			options: []Option{
+				WithSubscriber(SubscriberArgs{ConsumerName: "first"}, nil),
+				WithSubscriber(SubscriberArgs{ConsumerName: "second"}, nil),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Subscribers with the same consumer name",
+			options: []Option{
+				WithSubscriber(SubscriberArgs{ConsumerName: "first"}, nil),
+				WithSubscriber(SubscriberArgs{ConsumerName: "first"}, nil),
+			},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "Multiple authentication methods",
+			options: []Option{WithToken("T0k3n"), WithUserInfo("user", "secret")},
+			wantErr: ErrConflictingOptions,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &Connection{}
+			conn.applyOptions(tt.options...)
+
+			if err := conn.validateOptions(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnection_validateServers(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []string
+		options []Option
+		wantErr error
+	}{
+		{
+			name:    "Valid servers",
+			servers: []string{"nats://localhost:4222", "tls://nats.example.com:4222", "localhost:4223"},
+			wantErr: nil,
+		},
+		{
+			name:    "WebSocket servers with WebSocket options",
+			servers: []string{"wss://nats.example.com:443"},
+			options: []Option{WithWebsocketOptions(WebsocketOptions{ProxyPath: "/nats"})},
+			wantErr: nil,
+		},
+		{
+			name:    "No servers",
+			servers: nil,
+			wantErr: ErrNoServers,
+		},
+		{
+			name:    "Only empty servers",
+			servers: []string{"", " "},
+			wantErr: ErrNoServers,
+		},
+		{
+			name:    "Unsupported scheme",
+			servers: []string{"http://localhost:4222"},
+			wantErr: ErrInvalidServer,
+		},
+		{
+			name:    "Mixed WebSocket and non-WebSocket servers",
+			servers: []string{"ws://localhost:8080", "nats://localhost:4222"},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "WebSocket options without WebSocket servers",
+			servers: []string{"nats://localhost:4222"},
+			options: []Option{WithWebsocketOptions(WebsocketOptions{ProxyPath: "/nats"})},
+			wantErr: ErrConflictingOptions,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &Connection{}
+			conn.applyOptions(tt.options...)
+
+			if err := conn.validateServers(tt.servers); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateServers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnect_NoServers(t *testing.T) {
+	if _, err := Connect(nil); !errors.Is(err, ErrNoServers) {
+		t.Errorf("expected ErrNoServers, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package vnats
+
+import (
+	"sync"
+	"time"
+)
+
+// subjectSequencer serializes the handling of messages per subject for SingleSubscriberOrderedPerSubject,
+// while messages of different subjects are handled concurrently.
+type subjectSequencer struct {
+	mu     sync.Mutex
+	tails  map[string]chan struct{} // closed when the last dispatched message of the subject was handled
+	failed map[string]uint64        // stream sequence of the NAKed message, which blocks later messages of the subject
+}
+
+func newSubjectSequencer() *subjectSequencer {
+	return &subjectSequencer{
+		tails:  make(map[string]chan struct{}),
+		failed: make(map[string]uint64),
+	}
+}
+
+// enqueue appends a message of subject to its queue. The message must be handled after wait is closed,
+// and done must be called afterwards.
+func (q *subjectSequencer) enqueue(subject string) (wait <-chan struct{}, done func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	prev, ok := q.tails[subject]
+	if !ok {
+		prev = make(chan struct{})
+		close(prev)
+	}
+	tail := make(chan struct{})
+	q.tails[subject] = tail
+
+	return prev, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.tails[subject] == tail {
+			delete(q.tails, subject)
+		}
+		close(tail)
+	}
+}
+
+// blocked reports whether a message of subject with stream sequence seq must not be handled yet,
+// since an earlier message of the subject was NAKed and not redelivered yet.
+func (q *subjectSequencer) blocked(subject string, seq uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	failed, ok := q.failed[subject]
+	return ok && seq > failed
+}
+
+func (q *subjectSequencer) nakked(subject string, seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if failed, ok := q.failed[subject]; !ok || seq < failed {
+		q.failed[subject] = seq
+	}
+}
+
+func (q *subjectSequencer) settled(subject string, seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.failed[subject] == seq {
+		delete(q.failed, subject)
+	}
+}
+
+// orderedAcker tracks NAKed messages of an Acker, so later messages of the same subject wait for their redelivery.
+type orderedAcker struct {
+	Acker
+	sequencer *subjectSequencer
+	subject   string
+	seq       uint64
+}
+
+func (a *orderedAcker) Ack() error {
+	a.sequencer.settled(a.subject, a.seq)
+	return a.Acker.Ack()
+}
+
+func (a *orderedAcker) Nak(delay time.Duration) error {
+	a.sequencer.nakked(a.subject, a.seq)
+	return a.Acker.Nak(delay)
+}
+
+func (a *orderedAcker) Term() error {
+	a.sequencer.settled(a.subject, a.seq)
+	return a.Acker.Term()
+}
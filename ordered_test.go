@@ -0,0 +1,174 @@
+package vnats
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubjectSequencer_enqueue(t *testing.T) {
+	q := newSubjectSequencer()
+	waitA1, doneA1 := q.enqueue("A")
+	waitA2, doneA2 := q.enqueue("A")
+	waitB1, doneB1 := q.enqueue("B")
+
+	for name, wait := range map[string]<-chan struct{}{"A1": waitA1, "B1": waitB1} {
+		select {
+		case <-wait:
+		default:
+			t.Errorf("first message %s of subject has to wait", name)
+		}
+	}
+	select {
+	case <-waitA2:
+		t.Error("second message of subject must wait for the first one")
+	default:
+	}
+
+	doneA1()
+	select {
+	case <-waitA2:
+	default:
+		t.Error("second message of subject still waits after the first one was handled")
+	}
+	doneA2()
+	doneB1()
+	if len(q.tails) != 0 {
+		t.Errorf("queues of handled subjects were not removed: %v", q.tails)
+	}
+}
+
+func TestSubjectSequencer_blocked(t *testing.T) {
+	q := newSubjectSequencer()
+	acker := &orderedAcker{Acker: &recordingAcker{}, sequencer: q, subject: "A", seq: 2}
+	if err := acker.Nak(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		seq     uint64
+		want    bool
+	}{
+		{name: "Earlier message", subject: "A", seq: 1, want: false},
+		{name: "NAKed message", subject: "A", seq: 2, want: false},
+		{name: "Later message", subject: "A", seq: 3, want: true},
+		{name: "Other subject", subject: "B", seq: 3, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := q.blocked(tt.subject, tt.seq); got != tt.want {
+				t.Errorf("blocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := acker.Ack(); err != nil {
+		t.Fatal(err)
+	}
+	if q.blocked("A", 3) {
+		t.Error("subject is still blocked after the NAKed message was acknowledged")
+	}
+}
+
+func TestSubscriber_OrderedPerSubject(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".orderedPerSubject"
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{}
+	for i := 1; i <= 4; i++ {
+		for _, key := range []string{"a", "b"} {
+			data := fmt.Sprintf("%s%d", key, i)
+			if _, err := pub.Publish(&Msg{Subject: subject + "." + key, MsgID: data, Data: []byte(data)}); err != nil {
+				t.Fatal(err)
+			}
+			want[key] = append(want[key], data)
+		}
+	}
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: "TestOrderedPerSubject",
+		Subject:      subject + ".*",
+		Mode:         SingleSubscriberOrderedPerSubject,
+		Concurrency:  4,
+		Backoff:      []time.Duration{time.Millisecond * 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	got := map[string][]string{}
+	handled := make(chan struct{}, 8)
+	if err := sub.Start(func(msg Msg) error {
+		if string(msg.Data) == "a1" && msg.Metadata.NumDelivered == 1 {
+			return fmt.Errorf("fail first delivery")
+		}
+		time.Sleep(time.Millisecond * 20)
+		key := msg.Subject[len(msg.Subject)-1:]
+		mu.Lock()
+		got[key] = append(got[key], string(msg.Data))
+		mu.Unlock()
+		handled <- struct{}{}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 8 {
+		select {
+		case <-handled:
+		case <-time.After(time.Second * 10):
+			t.Fatal("not all messages were handled")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for key := range want {
+		if !slices.Equal(got[key], want[key]) {
+			t.Errorf("messages of subject %s were handled in order %v, want %v", key, got[key], want[key])
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_NewSubscriber_SingleSubscriber(t *testing.T) {
+	tests := []struct {
+		name    string
+		first   SubscriptionMode
+		second  SubscriptionMode
+		wantErr bool
+	}{
+		{name: "Multiple subscribers", first: MultipleSubscribersAllowed, second: MultipleSubscribersAllowed},
+		{name: "Ordered per subject", first: SingleSubscriberOrderedPerSubject, second: SingleSubscriberOrderedPerSubject, wantErr: true},
+		{name: "Strict order", first: SingleSubscriberStrictMessageOrder, second: MultipleSubscribersAllowed, wantErr: true},
+		{name: "Unknown mode", first: MultipleSubscribersAllowed, second: SingleSubscriberOrderedPerSubject + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+			if _, err := conn.NewSubscriber(SubscriberArgs{ConsumerName: "shipping", Subject: "ORDERS.>", Mode: tt.first}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := conn.NewSubscriber(SubscriberArgs{ConsumerName: "billing", Subject: "ORDERS.>", Mode: SingleSubscriberOrderedPerSubject}); err != nil {
+				t.Errorf("NewSubscriber() of another consumer returned error %v", err)
+			}
+
+			_, err := conn.NewSubscriber(SubscriberArgs{ConsumerName: "shipping", Subject: "ORDERS.>", Mode: tt.second})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSubscriber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
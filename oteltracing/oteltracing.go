@@ -0,0 +1,105 @@
+// Package oteltracing creates OpenTelemetry spans for the messages published and handled by a vnats.Connection and
+// propagates the W3C trace context ("traceparent" and "tracestate" header), so distributed traces don't break at NATS:
+//
+//	conn, err := vnats.Connect(servers, vnats.WithTracer(oteltracing.New(otel.GetTracerProvider())))
+package oteltracing
+
+import (
+	"context"
+
+	"github.com/fond-of-vertigo/vnats"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the instrumentation scope of the spans created by Tracer.
+const tracerName = "github.com/fond-of-vertigo/vnats"
+
+// propagator injects and extracts the W3C trace context.
+var propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Tracer implements vnats.Tracer with an OpenTelemetry tracer. It creates a producer span "publish <stream>" for
+// each published message and a consumer span "process <stream>" for each call of a handler, which continues the
+// trace of the publisher.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ vnats.Tracer = (*Tracer)(nil)
+
+// New returns a Tracer, which creates spans with a tracer of provider. It must be passed to vnats.WithTracer.
+func New(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(tracerName)}
+}
+
+// StartPublish starts a producer span for publishing msg to stream.
+func (t *Tracer) StartPublish(ctx context.Context, stream string, msg *vnats.Msg) (context.Context, vnats.Span) {
+	ctx, span := t.tracer.Start(ctx, "publish "+stream,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.operation.type", "publish"),
+			attribute.String("messaging.destination.name", msg.Subject),
+			attribute.String("messaging.message.id", msg.MsgID),
+		))
+	return ctx, spanAdapter{span}
+}
+
+// Inject writes the trace context of ctx into header.
+func (t *Tracer) Inject(ctx context.Context, header vnats.Header) {
+	propagator.Inject(ctx, headerCarrier(header))
+}
+
+// StartProcess extracts the trace context of the publisher from the header of msg and starts a consumer span
+// as its child for handling msg.
+func (t *Tracer) StartProcess(ctx context.Context, stream, consumer string, msg vnats.Msg) (context.Context, vnats.Span) {
+	ctx = propagator.Extract(ctx, headerCarrier(msg.Header))
+	ctx, span := t.tracer.Start(ctx, "process "+stream,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.operation.type", "process"),
+			attribute.String("messaging.destination.name", msg.Subject),
+			attribute.String("messaging.consumer.group.name", consumer),
+			attribute.String("messaging.message.id", msg.MsgID),
+			attribute.Int64("messaging.nats.message.delivery_count", int64(msg.Metadata.NumDelivered)),
+		))
+	return ctx, spanAdapter{span}
+}
+
+// spanAdapter adapts a trace.Span to vnats.Span.
+type spanAdapter struct {
+	span trace.Span
+}
+
+// Fail records err and sets the status of the span to error.
+func (s spanAdapter) Fail(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End ends the span.
+func (s spanAdapter) End() {
+	s.span.End()
+}
+
+// headerCarrier adapts a vnats.Header to propagation.TextMapCarrier.
+type headerCarrier vnats.Header
+
+func (c headerCarrier) Get(key string) string {
+	return vnats.Header(c).Get(key)
+}
+
+func (c headerCarrier) Set(key, value string) {
+	vnats.Header(c).Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
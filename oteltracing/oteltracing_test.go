@@ -0,0 +1,57 @@
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fond-of-vertigo/vnats"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := New(provider)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "checkout")
+	msg := vnats.NewMsg("ORDERS.created", "order-1", nil)
+	ctx, publishSpan := tracer.StartPublish(ctx, "ORDERS", msg)
+	msg.Header = vnats.Header{}
+	tracer.Inject(ctx, msg.Header)
+	publishSpan.End()
+	parent.End()
+
+	_, processSpan := tracer.StartProcess(context.Background(), "ORDERS", "shipping", *msg)
+	processSpan.Fail(errors.New("failed"))
+	processSpan.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want publish, parent and process span", len(spans))
+	}
+	publish, process := spans[0], spans[2]
+	if publish.Name() != "publish ORDERS" || publish.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("got publish span %s of kind %s", publish.Name(), publish.SpanKind())
+	}
+	if publish.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("publish span is not a child of the span of the context")
+	}
+	want := "00-" + publish.SpanContext().TraceID().String() + "-" + publish.SpanContext().SpanID().String() + "-01"
+	if got := msg.Header.Get("traceparent"); got != want {
+		t.Errorf("traceparent header = %q, want %q", got, want)
+	}
+
+	if process.Name() != "process ORDERS" || process.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("got process span %s of kind %s", process.Name(), process.SpanKind())
+	}
+	if process.Parent().SpanID() != publish.SpanContext().SpanID() {
+		t.Error("process span is not a child of the publish span")
+	}
+	if process.Status().Code != codes.Error || process.Status().Description != "failed" {
+		t.Errorf("process span has status %v, want error", process.Status())
+	}
+}
@@ -0,0 +1,161 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// OutboxStore is the storage of an Outbox, usually a table in the database of the service. Messages are written to
+// the store in the same transaction as the business data, and published by the Outbox afterwards.
+type OutboxStore interface {
+	// Pending returns up to limit messages, which are not published yet, in the order they must be published.
+	// Each message must have a stable MsgID, e.g. the primary key of the outbox entry.
+	Pending(ctx context.Context, limit int) ([]*Msg, error)
+
+	// MarkPublished marks msgs as published, so they are not returned by Pending anymore.
+	MarkPublished(ctx context.Context, msgs []*Msg) error
+}
+
+// OutboxArgs contains the arguments for creating an Outbox.
+type OutboxArgs struct {
+	// Store contains the pending messages.
+	Store OutboxStore
+
+	// Interval is the time between polling the store for pending messages. Default is one second.
+	Interval time.Duration
+
+	// BatchSize is the maximum number of messages fetched from the store at once. Default is 100.
+	BatchSize int
+}
+
+// Outbox publishes the messages of an OutboxStore with the Publisher, which allows publishing messages
+// transactionally with database changes. Delivery is at-least-once: a message is marked as published only after
+// the server acknowledged it, and a message published again after a crash is deduplicated by its MsgID within the
+// duplicate window of the stream.
+type Outbox struct {
+	publisher *Publisher
+	store     OutboxStore
+	logger    *slog.Logger
+	interval  time.Duration
+	batchSize int
+
+	mu       sync.Mutex // guards quit and done
+	quit     chan struct{}
+	done     chan struct{}
+	flushing sync.Mutex // serializes Flush, so messages are published in order
+}
+
+// NewOutbox creates an Outbox, which publishes the messages of args.Store with the Publisher.
+func (p *Publisher) NewOutbox(args OutboxArgs) (*Outbox, error) {
+	if args.Store == nil {
+		return nil, errors.New("outbox could not be created: store is nil")
+	}
+	if args.Interval <= 0 {
+		args.Interval = defaultOutboxInterval
+	}
+	if args.BatchSize <= 0 {
+		args.BatchSize = defaultOutboxBatchSize
+	}
+
+	return &Outbox{
+		publisher: p,
+		store:     args.Store,
+		logger:    p.logger,
+		interval:  args.Interval,
+		batchSize: args.BatchSize,
+	}, nil
+}
+
+// Start polls the store in the background and publishes pending messages until Stop is called.
+func (o *Outbox) Start() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.quit != nil {
+		return fmt.Errorf("outbox is already started, don't call Start() multiple times")
+	}
+
+	o.quit = make(chan struct{})
+	o.done = make(chan struct{})
+	go o.run(o.quit, o.done)
+	return nil
+}
+
+// Stop stops polling the store and waits until the messages currently being published are marked as published.
+func (o *Outbox) Stop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.quit == nil {
+		return
+	}
+
+	close(o.quit)
+	<-o.done
+	o.quit, o.done = nil, nil
+}
+
+func (o *Outbox) run(quit, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			o.flushAll()
+		}
+	}
+}
+
+// flushAll publishes batches until the store has no pending messages left.
+func (o *Outbox) flushAll() {
+	defer recoverPanic(o.logger, o.publisher.conn.hooks, "outbox")
+
+	for {
+		published, err := o.Flush(context.Background())
+		if err != nil {
+			o.logger.Error("Outbox could not publish pending messages", slog.Any("error", err))
+			return
+		}
+		if published < o.batchSize {
+			return
+		}
+	}
+}
+
+// Flush publishes one batch of pending messages and returns the number of published messages. Publishing stops at
+// the first failed message, so the order is kept; all messages published before are marked as published.
+func (o *Outbox) Flush(ctx context.Context) (int, error) {
+	o.flushing.Lock()
+	defer o.flushing.Unlock()
+
+	msgs, err := o.store.Pending(ctx, o.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("pending messages could not be fetched from outbox: %w", err)
+	}
+
+	var publishErr error
+	published := 0
+	for _, msg := range msgs {
+		if msg.MsgID == "" {
+			publishErr = fmt.Errorf("message @ %s in outbox has no msgID", msg.Subject)
+			break
+		}
+		if _, publishErr = o.publisher.PublishWithContext(ctx, msg); publishErr != nil {
+			break
+		}
+		published++
+	}
+
+	if published > 0 {
+		if err := o.store.MarkPublished(ctx, msgs[:published]); err != nil {
+			return published, errors.Join(publishErr, fmt.Errorf("published messages could not be marked in outbox: %w", err))
+		}
+	}
+	return published, publishErr
+}
@@ -0,0 +1,150 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type memoryOutboxStore struct {
+	mu        sync.Mutex
+	pending   []*Msg
+	published []string
+}
+
+func (s *memoryOutboxStore) Pending(_ context.Context, limit int) ([]*Msg, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.pending[:min(limit, len(s.pending))]), nil
+}
+
+func (s *memoryOutboxStore) MarkPublished(_ context.Context, msgs []*Msg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, msg := range msgs {
+		s.published = append(s.published, msg.MsgID)
+	}
+	s.pending = s.pending[len(msgs):]
+	return nil
+}
+
+func (s *memoryOutboxStore) publishedMsgIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.published)
+}
+
+// recordingBridge records the MsgIDs of published messages and fails publishing of the MsgIDs in failMsgIDs.
+type recordingBridge struct {
+	testBridge
+	failMsgIDs map[string]bool
+}
+
+func (b *recordingBridge) PublishMsg(_ context.Context, _ *nats.Msg, msgID string) (*nats.PubAck, error) {
+	if b.failMsgIDs[msgID] {
+		return nil, errors.New("publishing failed")
+	}
+	b.sequenceNumber++
+	return &nats.PubAck{Stream: b.streamName, Sequence: b.sequenceNumber}, nil
+}
+
+func makeOutboxTestPublisher(t *testing.T, failMsgIDs ...string) *Publisher {
+	bridge := &recordingBridge{
+		testBridge: testBridge{TB: t, streamName: "ORDERS"},
+		failMsgIDs: make(map[string]bool),
+	}
+	for _, msgID := range failMsgIDs {
+		bridge.failMsgIDs[msgID] = true
+	}
+	return &Publisher{
+		conn:       &Connection{nats: bridge, logger: slog.Default()},
+		logger:     slog.Default(),
+		streamName: "ORDERS",
+	}
+}
+
+func TestOutbox_Flush(t *testing.T) {
+	tests := []struct {
+		name          string
+		pending       []*Msg
+		failMsgIDs    []string
+		wantPublished []string
+		wantErr       bool
+	}{
+		{
+			name:          "all published",
+			pending:       []*Msg{NewMsg("ORDERS.created", "order-1", nil), NewMsg("ORDERS.created", "order-2", nil)},
+			wantPublished: []string{"order-1", "order-2"},
+		},
+		{
+			name:          "stops at first failure to keep the order",
+			pending:       []*Msg{NewMsg("ORDERS.created", "order-1", nil), NewMsg("ORDERS.created", "order-2", nil), NewMsg("ORDERS.created", "order-3", nil)},
+			failMsgIDs:    []string{"order-2"},
+			wantPublished: []string{"order-1"},
+			wantErr:       true,
+		},
+		{
+			name:    "message without msgID",
+			pending: []*Msg{NewMsg("ORDERS.created", "", nil)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryOutboxStore{pending: tt.pending}
+			outbox, err := makeOutboxTestPublisher(t, tt.failMsgIDs...).NewOutbox(OutboxArgs{Store: store})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			published, err := outbox.Flush(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Flush() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if published != len(tt.wantPublished) {
+				t.Errorf("Flush() published = %d, want %d", published, len(tt.wantPublished))
+			}
+			if got := store.publishedMsgIDs(); !slices.Equal(got, tt.wantPublished) {
+				t.Errorf("marked as published %v, want %v", got, tt.wantPublished)
+			}
+		})
+	}
+}
+
+func TestOutbox_StartStop(t *testing.T) {
+	store := &memoryOutboxStore{}
+	for _, msgID := range []string{"order-1", "order-2", "order-3"} {
+		store.pending = append(store.pending, NewMsg("ORDERS.created", msgID, nil))
+	}
+
+	outbox, err := makeOutboxTestPublisher(t).NewOutbox(OutboxArgs{
+		Store:     store,
+		Interval:  time.Millisecond * 10,
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Start(); err == nil {
+		t.Error("Start() of a started outbox should fail")
+	}
+
+	deadline := time.Now().Add(time.Second * 2)
+	for len(store.publishedMsgIDs()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	outbox.Stop()
+
+	if got := store.publishedMsgIDs(); !slices.Equal(got, []string{"order-1", "order-2", "order-3"}) {
+		t.Errorf("marked as published %v", got)
+	}
+}
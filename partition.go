@@ -0,0 +1,30 @@
+package vnats
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+)
+
+// PublishPartitioned publishes msg to one of the partitions of its subject, chosen by hashing key. The partition is
+// appended to the subject, e.g. "ORDERS.created.p3", so all messages with the same key are published to the same
+// subject. With one Subscriber per partition subject, messages are processed in order per key while the processing
+// scales horizontally. The number of partitions is set with PublisherArgs.Partitions.
+func (p *Publisher) PublishPartitioned(key string, msg *Msg) (PubAck, error) {
+	if p.partitions <= 0 {
+		return PubAck{}, fmt.Errorf("partitions are not configured for stream %s", p.streamName)
+	}
+
+	partitioned := *msg
+	partitioned.Subject = fmt.Sprintf("%s.p%d", msg.Subject, partition(key, p.partitions))
+	ack, err := p.PublishWithContext(context.Background(), &partitioned)
+	msg.MsgID = partitioned.MsgID // keep a generated MsgID for retries, like Publish
+	return ack, err
+}
+
+// partition returns the partition of key in [0, partitions).
+func partition(key string, partitions int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
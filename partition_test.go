@@ -0,0 +1,57 @@
+package vnats
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func Test_partition(t *testing.T) {
+	const partitions = 4
+	counts := make([]int, partitions)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("customer-%d", i)
+		got := partition(key, partitions)
+		if got < 0 || got >= partitions {
+			t.Fatalf("partition(%s) = %d out of range", key, got)
+		}
+		if again := partition(key, partitions); again != got {
+			t.Fatalf("partition(%s) is not stable: %d != %d", key, got, again)
+		}
+		counts[got]++
+	}
+	for i, count := range counts {
+		if count < 150 {
+			t.Errorf("partition %d has only %d of 1000 keys", i, count)
+		}
+	}
+}
+
+func TestPublisher_PublishPartitioned(t *testing.T) {
+	conn := makeTestConnection(t, "ORDERS", 0, []byte("order"), "order-001", nil)
+	pub := &Publisher{conn: conn, streamName: "ORDERS", partitions: 8}
+
+	var subject string
+	pub.Use(func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, msg *Msg) (PubAck, error) {
+			subject = msg.Subject
+			return next(ctx, msg)
+		}
+	})
+
+	msg := NewMsg("ORDERS.created", "order-001", []byte("order"))
+	if _, err := pub.PublishPartitioned("customer-42", msg); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("ORDERS.created.p%d", partition("customer-42", 8)); subject != want {
+		t.Errorf("published to %s, want %s", subject, want)
+	}
+	if msg.Subject != "ORDERS.created" {
+		t.Errorf("PublishPartitioned() must not modify the subject of msg, got %s", msg.Subject)
+	}
+
+	pub.partitions = 0
+	if _, err := pub.PublishPartitioned("customer-42", msg); err == nil {
+		t.Error("PublishPartitioned() without partitions should fail")
+	}
+}
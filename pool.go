@@ -0,0 +1,90 @@
+package vnats
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+)
+
+// ConnectionPool maintains multiple Connections to a NATS server/ cluster and distributes Publishers and Subscribers
+// round-robin across them. This avoids a single TCP connection becoming the throughput bottleneck.
+type ConnectionPool struct {
+	connections []*Connection
+	next        atomic.Uint64
+}
+
+// ConnectPool returns a ConnectionPool with size Connections. All Connections are created with the same options,
+// except that Subscribers registered with WithSubscriber are only started by the first Connection, and the limit of
+// WithPublishRateLimit is shared by all Connections.
+func ConnectPool(servers []string, size int, options ...Option) (*ConnectionPool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("pool size must be at least 1, got %d", size)
+	}
+
+	pool := &ConnectionPool{}
+	for i := 0; i < size; i++ {
+		connOptions := options
+		if i > 0 {
+			connOptions = append(slices.Clip(options), withoutSubscribers(), withPublishLimiter(pool.connections[0].publishLimiter))
+		}
+		conn, err := Connect(servers, connOptions...)
+		if err != nil {
+			if closeErr := pool.Close(); closeErr != nil {
+				err = errors.Join(err, closeErr)
+			}
+			return nil, fmt.Errorf("connection %d of pool could not be created: %w", i, err)
+		}
+		pool.connections = append(pool.connections, conn)
+	}
+	return pool, nil
+}
+
+// Connections returns all Connections of the pool.
+func (p *ConnectionPool) Connections() []*Connection {
+	return p.connections
+}
+
+// NewPublisher creates a new Publisher on the next Connection of the pool.
+func (p *ConnectionPool) NewPublisher(args PublisherArgs) (*Publisher, error) {
+	return p.nextConnection().NewPublisher(args)
+}
+
+// NewSubscriber creates a new Subscriber on the next Connection of the pool.
+func (p *ConnectionPool) NewSubscriber(args SubscriberArgs) (*Subscriber, error) {
+	return p.nextConnection().NewSubscriber(args)
+}
+
+// Close closes all Connections of the pool. All Connections are closed, even if closing one of them fails.
+func (p *ConnectionPool) Close() error {
+	var errs []error
+	for _, conn := range p.connections {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *ConnectionPool) nextConnection() *Connection {
+	idx := p.next.Add(1) - 1
+	return p.connections[idx%uint64(len(p.connections))]
+}
+
+// withoutSubscribers drops the Subscribers registered with WithSubscriber by the options before,
+// so they are not started by each Connection of a ConnectionPool.
+func withoutSubscribers() Option {
+	return func(c *Connection) {
+		c.registrations = nil
+	}
+}
+
+// withPublishLimiter replaces the limiter created by WithPublishRateLimit before with limiter, so the Connections
+// of a ConnectionPool share the limit.
+func withPublishLimiter(limiter *rate.Limiter) Option {
+	return func(c *Connection) {
+		c.publishLimiter = limiter
+	}
+}
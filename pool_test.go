@@ -0,0 +1,82 @@
+package vnats
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestConnectionPool_NewPublisher(t *testing.T) {
+	pool := &ConnectionPool{
+		connections: []*Connection{
+			makeTestConnection(t, "PRODUCTS", 1, nil, "", nil),
+			makeTestConnection(t, "PRODUCTS", 1, nil, "", nil),
+		},
+	}
+
+	for i := 0; i < 4; i++ {
+		pub, err := pool.NewPublisher(PublisherArgs{StreamName: "PRODUCTS"})
+		if err != nil {
+			t.Fatalf("Publisher could not be created: %v", err)
+		}
+		if want := pool.connections[i%2]; pub.conn != want {
+			t.Errorf("Publisher %d was not created on connection %d", i, i%2)
+		}
+	}
+}
+
+func TestConnectPool_InvalidSize(t *testing.T) {
+	if _, err := ConnectPool([]string{"nats://localhost:4222"}, 0); err == nil {
+		t.Error("expected error for pool size 0, got nil")
+	}
+}
+
+func TestConnectPool_WithSubscriber(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	if err := makeIntegrationTestConn(t).Close(); err != nil { // creates the stream
+		t.Fatal(err)
+	}
+
+	args := SubscriberArgs{ConsumerName: "TestPoolSubscriber", Subject: integrationTestStreamName + ".pool"}
+	handler := func(_ context.Context, _ Msg) error { return nil }
+	pool, err := ConnectPool([]string{os.Getenv("NATS_SERVER_URL")}, 3, WithSubscriber(args, handler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, conn := range pool.Connections() {
+		want := 0
+		if i == 0 {
+			want = 1
+		}
+		if got := len(conn.subscriberList()); got != want {
+			t.Errorf("connection %d of pool has %d subscribers, want %d", i, got, want)
+		}
+	}
+	if err := pool.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnectPool_WithPublishRateLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	pool, err := ConnectPool([]string{os.Getenv("NATS_SERVER_URL")}, 3, WithPublishRateLimit(100, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := pool.Connections()[0].publishLimiter
+	if limiter == nil {
+		t.Fatal("first connection of pool has no publish limiter")
+	}
+	for i, conn := range pool.Connections() {
+		if conn.publishLimiter != limiter {
+			t.Errorf("connection %d of pool does not share the publish limiter", i)
+		}
+	}
+	if err := pool.Close(); err != nil {
+		t.Error(err)
+	}
+}
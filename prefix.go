@@ -0,0 +1,242 @@
+package vnats
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// prefixedBridge adds the prefix of WithStreamPrefix to all stream names and subjects passed to the wrapped
+// bridge, and removes it from the names and subjects returned. System subjects starting with "$" are not
+// prefixed.
+type prefixedBridge struct {
+	bridge
+	prefix string
+}
+
+// prefixed wraps b in a prefixedBridge, if a prefix is set by WithStreamPrefix.
+func (c *Connection) prefixed(b bridge) bridge {
+	if c.streamPrefix == "" {
+		return b
+	}
+	return &prefixedBridge{bridge: b, prefix: c.streamPrefix}
+}
+
+func (b *prefixedBridge) name(name string) string {
+	if name == "" {
+		return ""
+	}
+	return b.prefix + name
+}
+
+func (b *prefixedBridge) subject(subject string) string {
+	if subject == "" || strings.HasPrefix(subject, "$") {
+		return subject
+	}
+	return b.prefix + subject
+}
+
+func (b *prefixedBridge) subjects(subjects []string) []string {
+	if subjects == nil {
+		return nil
+	}
+	prefixed := make([]string, len(subjects))
+	for i, subject := range subjects {
+		prefixed[i] = b.subject(subject)
+	}
+	return prefixed
+}
+
+func (b *prefixedBridge) trim(nameOrSubject string) string {
+	return strings.TrimPrefix(nameOrSubject, b.prefix)
+}
+
+func (b *prefixedBridge) trimSubjects(subjects []string) []string {
+	if subjects == nil {
+		return nil
+	}
+	trimmed := make([]string, len(subjects))
+	for i, subject := range subjects {
+		trimmed[i] = b.trim(subject)
+	}
+	return trimmed
+}
+
+// streamConfig returns a copy of config with all names and subjects mapped by name and subject.
+func (b *prefixedBridge) streamConfig(config *nats.StreamConfig, name, subject func(string) string) *nats.StreamConfig {
+	mapped := *config
+	mapped.Name = name(config.Name)
+	if config.Subjects != nil {
+		mapped.Subjects = make([]string, len(config.Subjects))
+		for i, s := range config.Subjects {
+			mapped.Subjects[i] = subject(s)
+		}
+	}
+	mapSource := func(source *nats.StreamSource) *nats.StreamSource {
+		m := *source
+		m.Name = name(source.Name)
+		m.FilterSubject = subject(source.FilterSubject)
+		return &m
+	}
+	if config.Mirror != nil {
+		mapped.Mirror = mapSource(config.Mirror)
+	}
+	if config.Sources != nil {
+		mapped.Sources = make([]*nats.StreamSource, len(config.Sources))
+		for i, source := range config.Sources {
+			mapped.Sources[i] = mapSource(source)
+		}
+	}
+	if config.SubjectTransform != nil {
+		mapped.SubjectTransform = &nats.SubjectTransformConfig{
+			Source:      subject(config.SubjectTransform.Source),
+			Destination: subject(config.SubjectTransform.Destination),
+		}
+	}
+	if config.RePublish != nil {
+		rePublish := *config.RePublish
+		rePublish.Source = subject(rePublish.Source)
+		rePublish.Destination = subject(rePublish.Destination)
+		mapped.RePublish = &rePublish
+	}
+	return &mapped
+}
+
+func (b *prefixedBridge) consumerInfo(info *nats.ConsumerInfo) *nats.ConsumerInfo {
+	trimmed := *info
+	trimmed.Stream = b.trim(info.Stream)
+	trimmed.Config.FilterSubject = b.trim(info.Config.FilterSubject)
+	trimmed.Config.FilterSubjects = b.trimSubjects(info.Config.FilterSubjects)
+	return &trimmed
+}
+
+func (b *prefixedBridge) rawStreamMsg(msg *nats.RawStreamMsg, err error) (*nats.RawStreamMsg, error) {
+	if err != nil {
+		return nil, err
+	}
+	trimmed := *msg
+	trimmed.Subject = b.trim(msg.Subject)
+	return &trimmed, nil
+}
+
+func (b *prefixedBridge) natsMsg(msg *nats.Msg) *nats.Msg {
+	return &nats.Msg{
+		Subject: b.subject(msg.Subject),
+		Reply:   msg.Reply,
+		Header:  msg.Header,
+		Data:    msg.Data,
+	}
+}
+
+func (b *prefixedBridge) EnsureStreamExists(streamConfig *nats.StreamConfig) error {
+	return b.bridge.EnsureStreamExists(b.streamConfig(streamConfig, b.name, b.subject))
+}
+
+func (b *prefixedBridge) StreamInfo(streamName string) (*nats.StreamInfo, error) {
+	info, err := b.bridge.StreamInfo(b.name(streamName))
+	if err != nil {
+		return nil, err
+	}
+	trimmed := *info
+	trimmed.Config = *b.streamConfig(&info.Config, b.trim, b.trim)
+	return &trimmed, nil
+}
+
+func (b *prefixedBridge) UpdateStream(streamConfig *nats.StreamConfig) error {
+	return b.bridge.UpdateStream(b.streamConfig(streamConfig, b.name, b.subject))
+}
+
+func (b *prefixedBridge) DeleteStream(streamName string) error {
+	return b.bridge.DeleteStream(b.name(streamName))
+}
+
+func (b *prefixedBridge) PurgeStream(streamName string, request *nats.StreamPurgeRequest) error {
+	if request != nil {
+		prefixed := *request
+		prefixed.Subject = b.subject(request.Subject)
+		request = &prefixed
+	}
+	return b.bridge.PurgeStream(b.name(streamName), request)
+}
+
+func (b *prefixedBridge) GetMsg(streamName string, seq uint64) (*nats.RawStreamMsg, error) {
+	return b.rawStreamMsg(b.bridge.GetMsg(b.name(streamName), seq))
+}
+
+func (b *prefixedBridge) GetLastMsg(streamName, subject string) (*nats.RawStreamMsg, error) {
+	return b.rawStreamMsg(b.bridge.GetLastMsg(b.name(streamName), b.subject(subject)))
+}
+
+func (b *prefixedBridge) GetNextMsg(streamName, subject string, seq uint64) (*nats.RawStreamMsg, error) {
+	return b.rawStreamMsg(b.bridge.GetNextMsg(b.name(streamName), b.subject(subject), seq))
+}
+
+func (b *prefixedBridge) SnapshotStream(streamName string, w io.Writer, timeout time.Duration) error {
+	return b.bridge.SnapshotStream(b.name(streamName), w, timeout)
+}
+
+func (b *prefixedBridge) RestoreStream(streamName string, r io.Reader, timeout time.Duration) error {
+	return b.bridge.RestoreStream(b.name(streamName), r, timeout)
+}
+
+func (b *prefixedBridge) DeleteMsg(streamName string, seq uint64, secure bool) error {
+	return b.bridge.DeleteMsg(b.name(streamName), seq, secure)
+}
+
+func (b *prefixedBridge) Consumers(streamName string) ([]*nats.ConsumerInfo, error) {
+	infos, err := b.bridge.Consumers(b.name(streamName))
+	if err != nil {
+		return nil, err
+	}
+	trimmed := make([]*nats.ConsumerInfo, len(infos))
+	for i, info := range infos {
+		trimmed[i] = b.consumerInfo(info)
+	}
+	return trimmed, nil
+}
+
+func (b *prefixedBridge) ConsumerInfo(streamName, consumerName string) (*nats.ConsumerInfo, error) {
+	info, err := b.bridge.ConsumerInfo(b.name(streamName), consumerName)
+	if err != nil {
+		return nil, err
+	}
+	return b.consumerInfo(info), nil
+}
+
+func (b *prefixedBridge) EnsureConsumer(streamName string, consumerConfig *nats.ConsumerConfig) error {
+	prefixed := *consumerConfig
+	prefixed.FilterSubject = b.subject(consumerConfig.FilterSubject)
+	prefixed.FilterSubjects = b.subjects(consumerConfig.FilterSubjects)
+	return b.bridge.EnsureConsumer(b.name(streamName), &prefixed)
+}
+
+func (b *prefixedBridge) PauseConsumer(streamName, consumerName string, until time.Time) error {
+	return b.bridge.PauseConsumer(b.name(streamName), consumerName, until)
+}
+
+func (b *prefixedBridge) DeleteConsumer(streamName, consumerName string) error {
+	return b.bridge.DeleteConsumer(b.name(streamName), consumerName)
+}
+
+func (b *prefixedBridge) Subscribe(args SubscriberArgs) (*nats.Subscription, error) {
+	args.Subject = b.subject(args.Subject)
+	args.Subjects = b.subjects(args.Subjects)
+	return b.bridge.Subscribe(args)
+}
+
+func (b *prefixedBridge) PublishMsg(ctx context.Context, msg *nats.Msg, msgID string) (*nats.PubAck, error) {
+	ack, err := b.bridge.PublishMsg(ctx, b.natsMsg(msg), msgID)
+	if ack == nil {
+		return nil, err
+	}
+	trimmed := *ack
+	trimmed.Stream = b.trim(ack.Stream)
+	return &trimmed, err
+}
+
+func (b *prefixedBridge) PublishMsgAsync(msg *nats.Msg, msgID string) (nats.PubAckFuture, error) {
+	return b.bridge.PublishMsgAsync(b.natsMsg(msg), msgID)
+}
@@ -0,0 +1,159 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+)
+
+func TestWithStreamPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		wantErr bool
+	}{
+		{name: "prefix", prefix: "STG_"},
+		{name: "no prefix", prefix: ""},
+		{name: "prefix with dot", prefix: "STG.", wantErr: true},
+		{name: "prefix with wildcard", prefix: "STG*", wantErr: true},
+		{name: "prefix with space", prefix: "STG ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &Connection{}
+			conn.applyOptions(WithStreamPrefix(tt.prefix))
+			if err := conn.validateOptions(); (err != nil) != tt.wantErr {
+				t.Errorf("validateOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnection_StreamPrefix(t *testing.T) {
+	conn := makeTestConnection(t, "STG_ORDERS", 0, nil, "", nil)
+	b := conn.nats.(*testBridge)
+	conn.streamPrefix = "STG_"
+	conn.nats = conn.prefixed(b)
+
+	b.streamInfo = &nats.StreamInfo{Config: nats.StreamConfig{
+		Name:     "STG_ORDERS",
+		Subjects: []string{"STG_ORDERS.>"},
+		Sources:  []*nats.StreamSource{{Name: "STG_INVOICES", FilterSubject: "STG_INVOICES.paid"}},
+	}}
+	info, err := conn.StreamInfo("ORDERS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "ORDERS" {
+		t.Errorf("StreamInfo() returned name %s, want ORDERS", info.Name)
+	}
+
+	if err := conn.UpdateStream(StreamConfig{
+		Name:     "ORDERS",
+		Subjects: []string{"ORDERS.>", "$JS.EVENT.ADVISORY.>"},
+		Sources:  []StreamSource{{Name: "INVOICES", FilterSubject: "INVOICES.paid"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"STG_ORDERS.>", "$JS.EVENT.ADVISORY.>"}, b.updatedStream.Subjects); diff != "" {
+		t.Errorf("UpdateStream() passed unexpected subjects (-want +got):\n%s", diff)
+	}
+	if got := b.updatedStream.Sources[0]; got.Name != "STG_INVOICES" || got.FilterSubject != "STG_INVOICES.paid" {
+		t.Errorf("UpdateStream() passed source %s filtered by %s, want STG_INVOICES filtered by STG_INVOICES.paid",
+			got.Name, got.FilterSubject)
+	}
+	if b.streamInfo.Config.Name != "STG_ORDERS" {
+		t.Errorf("StreamInfo() modified the config of the wrapped bridge: %s", b.streamInfo.Config.Name)
+	}
+
+	if err := conn.PurgeSubject("ORDERS", "ORDERS.created"); err != nil {
+		t.Fatal(err)
+	}
+	if b.purgeRequest.Subject != "STG_ORDERS.created" {
+		t.Errorf("PurgeSubject() passed subject %s, want STG_ORDERS.created", b.purgeRequest.Subject)
+	}
+
+	if err := conn.ApplyTopology(Topology{Consumers: []ConsumerConfig{{
+		Stream:   "ORDERS",
+		Name:     "shipping",
+		Subjects: []string{"ORDERS.created"},
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.consumers[0]; got.Stream != "STG_ORDERS" || got.Config.FilterSubject != "STG_ORDERS.created" {
+		t.Errorf("EnsureConsumer() passed stream %s filtered by %s, want STG_ORDERS filtered by STG_ORDERS.created",
+			got.Stream, got.Config.FilterSubject)
+	}
+	consumer, err := conn.ConsumerInfo("ORDERS", "shipping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consumer.Stream != "ORDERS" || !cmp.Equal(consumer.Subjects, []string{"ORDERS.created"}) {
+		t.Errorf("ConsumerInfo() returned stream %s filtered by %v, want ORDERS filtered by [ORDERS.created]",
+			consumer.Stream, consumer.Subjects)
+	}
+}
+
+func TestConnection_StreamPrefix_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_PREFIX"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream("STG_" + streamName)
+	t.Cleanup(func() { _ = js.DeleteStream("STG_" + streamName) })
+	conn.streamPrefix = "STG_"
+	conn.nats = conn.prefixed(conn.nats)
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack, err := pub.PublishWithContext(context.Background(), &Msg{Subject: streamName + ".created", Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Stream != streamName {
+		t.Errorf("PublishWithContext() acknowledged stream %s, want %s", ack.Stream, streamName)
+	}
+
+	// the stream is stored with the prefix on the server
+	if _, err := js.StreamInfo(streamName); !errors.Is(err, nats.ErrStreamNotFound) {
+		t.Errorf("stream %s without prefix exists, error %v", streamName, err)
+	}
+	if _, err := js.GetLastMsg("STG_"+streamName, "STG_"+streamName+".created"); err != nil {
+		t.Errorf("message was not stored with prefixed subject: %v", err)
+	}
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{ConsumerName: "TestStreamPrefix", Subject: streamName + ".>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan Msg, 1)
+	if err := sub.Start(func(msg Msg) error {
+		received <- msg
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-received:
+		if msg.Subject != streamName+".created" || msg.Metadata.Stream != streamName {
+			t.Errorf("received message of stream %s @ %s, want %s @ %s.created",
+				msg.Metadata.Stream, msg.Subject, streamName, streamName)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("message was not received")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
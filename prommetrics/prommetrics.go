@@ -0,0 +1,139 @@
+// Package prommetrics exports the measurements of a vnats.Connection to Prometheus:
+//
+//	metrics := prommetrics.New()
+//	prometheus.MustRegister(metrics)
+//	conn, err := vnats.Connect(servers, vnats.WithMetrics(metrics))
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/fond-of-vertigo/vnats"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "vnats"
+
+// Metrics implements vnats.Metrics and is a prometheus.Collector of the following metrics:
+//
+//   - vnats_published_total{stream,result}: messages published, by vnats.PublishResult
+//   - vnats_publish_duration_seconds{stream}: time publishing a message took
+//   - vnats_delivered_total{stream,consumer}: messages delivered to subscribers
+//   - vnats_redelivered_total{stream,consumer}: messages delivered more than once
+//   - vnats_acks_total{stream,consumer,result}: acknowledgments, by vnats.AckResult
+//   - vnats_ack_duration_seconds{stream,consumer}: time from the delivery of a message to its acknowledgment
+//   - vnats_consumer_pending{stream,consumer}: messages not delivered yet, the lag of the consumer
+//   - vnats_handlers_in_flight{stream,consumer}: handlers currently running
+//   - vnats_reconnects_total: reconnects to the NATS server/ cluster
+type Metrics struct {
+	published       *prometheus.CounterVec
+	publishDuration *prometheus.HistogramVec
+	delivered       *prometheus.CounterVec
+	redelivered     *prometheus.CounterVec
+	acks            *prometheus.CounterVec
+	ackDuration     *prometheus.HistogramVec
+	pending         *prometheus.GaugeVec
+	inFlight        *prometheus.GaugeVec
+	reconnects      prometheus.Counter
+}
+
+var _ vnats.Metrics = (*Metrics)(nil)
+
+// New returns Metrics, which must be registered, e.g. with prometheus.MustRegister, and passed to
+// vnats.WithMetrics.
+func New() *Metrics {
+	return &Metrics{
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "published_total",
+			Help:      "Number of messages published, by result.",
+		}, []string{"stream", "result"}),
+		publishDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "publish_duration_seconds",
+			Help:      "Time publishing a message took, including retries.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"stream"}),
+		delivered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "delivered_total",
+			Help:      "Number of messages delivered to subscribers.",
+		}, []string{"stream", "consumer"}),
+		redelivered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redelivered_total",
+			Help:      "Number of messages delivered more than once.",
+		}, []string{"stream", "consumer"}),
+		acks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "acks_total",
+			Help:      "Number of acknowledgments of messages, by result.",
+		}, []string{"stream", "consumer", "result"}),
+		ackDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ack_duration_seconds",
+			Help:      "Time from the delivery of a message to its acknowledgment.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"stream", "consumer"}),
+		pending: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "consumer_pending",
+			Help:      "Number of messages not delivered to the consumer yet.",
+		}, []string{"stream", "consumer"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "handlers_in_flight",
+			Help:      "Number of message handlers currently running.",
+		}, []string{"stream", "consumer"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconnects_total",
+			Help:      "Number of reconnects to the NATS server.",
+		}),
+	}
+}
+
+func (m *Metrics) ObservePublish(stream string, result vnats.PublishResult, latency time.Duration) {
+	m.published.WithLabelValues(stream, string(result)).Inc()
+	m.publishDuration.WithLabelValues(stream).Observe(latency.Seconds())
+}
+
+func (m *Metrics) ObserveDelivery(stream, consumer string, redelivered bool, pending uint64) {
+	m.delivered.WithLabelValues(stream, consumer).Inc()
+	if redelivered {
+		m.redelivered.WithLabelValues(stream, consumer).Inc()
+	}
+	m.pending.WithLabelValues(stream, consumer).Set(float64(pending))
+}
+
+func (m *Metrics) ObserveAck(stream, consumer string, result vnats.AckResult, latency time.Duration) {
+	m.acks.WithLabelValues(stream, consumer, string(result)).Inc()
+	m.ackDuration.WithLabelValues(stream, consumer).Observe(latency.Seconds())
+}
+
+func (m *Metrics) AddInFlight(stream, consumer string, delta int) {
+	m.inFlight.WithLabelValues(stream, consumer).Add(float64(delta))
+}
+
+func (m *Metrics) ObserveReconnect() {
+	m.reconnects.Inc()
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.published, m.publishDuration, m.delivered, m.redelivered, m.acks, m.ackDuration, m.pending, m.inFlight,
+		m.reconnects,
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
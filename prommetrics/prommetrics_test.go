@@ -0,0 +1,66 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fond-of-vertigo/vnats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := New()
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics.ObservePublish("ORDERS", vnats.PublishSucceeded, time.Millisecond*3)
+	metrics.ObservePublish("ORDERS", vnats.PublishSucceeded, time.Millisecond*7)
+	metrics.ObservePublish("ORDERS", vnats.PublishFailed, time.Second)
+	metrics.ObserveDelivery("ORDERS", "shipping", false, 3)
+	metrics.ObserveDelivery("ORDERS", "shipping", true, 2)
+	metrics.AddInFlight("ORDERS", "shipping", 1)
+	metrics.AddInFlight("ORDERS", "shipping", 1)
+	metrics.AddInFlight("ORDERS", "shipping", -1)
+	metrics.ObserveAck("ORDERS", "shipping", vnats.Naked, time.Millisecond*20)
+	metrics.ObserveReconnect()
+
+	want := `
+# HELP vnats_acks_total Number of acknowledgments of messages, by result.
+# TYPE vnats_acks_total counter
+vnats_acks_total{consumer="shipping",result="nak",stream="ORDERS"} 1
+# HELP vnats_consumer_pending Number of messages not delivered to the consumer yet.
+# TYPE vnats_consumer_pending gauge
+vnats_consumer_pending{consumer="shipping",stream="ORDERS"} 2
+# HELP vnats_delivered_total Number of messages delivered to subscribers.
+# TYPE vnats_delivered_total counter
+vnats_delivered_total{consumer="shipping",stream="ORDERS"} 2
+# HELP vnats_handlers_in_flight Number of message handlers currently running.
+# TYPE vnats_handlers_in_flight gauge
+vnats_handlers_in_flight{consumer="shipping",stream="ORDERS"} 1
+# HELP vnats_published_total Number of messages published, by result.
+# TYPE vnats_published_total counter
+vnats_published_total{result="failed",stream="ORDERS"} 1
+vnats_published_total{result="succeeded",stream="ORDERS"} 2
+# HELP vnats_reconnects_total Number of reconnects to the NATS server.
+# TYPE vnats_reconnects_total counter
+vnats_reconnects_total 1
+# HELP vnats_redelivered_total Number of messages delivered more than once.
+# TYPE vnats_redelivered_total counter
+vnats_redelivered_total{consumer="shipping",stream="ORDERS"} 1
+`
+	names := []string{
+		"vnats_acks_total", "vnats_consumer_pending", "vnats_delivered_total", "vnats_handlers_in_flight",
+		"vnats_published_total", "vnats_reconnects_total", "vnats_redelivered_total",
+	}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), names...); err != nil {
+		t.Error(err)
+	}
+
+	if got := testutil.CollectAndCount(metrics, "vnats_publish_duration_seconds", "vnats_ack_duration_seconds"); got != 2 {
+		t.Errorf("got %d duration histograms, want 2", got)
+	}
+}
@@ -1,6 +1,8 @@
 package vnats
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -9,46 +11,397 @@ import (
 	"github.com/nats-io/nats.go"
 )
 
+// ErrExpectationNotMet is returned by Publish, if the Expectations of a message are not met by the stream.
+var ErrExpectationNotMet = errors.New("publish expectation not met")
+
+// jsErrCodeStreamWrongLastMsgID is returned by the server if the ExpectedLastMsgID does not match.
+const jsErrCodeStreamWrongLastMsgID nats.ErrorCode = 10070
+
 // NewPublisher creates a new Publisher that publishes to a NATS stream.
 func (c *Connection) NewPublisher(args PublisherArgs) (*Publisher, error) {
 	if err := validateStreamName(args.StreamName); err != nil {
 		return nil, err
 	}
-	if err := c.nats.EnsureStreamExists(&nats.StreamConfig{
-		Name:       args.StreamName,
-		Subjects:   []string{args.StreamName + ".>"},
-		Storage:    defaultStorageType,
-		Replicas:   len(c.nats.Servers()),
-		Duplicates: defaultDuplicationWindow,
-		MaxAge:     time.Hour * 24 * 30,
-	}); err != nil {
-		return nil, fmt.Errorf("publisher could not be created: %w", err)
+	if c.strictTopology {
+		if err := c.requireStream(args.StreamName); err != nil {
+			return nil, fmt.Errorf("publisher could not be created: %w", err)
+		}
+	} else {
+		streamConfig := args.StreamConfig
+		streamConfig.Name = args.StreamName
+		if err := c.EnsureStream(streamConfig); err != nil {
+			return nil, fmt.Errorf("publisher could not be created: %w", err)
+		}
 	}
 
 	p := &Publisher{
 		conn:       c,
-		logger:     c.logger,
+		logger:     c.logger.With(slog.String("stream", args.StreamName)),
 		streamName: args.StreamName,
+		retry:      args.Retry,
+
+		defaultHeader: args.DefaultHeader.clone(),
+		deadLetters:   args.DeadLetters,
+		partitions:    args.Partitions,
+		chunking:      args.Chunking,
+	}
+	p.stats.stream, p.stats.metrics = args.StreamName, c.metrics
+	if c.publishedMsgIDs != nil {
+		info, err := c.nats.StreamInfo(args.StreamName)
+		if err != nil {
+			return nil, fmt.Errorf("publisher could not be created: stream %s could not be fetched: %w", args.StreamName, err)
+		}
+		p.duplicateWindow = info.Config.Duplicates
+	}
+
+	if args.DelayedMessages {
+		if err := p.startDelayedForwarder(); err != nil {
+			return nil, fmt.Errorf("publisher could not be created: %w", err)
+		}
 	}
 	return p, nil
 }
 
+// natsMsg converts msg to a NATS message, merges the default header of the Publisher and compresses the data.
+// msg itself is not modified.
+func (p *Publisher) natsMsg(msg *Msg) (*nats.Msg, error) {
+	natsMsg := msg.toNATS()
+	if natsMsg.Header == nil {
+		natsMsg.Header = nats.Header{}
+	}
+	for key, values := range p.defaultHeader {
+		if _, ok := natsMsg.Header[key]; !ok { // values of the message take precedence
+			natsMsg.Header[key] = append([]string(nil), values...)
+		}
+	}
+
+	if err := compressMsg(p.conn.compression, natsMsg, msg.MsgID); err != nil {
+		return nil, err
+	}
+	return natsMsg, nil
+}
+
 // Publisher is a NATS publisher that publishes to a NATS stream.
 type Publisher struct {
 	conn       *Connection
 	streamName string
 	logger     *slog.Logger
+	retry      RetryPolicy
+
+	defaultHeader   Header
+	delayedMessages bool
+	delayedMaxAge   time.Duration // of the delay stream, the maximum delay of PublishAfter, unlimited if zero
+	interceptors    []PublishInterceptor
+	deadLetters     DeadLetterSink
+	partitions      int
+	chunking        bool          // splits data exceeding the max payload, see PublisherArgs.Chunking
+	duplicateWindow time.Duration // of the stream, how long MsgIDs are cached, see WithLocalDeduplication
+	stats           publisherStats
 }
 
-// Publish publishes the message (data) to the given subject.
-func (p *Publisher) Publish(msg *Msg) error {
-	if err := validateSubject(msg.Subject, p.streamName); err != nil {
-		return err
+// Publish publishes the message (data) to the given subject and returns the PubAck of the server.
+// With PublisherArgs.Chunking, data exceeding the max payload of the server is split into ordered chunks,
+// and the PubAck of a chunked message is the one of its last chunk.
+func (p *Publisher) Publish(msg *Msg) (PubAck, error) {
+	return p.PublishWithContext(context.Background(), msg)
+}
+
+// PublishWithContext publishes the message (data) to the given subject like Publish. Waiting for the rate limit
+// and the acknowledgment of the server is aborted, when ctx is done.
+func (p *Publisher) PublishWithContext(ctx context.Context, msg *Msg) (PubAck, error) {
+	return p.publishMsg(ctx, msg, p.intercept(p.publish))
+}
+
+// publishMsg validates msg and publishes it with publish, unless its MsgID was published already.
+func (p *Publisher) publishMsg(ctx context.Context, msg *Msg, publish PublishFunc) (ack PubAck, err error) {
+	if err := p.validate(msg); err != nil {
+		return PubAck{}, err
+	}
+	p.ensureMsgID(msg)
+
+	ctx, span := p.conn.startPublishSpan(ctx, p.streamName, msg)
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	if ack, ok := p.conn.publishedMsgIDs.get(p.streamName, msg.MsgID); ok {
+		ack.Duplicate = true
+		p.stats.duplicates.Add(1)
+		p.stats.observe(PublishDuplicate, time.Since(start))
+		return ack, nil
+	}
+
+	ack, err = publish(ctx, msg)
+	p.stats.record(msg, ack, time.Since(start), err)
+	if err != nil {
+		return PubAck{}, p.deadLetter(msg, err)
+	}
+	p.conn.publishedMsgIDs.add(p.streamName, msg.MsgID, ack, p.duplicateWindow)
+	return ack, nil
+}
+
+// publish publishes the validated msg, after waiting for the rate limit.
+func (p *Publisher) publish(ctx context.Context, msg *Msg) (PubAck, error) {
+	return p.publishChunked(ctx, msg, p.chunking)
+}
+
+// publishChunked publishes msg like publish. Data exceeding the max payload of the server is only split into
+// chunks, if chunked is true; otherwise the server rejects it.
+func (p *Publisher) publishChunked(ctx context.Context, msg *Msg, chunked bool) (PubAck, error) {
+	if p.conn.publishLimiter != nil {
+		if err := p.conn.publishLimiter.Wait(ctx); err != nil {
+			return PubAck{}, fmt.Errorf("message with msgID: %s @ %s was rate limited: %w", msg.MsgID, msg.Subject, err)
+		}
+	}
+
+	natsMsg, err := p.natsMsg(msg)
+	if err != nil {
+		return PubAck{}, err
+	}
+	p.conn.injectTrace(ctx, Header(natsMsg.Header))
+
+	start := time.Now()
+	var ack *nats.PubAck
+	retry := p.retry
+	if msg.MsgID == "" { // a retry could store the message twice, since the server can't deduplicate it
+		retry.MaxAttempts = 0
+	}
+	chunks := []chunk{{msg: natsMsg, msgID: msg.MsgID}}
+	if chunked {
+		chunks = p.splitChunks(natsMsg, msg.MsgID)
+	}
+	for _, c := range chunks {
+		err = retry.retry(ctx, func() error {
+			ack, err = p.conn.nats.PublishMsg(ctx, c.msg, c.msgID)
+			return err
+		})
+		if err != nil {
+			return PubAck{}, fmt.Errorf("message with msgID: %s @ %s could not be published: %w", msg.MsgID, msg.Subject, wrapExpectationErr(err))
+		}
+	}
+	p.stats.ackLatency.observe(time.Since(start))
+	return makePubAck(ack), nil
+}
+
+// ensureMsgID sets the MsgID of msg with the MsgIDGenerator of the Connection, if msg has no MsgID.
+func (p *Publisher) ensureMsgID(msg *Msg) {
+	if msg.MsgID == "" && p.conn.msgIDGenerator != nil {
+		msg.MsgID = p.conn.msgIDGenerator(msg)
+	}
+}
+
+// wrapExpectationErr wraps err with ErrExpectationNotMet, if the server rejected the message because of
+// its Expectations.
+func wrapExpectationErr(err error) error {
+	var apiErr *nats.APIError
+	if errors.As(err, &apiErr) &&
+		(apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence || apiErr.ErrorCode == jsErrCodeStreamWrongLastMsgID) {
+		return fmt.Errorf("%w: %w", ErrExpectationNotMet, err)
+	}
+	return err
+}
+
+// PubAck is the acknowledgment of the server for a published message.
+type PubAck struct {
+	// Stream is the name of the stream the message was stored in.
+	Stream string
+
+	// Sequence is the sequence number of the message in the stream.
+	Sequence uint64
+
+	// Duplicate is true, if a message with the same MsgID was already stored within the duplicate window.
+	Duplicate bool
+
+	// Domain is the JetStream domain of the stream.
+	Domain string
+}
+
+func makePubAck(ack *nats.PubAck) PubAck {
+	return PubAck{
+		Stream:    ack.Stream,
+		Sequence:  ack.Sequence,
+		Duplicate: ack.Duplicate,
+		Domain:    ack.Domain,
+	}
+}
+
+// PublishFuture is returned by PublishAsync and resolves when the server acknowledged the message.
+type PublishFuture struct {
+	msg    *Msg
+	future nats.PubAckFuture
+	stats  *publisherStats
+	sentAt time.Time
+
+	streamPrefix string // removed from the acknowledged stream name, see WithStreamPrefix
+}
+
+// Msg returns the published message.
+func (f *PublishFuture) Msg() *Msg {
+	return f.msg
+}
+
+// Wait blocks until the server acknowledged the message, publishing failed or ctx is done.
+func (f *PublishFuture) Wait(ctx context.Context) (PubAck, error) {
+	select {
+	case ack := <-f.future.Ok():
+		f.stats.ackLatency.observe(time.Since(f.sentAt))
+		pubAck := makePubAck(ack)
+		pubAck.Stream = strings.TrimPrefix(pubAck.Stream, f.streamPrefix)
+		f.stats.record(f.msg, pubAck, time.Since(f.sentAt), nil)
+		return pubAck, nil
+	case err := <-f.future.Err():
+		f.stats.record(f.msg, PubAck{}, time.Since(f.sentAt), err)
+		return PubAck{}, fmt.Errorf("message with msgID: %s @ %s could not be published: %w", f.msg.MsgID, f.msg.Subject, wrapExpectationErr(err))
+	case <-ctx.Done():
+		return PubAck{}, ctx.Err()
+	}
+}
+
+// PublishAsync publishes the message without waiting for the acknowledgment of the server. The returned
+// PublishFuture resolves when the message was acknowledged. The number of pending acknowledgments can be bounded
+// with WithPublishAsyncMaxPending. Unlike Publish, PublishAsync doesn't split data exceeding the max payload of the
+// server into chunks, the message is rejected instead. The span created with WithTracer ends when the
+// message was sent.
+func (p *Publisher) PublishAsync(msg *Msg) (future *PublishFuture, err error) {
+	if err := p.validate(msg); err != nil {
+		return nil, err
+	}
+	p.ensureMsgID(msg)
+
+	ctx, span := p.conn.startPublishSpan(context.Background(), p.streamName, msg)
+	defer func() { endSpan(span, err) }()
+
+	_, err = p.intercept(func(ctx context.Context, msg *Msg) (PubAck, error) {
+		var err error
+		future, err = p.publishAsync(ctx, msg)
+		return PubAck{}, err
+	})(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	if future == nil {
+		return nil, fmt.Errorf("message with msgID: %s @ %s was not published by the interceptors", msg.MsgID, msg.Subject)
 	}
+	return future, nil
+}
+
+// publishAsync publishes the validated msg without waiting for the acknowledgment, after waiting for the rate limit.
+func (p *Publisher) publishAsync(ctx context.Context, msg *Msg) (*PublishFuture, error) {
+	if p.conn.publishLimiter != nil {
+		if err := p.conn.publishLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("message with msgID: %s @ %s was rate limited: %w", msg.MsgID, msg.Subject, err)
+		}
+	}
+
+	natsMsg, err := p.natsMsg(msg)
+	if err != nil {
+		return nil, err
+	}
+	p.conn.injectTrace(ctx, Header(natsMsg.Header))
 
-	err := p.conn.nats.PublishMsg(msg.toNATS(), msg.MsgID)
+	sentAt := time.Now()
+	future, err := p.conn.nats.PublishMsgAsync(natsMsg, msg.MsgID)
 	if err != nil {
-		return fmt.Errorf("message with msgID: %s @ %s could not be published: %w", msg.MsgID, msg.Subject, err)
+		p.stats.record(msg, PubAck{}, time.Since(sentAt), err)
+		return nil, fmt.Errorf("message with msgID: %s @ %s could not be published: %w", msg.MsgID, msg.Subject, err)
+	}
+	return &PublishFuture{msg: msg, future: future, stats: &p.stats, sentAt: sentAt, streamPrefix: p.conn.streamPrefix}, nil
+}
+
+// PublishAsyncComplete returns a channel, which is closed when all messages published with PublishAsync
+// are acknowledged. The acknowledgments are tracked per Connection, not per Publisher.
+func (p *Publisher) PublishAsyncComplete() <-chan struct{} {
+	return p.conn.nats.PublishAsyncComplete()
+}
+
+// Pending returns the number of messages published with PublishAsync, which are not acknowledged yet.
+// Like PublishAsyncComplete, the acknowledgments are tracked per Connection.
+func (p *Publisher) Pending() int {
+	return p.conn.nats.PublishAsyncPending()
+}
+
+// Flush waits until all messages published with PublishAsync are acknowledged, or ctx is done.
+// Call it on graceful shutdown before the Connection is closed, so no acknowledgment is lost.
+func (p *Publisher) Flush(ctx context.Context) error {
+	select {
+	case <-p.conn.nats.PublishAsyncComplete():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d messages are not acknowledged: %w", p.Pending(), ctx.Err())
+	}
+}
+
+// BatchMode defines how PublishBatch handles invalid messages.
+type BatchMode int
+
+const (
+	// BatchBestEffort mode (default) publishes all valid messages of the batch, invalid messages are skipped
+	// and reported in their BatchResult.
+	BatchBestEffort BatchMode = iota
+
+	// BatchAllOrNothing mode validates all messages before publishing, and publishes none if any of them is invalid.
+	// Since JetStream has no transactions, messages already stored are not rolled back if publishing of a
+	// valid message fails.
+	BatchAllOrNothing
+)
+
+// BatchResult is the result of publishing a single message of a batch.
+type BatchResult struct {
+	Msg    *Msg
+	PubAck PubAck
+	Err    error
+}
+
+// PublishBatch publishes all messages pipelined, without waiting for the acknowledgment of each message before
+// publishing the next one. The results are returned in the order of msgs. The returned error joins the errors
+// of all failed messages. Like PublishAsync, it does not split data exceeding the max payload into chunks.
+func (p *Publisher) PublishBatch(msgs []*Msg, mode BatchMode) ([]BatchResult, error) {
+	results := make([]BatchResult, len(msgs))
+	for i, msg := range msgs {
+		results[i].Msg = msg
+		results[i].Err = p.validate(msg)
+	}
+
+	if mode == BatchAllOrNothing {
+		var errs []error
+		for _, result := range results {
+			if result.Err != nil {
+				errs = append(errs, result.Err)
+			}
+		}
+		if len(errs) > 0 {
+			return results, fmt.Errorf("batch was not published: %w", errors.Join(errs...))
+		}
+	}
+
+	futures := make([]*PublishFuture, len(msgs))
+	for i, msg := range msgs {
+		if results[i].Err != nil {
+			continue
+		}
+		futures[i], results[i].Err = p.PublishAsync(msg)
+	}
+
+	var errs []error
+	for i, future := range futures {
+		if future != nil {
+			results[i].PubAck, results[i].Err = future.Wait(context.Background())
+		}
+		if results[i].Err != nil {
+			errs = append(errs, results[i].Err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// validate validates the subject of msg and calls the validator of the Connection.
+func (p *Publisher) validate(msg *Msg) error {
+	if err := validateSubject(msg.Subject, p.streamName); err != nil {
+		return err
+	}
+	if p.conn.publishValidator != nil {
+		if err := p.conn.publishValidator(msg); err != nil {
+			return fmt.Errorf("message with msgID: %s @ %s is invalid: %w", msg.MsgID, msg.Subject, err)
+		}
 	}
 	return nil
 }
@@ -75,3 +428,31 @@ func validateStreamName(streamName string) error {
 	}
 	return nil
 }
+
+// PublishToAll publishes a copy of msg to each of the given subjects, e.g. per-tenant subjects. All subjects are
+// validated before publishing, so nothing is published if any of them is invalid. The copies are published
+// pipelined like PublishBatch in BatchAllOrNothing mode; the results are returned in the order of subjects.
+// Since a stream deduplicates by MsgID across subjects, the MsgID of each copy is suffixed with its subject.
+func (p *Publisher) PublishToAll(subjects []string, msg *Msg) ([]BatchResult, error) {
+	p.ensureMsgID(msg)
+
+	msgs := make([]*Msg, len(subjects))
+	for i, subject := range subjects {
+		msgs[i] = &Msg{
+			Subject: subject,
+			Reply:   msg.Reply,
+			Data:    msg.Data,
+			Header:  msg.Header.clone(),
+			Expect:  msg.Expect,
+		}
+		if msg.MsgID != "" {
+			msgs[i].MsgID = msg.MsgID + "@" + subject
+		}
+	}
+
+	results, err := p.PublishBatch(msgs, BatchAllOrNothing)
+	if err != nil {
+		return results, fmt.Errorf("message with msgID: %s could not be published to all subjects: %w", msg.MsgID, err)
+	}
+	return results, nil
+}
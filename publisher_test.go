@@ -1,8 +1,12 @@
 package vnats
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"testing"
+	"time"
 )
 
 type testMessagePayload struct {
@@ -95,7 +99,7 @@ func Test_publisher_Publish(t *testing.T) {
 				logger:     slog.Default(),
 				streamName: tt.args.streamName,
 			}
-			err := pub.Publish(&Msg{
+			_, err := pub.Publish(&Msg{
 				Subject: tt.args.subject,
 				MsgID:   tt.args.msgID,
 				Data:    tt.args.data,
@@ -196,3 +200,445 @@ func Test_makePublisher(t *testing.T) {
 		})
 	}
 }
+
+func TestPublisher_publish_RateLimited(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 1, []byte("test message"), "msg-001", nil)
+	conn.applyOptions(WithPublishRateLimit(1, 1))
+
+	pub := &Publisher{
+		conn:       conn,
+		logger:     slog.Default(),
+		streamName: "MESSAGES",
+	}
+	msg := NewMsg("MESSAGES.Important", "msg-001", []byte("test message"))
+
+	if _, err := pub.Publish(msg); err != nil {
+		t.Fatalf("First message within burst should be published: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := pub.PublishWithContext(ctx, msg); err == nil {
+		t.Error("Second message should be rate limited, but no error was returned")
+	}
+}
+
+func TestWithPublishRateLimit_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond int
+		burst     int
+	}{
+		{name: "zero burst", perSecond: 10, burst: 0},
+		{name: "negative rate", perSecond: -1, burst: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &Connection{}
+			WithPublishRateLimit(tt.perSecond, tt.burst)(conn)
+			if len(conn.optionErrs) == 0 {
+				t.Error("WithPublishRateLimit() should record an option error")
+			}
+			if conn.publishLimiter != nil {
+				t.Error("WithPublishRateLimit() must not set a limiter")
+			}
+		})
+	}
+}
+
+func TestPublisher_PublishAsync(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 1, []byte("test message"), "msg-001", nil)
+	pub := &Publisher{
+		conn:       conn,
+		logger:     slog.Default(),
+		streamName: "MESSAGES",
+	}
+
+	future, err := pub.PublishAsync(NewMsg("MESSAGES.Important", "msg-001", []byte("test message")))
+	if err != nil {
+		t.Fatalf("Publisher.PublishAsync() error = %v", err)
+	}
+
+	ack, err := future.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("PublishFuture.Wait() error = %v", err)
+	}
+	if ack.Stream != "MESSAGES" || ack.Sequence != 2 {
+		t.Errorf("PublishFuture.Wait() got = %+v", ack)
+	}
+
+	select {
+	case <-pub.PublishAsyncComplete():
+	case <-time.After(time.Second):
+		t.Error("PublishAsyncComplete() was not closed")
+	}
+
+	if _, err := pub.PublishAsync(NewMsg("Important", "msg-001", []byte("test message"))); err == nil {
+		t.Error("Publisher.PublishAsync() with invalid subject should fail")
+	}
+}
+
+func TestPublisher_PublishAsync_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var futures []*PublishFuture
+	for i := 0; i < 10; i++ {
+		future, err := pub.PublishAsync(NewMsg(integrationTestStreamName+".async", fmt.Sprintf("msg-%d", i), []byte("async")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, future)
+	}
+
+	select {
+	case <-pub.PublishAsyncComplete():
+	case <-time.After(time.Second * 5):
+		t.Fatal("Messages were not acknowledged in time")
+	}
+
+	for i, future := range futures {
+		ack, err := future.Wait(context.Background())
+		if err != nil {
+			t.Errorf("Message %d could not be published: %v", i, err)
+		}
+		if ack.Sequence != uint64(i+1) {
+			t.Errorf("Message %d has sequence %d, want %d", i, ack.Sequence, i+1)
+		}
+	}
+}
+
+func TestPublisher_PublishAsync_Reply(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".reply"
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	makeRequest := func(msgID string) *Msg {
+		msg := NewMsg(subject, msgID, []byte("ping"))
+		msg.Reply = "replies.request"
+		return msg
+	}
+
+	future, err := pub.PublishAsync(makeRequest("async"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := future.Wait(context.Background()); err != nil {
+		t.Errorf("PublishAsync() with Reply failed: %v", err)
+	}
+	if _, err := pub.PublishBatch([]*Msg{makeRequest("batch-1"), makeRequest("batch-2")}, BatchBestEffort); err != nil {
+		t.Errorf("PublishBatch() with Reply failed: %v", err)
+	}
+
+	sub := createSubscriber(t, conn, "TestPublishAsyncReply", subject, MultipleSubscribersAllowed)
+	for range 3 {
+		msg, acker, err := sub.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Reply != "replies.request" {
+			t.Errorf("Reply of %s = %q, want replies.request", msg.MsgID, msg.Reply)
+		}
+		_ = acker.Ack()
+	}
+}
+
+func TestPublisher_PublishBatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          BatchMode
+		subjects      []string
+		wantPublished int
+		wantErr       bool
+	}{
+		{
+			name:          "Best effort, all valid",
+			mode:          BatchBestEffort,
+			subjects:      []string{"MESSAGES.a", "MESSAGES.b"},
+			wantPublished: 2,
+			wantErr:       false,
+		},
+		{
+			name:          "Best effort, one invalid",
+			mode:          BatchBestEffort,
+			subjects:      []string{"MESSAGES.a", "Invalid"},
+			wantPublished: 1,
+			wantErr:       true,
+		},
+		{
+			name:          "All or nothing, one invalid",
+			mode:          BatchAllOrNothing,
+			subjects:      []string{"MESSAGES.a", "Invalid"},
+			wantPublished: 0,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &Publisher{
+				conn:       makeTestConnection(t, "MESSAGES", 0, []byte("test message"), "msg-001", nil),
+				logger:     slog.Default(),
+				streamName: "MESSAGES",
+			}
+
+			var msgs []*Msg
+			for _, subject := range tt.subjects {
+				msgs = append(msgs, NewMsg(subject, "msg-001", []byte("test message")))
+			}
+
+			results, err := pub.PublishBatch(msgs, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Publisher.PublishBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			published := 0
+			for _, result := range results {
+				if result.Err == nil && result.PubAck.Sequence > 0 {
+					published++
+				}
+			}
+			if published != tt.wantPublished {
+				t.Errorf("Publisher.PublishBatch() published %d messages, want %d", published, tt.wantPublished)
+			}
+		})
+	}
+}
+
+func TestPublisher_Publish_Header(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".header"
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := NewMsg(subject, "msg-001", []byte("with header"))
+	msg.Header = Header{}
+	msg.Header.Set("Trace-Id", "abc")
+	if _, err := pub.Publish(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := createSubscriber(t, conn, "TestPublishHeader", subject, MultipleSubscribersAllowed)
+	received := make(chan Msg, 1)
+	if err := sub.Start(func(msg Msg) error {
+		received <- msg
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-received:
+		if got.Header.Get("Trace-Id") != "abc" || got.MsgID != "msg-001" {
+			t.Errorf("Header was not received, got %v", got.Header)
+		}
+	case <-time.After(time.Second * 5):
+		t.Error("Message was not received")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPublisher_Publish_Expectations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".expectations"
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noMessage := uint64(0)
+	first := NewMsg(subject, "msg-001", []byte("first"))
+	first.Expect.LastSubjectSequence = &noMessage
+	if _, err := pub.Publish(first); err != nil {
+		t.Fatalf("First message should be published: %v", err)
+	}
+
+	second := NewMsg(subject, "msg-002", []byte("second"))
+	second.Expect.LastSubjectSequence = &noMessage
+	if _, err := pub.Publish(second); !errors.Is(err, ErrExpectationNotMet) {
+		t.Errorf("expected ErrExpectationNotMet, got %v", err)
+	}
+
+	third := NewMsg(subject, "msg-003", []byte("third"))
+	third.Expect.LastMsgID = "msg-002"
+	if _, err := pub.Publish(third); !errors.Is(err, ErrExpectationNotMet) {
+		t.Errorf("expected ErrExpectationNotMet, got %v", err)
+	}
+}
+
+func TestPublisher_natsMsg_DefaultHeader(t *testing.T) {
+	pub := &Publisher{
+		conn:       makeTestConnection(t, "MESSAGES", 1, nil, "", nil),
+		streamName: "MESSAGES",
+		defaultHeader: Header{
+			"Service":        []string{"order-service"},
+			"Schema-Version": []string{"1"},
+		},
+	}
+
+	msg := NewMsg("MESSAGES.created", "msg-001", nil)
+	msg.Header = Header{"Schema-Version": []string{"2"}}
+	natsMsg, err := pub.natsMsg(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := natsMsg.Header.Get("Service"); got != "order-service" {
+		t.Errorf("Service header = %q, want default value", got)
+	}
+	if got := natsMsg.Header.Get("Schema-Version"); got != "2" {
+		t.Errorf("Schema-Version header = %q, want value of the message", got)
+	}
+	if msg.Header.Get("Service") != "" {
+		t.Error("natsMsg() must not modify the header of msg")
+	}
+}
+
+func TestPublisher_Publish_PubAck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".puback"
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgID := fmt.Sprintf("puback-%d", time.Now().UnixNano())
+	first, err := pub.Publish(NewMsg(subject, msgID, []byte("first")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Stream != integrationTestStreamName || first.Sequence == 0 || first.Duplicate {
+		t.Errorf("Publish() PubAck = %+v", first)
+	}
+
+	second, err := pub.Publish(NewMsg(subject, msgID, []byte("first")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.Duplicate || second.Sequence != first.Sequence {
+		t.Errorf("Publish() of duplicate PubAck = %+v, want duplicate of sequence %d", second, first.Sequence)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPublisher_PublishToAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		subjects      []string
+		msgID         string
+		wantMsgID     string
+		wantErr       bool
+		wantPublished int
+	}{
+		{name: "all subjects", subjects: []string{"MESSAGES.tenant-a", "MESSAGES.tenant-b"}, wantPublished: 2},
+		{name: "msgID is suffixed with subject", subjects: []string{"MESSAGES.tenant-a"}, msgID: "msg-001", wantMsgID: "msg-001@MESSAGES.tenant-a", wantPublished: 1},
+		{name: "invalid subject publishes nothing", subjects: []string{"MESSAGES.tenant-a", "OTHER.tenant-b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "MESSAGES", 0, []byte("announcement"), tt.wantMsgID, nil)
+			pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+
+			results, err := pub.PublishToAll(tt.subjects, NewMsg("", tt.msgID, []byte("announcement")))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PublishToAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(results) != len(tt.subjects) {
+				t.Fatalf("PublishToAll() returned %d results, want %d", len(results), len(tt.subjects))
+			}
+			for i, result := range results {
+				if result.Msg.Subject != tt.subjects[i] {
+					t.Errorf("result %d has subject %s, want %s", i, result.Msg.Subject, tt.subjects[i])
+				}
+			}
+			if got := int(conn.nats.(*testBridge).sequenceNumber); got != tt.wantPublished {
+				t.Errorf("PublishToAll() published %d messages, want %d", got, tt.wantPublished)
+			}
+		})
+	}
+}
+
+func TestPublisher_Flush(t *testing.T) {
+	tests := []struct {
+		name         string
+		asyncPending int
+		wantErr      bool
+	}{
+		{name: "all acknowledged", asyncPending: 0},
+		{name: "pending acknowledgments", asyncPending: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+			conn.nats.(*testBridge).asyncPending = tt.asyncPending
+			pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+
+			if got := pub.Pending(); got != tt.asyncPending {
+				t.Errorf("Pending() = %d, want %d", got, tt.asyncPending)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+			defer cancel()
+			if err := pub.Flush(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("Flush() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublisher_Publish_Validator(t *testing.T) {
+	errEmptyData := errors.New("data must not be empty")
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "valid message", data: []byte("valid")},
+		{name: "invalid message is rejected", data: nil, wantErr: errEmptyData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "MESSAGES", 0, tt.data, "msg-001", nil)
+			WithPublishValidator(func(msg *Msg) error {
+				if len(msg.Data) == 0 {
+					return errEmptyData
+				}
+				return nil
+			})(conn)
+			pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+
+			msg := NewMsg("MESSAGES.validated", "msg-001", tt.data)
+			if _, err := pub.Publish(msg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Publish() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := pub.PublishAsync(msg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("PublishAsync() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package vnats
+
+import (
+	"log/slog"
+	"runtime/debug"
+)
+
+// recoverPanic recovers a panic of the calling go-routine, logs it and calls the OnPanic hook, so a single panic
+// can't crash the whole process. It must be called deferred.
+func recoverPanic(logger *slog.Logger, hooks ConnectionHooks, source string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	reportPanic(logger, hooks, source, recovered)
+}
+
+// reportPanic logs a recovered panic with attrs and calls the OnPanic hook.
+func reportPanic(logger *slog.Logger, hooks ConnectionHooks, source string, recovered any, attrs ...any) {
+	logger.Error("Recovered from panic", append([]any{
+		slog.String("source", source),
+		slog.Any("panic", recovered),
+		slog.String("stack", string(debug.Stack()))}, attrs...)...)
+
+	if hooks.OnPanic != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("Recovered from panic in OnPanic hook", slog.Any("panic", r))
+			}
+		}()
+		hooks.OnPanic(source, recovered)
+	}
+}
@@ -0,0 +1,89 @@
+package vnats
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func Test_recoverPanic(t *testing.T) {
+	var gotSource string
+	var gotRecovered any
+	hooks := ConnectionHooks{
+		OnPanic: func(source string, recovered any) {
+			gotSource = source
+			gotRecovered = recovered
+		},
+	}
+
+	func() {
+		defer recoverPanic(slog.Default(), hooks, "test")
+		panic("handler crashed")
+	}()
+
+	if gotSource != "test" || gotRecovered != "handler crashed" {
+		t.Errorf("OnPanic got source=%q recovered=%v", gotSource, gotRecovered)
+	}
+}
+
+func Test_recoverPanic_PanickingHook(t *testing.T) {
+	hooks := ConnectionHooks{
+		OnPanic: func(_ string, _ any) {
+			panic("hook crashed")
+		},
+	}
+
+	func() {
+		defer recoverPanic(slog.Default(), hooks, "test")
+		panic("handler crashed")
+	}()
+}
+
+func TestSubscriber_callHandler_Panic(t *testing.T) {
+	var panics int
+	conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+	conn.hooks.OnPanic = func(_ string, _ any) { panics++ }
+	sub := &Subscriber{
+		conn:    conn,
+		logger:  slog.Default(),
+		args:    SubscriberArgs{MaxPanics: 2},
+		handler: func(_ context.Context, _ Msg, _ Acker) { panic("handler crashed") },
+	}
+	msg := Msg{MsgID: "msg-001", Metadata: Metadata{StreamSequence: 42, NumDelivered: 1}}
+
+	first := &recordingAcker{}
+	sub.callHandler(context.Background(), msg, first)
+	if first.nakDelay != defaultNakDelay || first.terminated {
+		t.Errorf("first panic: message was not NAKed, acker %+v", first)
+	}
+
+	second := &recordingAcker{}
+	sub.callHandler(context.Background(), msg, second)
+	if !second.terminated {
+		t.Errorf("second panic: message was not terminated after MaxPanics, acker %+v", second)
+	}
+	if panics != 2 {
+		t.Errorf("OnPanic was called %d times, want 2", panics)
+	}
+	if len(sub.panics) != 0 {
+		t.Errorf("panics of terminated message were not reset: %v", sub.panics)
+	}
+}
+
+func TestSubscriber_callHandler_ResetPanics(t *testing.T) {
+	sub := &Subscriber{
+		conn:    makeTestConnection(t, "MESSAGES", 0, nil, "", nil),
+		logger:  slog.Default(),
+		handler: func(_ context.Context, _ Msg, acker Acker) { _ = acker.Ack() },
+		panics:  map[uint64]int{42: 1},
+	}
+
+	acker := &recordingAcker{}
+	sub.callHandler(context.Background(), Msg{Metadata: Metadata{StreamSequence: 42}}, acker)
+	if !acker.acked || acker.nakDelay != 0 {
+		t.Errorf("message was not acknowledged, acker %+v", acker)
+	}
+	if len(sub.panics) != 0 {
+		t.Errorf("panics of handled message were not reset: %v", sub.panics)
+	}
+}
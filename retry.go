@@ -0,0 +1,88 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// RetryPolicy defines how often and when publishing a message is retried after a transient error, e.g. during
+// a leader election of the stream. Only messages with a MsgID are retried: the server may have stored a message,
+// whose acknowledgment timed out, and only the MsgID lets it deduplicate the retry. Set MsgIDs or use
+// WithMsgIDGenerator to retry all messages.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts to publish a message, including the first one.
+	// Zero or one disables retrying.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry. Default is 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff bounds the time to wait between two attempts. Default is 5s.
+	MaxBackoff time.Duration
+
+	// Multiplier is applied to the backoff after each retry. Default is 2.
+	Multiplier float64
+
+	// IsRetryable decides whether publishing is retried after err. Default is IsRetryablePublishErr.
+	IsRetryable func(err error) bool
+}
+
+const (
+	defaultRetryInitialBackoff = time.Millisecond * 100
+	defaultRetryMaxBackoff     = time.Second * 5
+	defaultRetryMultiplier     = 2.0
+)
+
+// IsRetryablePublishErr returns true for transient errors, like missing responders or timeouts while the
+// stream elects a new leader or the Connection reconnects.
+func IsRetryablePublishErr(err error) bool {
+	return errors.Is(err, nats.ErrNoResponders) ||
+		errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, nats.ErrNoStreamResponse) ||
+		errors.Is(err, nats.ErrConnectionReconnecting) ||
+		errors.Is(err, nats.ErrDisconnected)
+}
+
+// isRetryable returns whether err is transient according to IsRetryable.
+func (r RetryPolicy) isRetryable(err error) bool {
+	if r.IsRetryable == nil {
+		return IsRetryablePublishErr(err)
+	}
+	return r.IsRetryable(err)
+}
+
+// retry calls fn until it succeeds, returns a non-retryable error, MaxAttempts is reached or ctx is done.
+func (r RetryPolicy) retry(ctx context.Context, fn func() error) error {
+	backoff := r.InitialBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryInitialBackoff
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	multiplier := r.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultRetryMultiplier
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.MaxAttempts || !r.isRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff = min(time.Duration(float64(backoff)*multiplier), maxBackoff)
+	}
+}
@@ -0,0 +1,85 @@
+package vnats
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPublisher_Publish_Retry(t *testing.T) {
+	errPermanent := errors.New("permanent error")
+
+	tests := []struct {
+		name        string
+		retry       RetryPolicy
+		publishErrs []error
+		noMsgID     bool
+		wantErr     bool
+	}{
+		{
+			name:        "No retry policy, transient error",
+			retry:       RetryPolicy{},
+			publishErrs: []error{nats.ErrNoResponders},
+			wantErr:     true,
+		},
+		{
+			name:        "Transient errors are retried",
+			retry:       RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			publishErrs: []error{nats.ErrNoResponders, nats.ErrTimeout},
+			wantErr:     false,
+		},
+		{
+			name:        "Max attempts exceeded",
+			retry:       RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			publishErrs: []error{nats.ErrNoResponders, nats.ErrTimeout},
+			wantErr:     true,
+		},
+		{
+			name:        "Permanent error is not retried",
+			retry:       RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			publishErrs: []error{errPermanent},
+			wantErr:     true,
+		},
+		{
+			name: "Custom retryable classification",
+			retry: RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				IsRetryable:    func(err error) bool { return errors.Is(err, errPermanent) },
+			},
+			publishErrs: []error{errPermanent},
+			wantErr:     false,
+		},
+		{
+			name:        "Message without MsgID is not retried",
+			retry:       RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			publishErrs: []error{nats.ErrTimeout},
+			noMsgID:     true,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgID := "msg-001"
+			if tt.noMsgID {
+				msgID = ""
+			}
+			conn := makeTestConnection(t, "MESSAGES", 1, []byte("test message"), msgID, nil)
+			conn.nats.(*testBridge).publishErrs = tt.publishErrs
+
+			pub := &Publisher{
+				conn:       conn,
+				logger:     slog.Default(),
+				streamName: "MESSAGES",
+				retry:      tt.retry,
+			}
+			_, err := pub.Publish(NewMsg("MESSAGES.Important", msgID, []byte("test message")))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Publisher.Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
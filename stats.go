@@ -0,0 +1,212 @@
+package vnats
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the buckets of a LatencyHistogram.
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	time.Millisecond * 5,
+	time.Millisecond * 10,
+	time.Millisecond * 25,
+	time.Millisecond * 50,
+	time.Millisecond * 100,
+	time.Millisecond * 250,
+	time.Millisecond * 500,
+	time.Second,
+	time.Millisecond * 2500,
+	time.Second * 5,
+}
+
+// LatencyHistogram is a snapshot of observed latencies, which can be exported to a metrics system.
+type LatencyHistogram struct {
+	// Buckets are the upper bounds of the buckets in ascending order.
+	Buckets []time.Duration
+
+	// Counts contains the number of observations per bucket, which are greater than the upper bound of the
+	// previous bucket. The last count contains the observations greater than the last bucket.
+	Counts []uint64
+
+	// Count is the total number of observations.
+	Count uint64
+
+	// Sum is the sum of all observations.
+	Sum time.Duration
+}
+
+type latencyHistogram struct {
+	counts [12]atomic.Uint64 // len(latencyBuckets)+1
+	sum    atomic.Int64
+}
+
+func (h *latencyHistogram) observe(latency time.Duration) {
+	i := 0
+	for i < len(latencyBuckets) && latency > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(latency))
+}
+
+func (h *latencyHistogram) snapshot() LatencyHistogram {
+	snapshot := LatencyHistogram{
+		Buckets: latencyBuckets,
+		Counts:  make([]uint64, len(h.counts)),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+	for i := range h.counts {
+		snapshot.Counts[i] = h.counts[i].Load()
+		snapshot.Count += snapshot.Counts[i]
+	}
+	return snapshot
+}
+
+// PublisherStats contains the counters of a Publisher since its creation.
+type PublisherStats struct {
+	// Published is the number of messages acknowledged by the server, including duplicates.
+	Published uint64
+
+	// Failed is the number of messages, which could not be published.
+	Failed uint64
+
+	// Duplicates is the number of messages, which were not stored because of their MsgID.
+	Duplicates uint64
+
+	// Bytes is the size of the data of all published messages.
+	Bytes uint64
+
+	// AckLatency contains the time from sending a message until its acknowledgment, including retries.
+	AckLatency LatencyHistogram
+}
+
+type publisherStats struct {
+	published  atomic.Uint64
+	failed     atomic.Uint64
+	duplicates atomic.Uint64
+	bytes      atomic.Uint64
+	ackLatency latencyHistogram
+
+	stream  string
+	metrics Metrics // optional, see WithMetrics
+}
+
+// record counts the result of publishing msg, which took latency.
+func (s *publisherStats) record(msg *Msg, ack PubAck, latency time.Duration, err error) {
+	if err != nil {
+		s.failed.Add(1)
+		s.observe(PublishFailed, latency)
+		return
+	}
+	s.published.Add(1)
+	s.bytes.Add(uint64(len(msg.Data)))
+	result := PublishSucceeded
+	if ack.Duplicate {
+		s.duplicates.Add(1)
+		result = PublishDuplicate
+	}
+	s.observe(result, latency)
+}
+
+// observe passes the result of publishing a message to the Metrics of the Connection, if set.
+func (s *publisherStats) observe(result PublishResult, latency time.Duration) {
+	if s.metrics != nil {
+		s.metrics.ObservePublish(s.stream, result, latency)
+	}
+}
+
+// Stats returns the counters of the Publisher. Messages published with Publish, PublishWithContext and
+// PublishAsync are counted; asynchronously published messages are counted when their PublishFuture resolves.
+func (p *Publisher) Stats() PublisherStats {
+	return PublisherStats{
+		Published:  p.stats.published.Load(),
+		Failed:     p.stats.failed.Load(),
+		Duplicates: p.stats.duplicates.Load(),
+		Bytes:      p.stats.bytes.Load(),
+		AckLatency: p.stats.ackLatency.snapshot(),
+	}
+}
+
+// SubscriberStats contains the counters of a Subscriber since its creation.
+type SubscriberStats struct {
+	// Delivered is the number of messages passed to the handler, including redeliveries.
+	Delivered uint64
+
+	// Acked is the number of acknowledged messages.
+	Acked uint64
+
+	// Naked is the number of NAKed messages.
+	Naked uint64
+
+	// Termed is the number of terminated messages.
+	Termed uint64
+
+	// InFlight is the number of handlers currently running.
+	InFlight int64
+
+	// LastFetch is the time the Subscriber finished the last fetch of messages.
+	LastFetch time.Time
+
+	// Pending is the number of messages of the consumer, which were not delivered yet, as of the last delivered
+	// message. It is the lag of the consumer.
+	Pending uint64
+}
+
+type subscriberStats struct {
+	delivered atomic.Uint64
+	acked     atomic.Uint64
+	naked     atomic.Uint64
+	termed    atomic.Uint64
+	inFlight  atomic.Int64
+	pending   atomic.Uint64
+
+	stream   string
+	consumer string
+	metrics  Metrics // optional, see WithMetrics
+}
+
+// deliver counts the delivery of msg.
+func (s *subscriberStats) deliver(msg Msg) {
+	s.delivered.Add(1)
+	s.pending.Store(msg.Metadata.NumPending)
+	if s.metrics != nil {
+		s.metrics.ObserveDelivery(s.stream, s.consumer, msg.Metadata.NumDelivered > 1, msg.Metadata.NumPending)
+	}
+}
+
+// ack counts an acknowledgment of a message with result, latency after its delivery.
+func (s *subscriberStats) ack(result AckResult, latency time.Duration) {
+	switch result {
+	case Acked:
+		s.acked.Add(1)
+	case Naked:
+		s.naked.Add(1)
+	case Termed:
+		s.termed.Add(1)
+	}
+	if s.metrics != nil {
+		s.metrics.ObserveAck(s.stream, s.consumer, result, latency)
+	}
+}
+
+// addInFlight counts the start (delta 1) or the return (delta -1) of a handler.
+func (s *subscriberStats) addInFlight(delta int) {
+	s.inFlight.Add(int64(delta))
+	if s.metrics != nil {
+		s.metrics.AddInFlight(s.stream, s.consumer, delta)
+	}
+}
+
+// Stats returns the counters of the Subscriber.
+func (s *Subscriber) Stats() SubscriberStats {
+	return SubscriberStats{
+		Delivered: s.stats.delivered.Load(),
+		Acked:     s.stats.acked.Load(),
+		Naked:     s.stats.naked.Load(),
+		Termed:    s.stats.termed.Load(),
+		InFlight:  s.stats.inFlight.Load(),
+		LastFetch: time.Unix(0, s.lastFetch.Load()),
+		Pending:   s.stats.pending.Load(),
+	}
+}
@@ -0,0 +1,215 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+)
+
+func Test_latencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	h.observe(time.Microsecond * 500)
+	h.observe(time.Millisecond * 7)
+	h.observe(time.Minute)
+
+	snapshot := h.snapshot()
+	if snapshot.Count != 3 || snapshot.Sum != time.Microsecond*500+time.Millisecond*7+time.Minute {
+		t.Errorf("snapshot() Count = %d, Sum = %s", snapshot.Count, snapshot.Sum)
+	}
+	if len(snapshot.Counts) != len(snapshot.Buckets)+1 {
+		t.Fatalf("snapshot() has %d counts for %d buckets", len(snapshot.Counts), len(snapshot.Buckets))
+	}
+	for i, want := range map[int]uint64{0: 1, 2: 1, len(snapshot.Buckets): 1} {
+		if snapshot.Counts[i] != want {
+			t.Errorf("bucket %d count = %d, want %d", i, snapshot.Counts[i], want)
+		}
+	}
+}
+
+func TestPublisher_Stats(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 0, []byte("counted"), "msg-001", nil)
+	conn.nats.(*testBridge).publishErrs = []error{errors.New("stream not found")}
+	pub := &Publisher{conn: conn, streamName: "MESSAGES"}
+
+	msg := NewMsg("MESSAGES.counted", "msg-001", []byte("counted"))
+	if _, err := pub.Publish(msg); err == nil {
+		t.Fatal("first Publish() should fail")
+	}
+	if _, err := pub.Publish(msg); err != nil {
+		t.Fatal(err)
+	}
+	future, err := pub.PublishAsync(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := future.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := pub.Stats()
+	if stats.Published != 2 || stats.Failed != 1 || stats.Bytes != 14 || stats.AckLatency.Count != 2 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestPublisher_Stats_Duplicates(t *testing.T) {
+	bridge := &recordingBridge{testBridge: testBridge{TB: t, streamName: "MESSAGES"}}
+	pub := &Publisher{conn: &Connection{nats: duplicateBridge{bridge}}, streamName: "MESSAGES"}
+
+	if _, err := pub.Publish(NewMsg("MESSAGES.counted", "msg-001", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pub.Stats(); stats.Duplicates != 1 || stats.Published != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestPublisher_Metrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	conn := makeTestConnection(t, "MESSAGES", 0, []byte("counted"), "msg-001", nil)
+	conn.nats.(*testBridge).publishErrs = []error{errors.New("stream not found")}
+	conn.metrics = metrics
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: "MESSAGES"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := NewMsg("MESSAGES.counted", "msg-001", []byte("counted"))
+	if _, err := pub.Publish(msg); err == nil {
+		t.Fatal("first Publish() should fail")
+	}
+	if _, err := pub.Publish(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"MESSAGES failed", "MESSAGES succeeded"}
+	if diff := cmp.Diff(want, metrics.events()); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// recordingMetrics records the calls of Metrics as events.
+type recordingMetrics struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (m *recordingMetrics) record(event string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.log = append(m.log, event)
+}
+
+func (m *recordingMetrics) events() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.log)
+}
+
+func (m *recordingMetrics) ObservePublish(stream string, result PublishResult, _ time.Duration) {
+	m.record(fmt.Sprintf("%s %s", stream, result))
+}
+
+func (m *recordingMetrics) ObserveDelivery(stream, consumer string, redelivered bool, pending uint64) {
+	m.record(fmt.Sprintf("%s/%s delivered redelivered=%t pending=%d", stream, consumer, redelivered, pending))
+}
+
+func (m *recordingMetrics) ObserveAck(stream, consumer string, result AckResult, _ time.Duration) {
+	m.record(fmt.Sprintf("%s/%s %s", stream, consumer, result))
+}
+
+func (m *recordingMetrics) AddInFlight(stream, consumer string, delta int) {
+	m.record(fmt.Sprintf("%s/%s in flight %+d", stream, consumer, delta))
+}
+
+func (m *recordingMetrics) ObserveReconnect() {
+	m.record("reconnect")
+}
+
+// duplicateBridge acknowledges every message as duplicate.
+type duplicateBridge struct {
+	*recordingBridge
+}
+
+func (b duplicateBridge) PublishMsg(ctx context.Context, msg *nats.Msg, msgID string) (*nats.PubAck, error) {
+	ack, err := b.recordingBridge.PublishMsg(ctx, msg, msgID)
+	if ack != nil {
+		ack.Duplicate = true
+	}
+	return ack, err
+}
+
+func TestSubscriber_Stats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".stats"
+	conn := makeIntegrationTestConn(t)
+	publishStringMessages(t, conn, subject, []string{"nak", "terminate", "ack"})
+	metrics := &recordingMetrics{}
+	conn.metrics = metrics
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: "TestSubscriberStats",
+		Subject:      subject,
+		Mode:         SingleSubscriberStrictMessageOrder,
+		Backoff:      []time.Duration{time.Millisecond * 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handled := make(chan struct{}, 4)
+	if err := sub.StartWithAcker(func(_ context.Context, msg Msg, acker Acker) {
+		defer func() { handled <- struct{}{} }()
+		switch {
+		case string(msg.Data) == "terminate":
+			_ = acker.Term()
+		case string(msg.Data) == "nak" && msg.Metadata.NumDelivered == 1:
+			_ = acker.Nak(time.Millisecond * 100)
+		default:
+			_ = acker.Ack()
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 4 {
+		select {
+		case <-handled:
+		case <-time.After(time.Second * 5):
+			t.Fatal("not all messages were handled")
+		}
+	}
+	time.Sleep(time.Millisecond * 50) // the counters are incremented after the handler returned
+
+	stats := sub.Stats()
+	if time.Since(stats.LastFetch) > time.Minute {
+		t.Errorf("Stats() LastFetch = %s", stats.LastFetch)
+	}
+	stats.LastFetch = time.Time{}
+	want := SubscriberStats{Delivered: 4, Acked: 2, Naked: 1, Termed: 1, InFlight: 0, Pending: 0}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	const consumer = integrationTestStreamName + "/TestSubscriberStats"
+	wantEvents := []string{
+		consumer + " delivered redelivered=false pending=2", consumer + " in flight +1", consumer + " nak", consumer + " in flight -1",
+		consumer + " delivered redelivered=true pending=2", consumer + " in flight +1", consumer + " ack", consumer + " in flight -1",
+		consumer + " delivered redelivered=false pending=1", consumer + " in flight +1", consumer + " term", consumer + " in flight -1",
+		consumer + " delivered redelivered=false pending=0", consumer + " in flight +1", consumer + " ack", consumer + " in flight -1",
+	}
+	if diff := cmp.Diff(wantEvents, metrics.events()); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
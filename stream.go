@@ -0,0 +1,688 @@
+package vnats
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrStreamNotFound is returned by NewPublisher and NewSubscriber with WithStrictTopology, if the stream does
+// not exist.
+var ErrStreamNotFound = errors.New("stream not found")
+
+// RetentionPolicy defines when messages of a stream are removed.
+type RetentionPolicy int
+
+const (
+	// LimitsRetention (default) keeps messages until MaxAge, MaxBytes or MaxMsgs of the stream is exceeded.
+	LimitsRetention RetentionPolicy = iota
+
+	// InterestRetention keeps messages until all consumers of the stream acknowledged them.
+	InterestRetention
+
+	// WorkQueueRetention removes messages as soon as one consumer acknowledged them.
+	WorkQueueRetention
+)
+
+// StorageType defines where messages of a stream are stored.
+type StorageType int
+
+const (
+	// FileStorage (default) stores messages on disk.
+	FileStorage StorageType = iota
+
+	// MemoryStorage stores messages in memory, so they are lost when the server restarts.
+	MemoryStorage
+)
+
+// DiscardPolicy defines which messages are discarded, if a limit of the stream is exceeded.
+type DiscardPolicy int
+
+const (
+	// DiscardOld (default) removes the oldest messages to make room for new ones.
+	DiscardOld DiscardPolicy = iota
+
+	// DiscardNew rejects new messages, so publishing fails.
+	DiscardNew
+)
+
+const (
+	// HeaderRollup is the header key of rollup messages, which replace the stored messages of a stream with
+	// AllowRollup. Its value is RollupSubject or RollupAll.
+	HeaderRollup = nats.MsgRollup
+
+	// RollupSubject removes all other messages of the subject of the rollup message, e.g. to store a snapshot
+	// of the state of an entity.
+	RollupSubject = nats.MsgRollupSubject
+
+	// RollupAll removes all other messages of the stream.
+	RollupAll = nats.MsgRollupAll
+)
+
+// StreamConfig contains the configuration of a stream. The zero value of a field means its default.
+type StreamConfig struct {
+	// Name is the name of the stream like "PRODUCTS" or "ORDERS".
+	Name string
+
+	// Subjects of the stream. Default is all subjects starting with the stream name, like "ORDERS.>".
+	Subjects []string
+
+	// Retention defines when messages are removed. Default is LimitsRetention.
+	Retention RetentionPolicy
+
+	// MaxAge is the maximum age of messages. Default is 30 days.
+	MaxAge time.Duration
+
+	// MaxBytes is the maximum size of all messages of the stream. Default is unlimited.
+	MaxBytes int64
+
+	// MaxMsgs is the maximum number of messages of the stream. Default is unlimited.
+	MaxMsgs int64
+
+	// MaxMsgsPerSubject is the maximum number of messages per subject, e.g. 1 to keep only the latest state of each
+	// entity with subjects like "PRODUCTS.<id>". Older messages of a subject are removed. Default is unlimited.
+	MaxMsgsPerSubject int64
+
+	// Storage defines where messages are stored. Default is FileStorage.
+	Storage StorageType
+
+	// Replicas is the number of copies of the stream in a cluster. Default is the number of servers passed to
+	// Connect.
+	Replicas int
+
+	// Placement selects the servers storing the stream, e.g. servers tagged with "ssd" for production streams.
+	// Default is any server of the cluster the Connection is connected to.
+	Placement *Placement
+
+	// Discard defines which messages are discarded, if MaxBytes or MaxMsgs is exceeded. Default is DiscardOld.
+	Discard DiscardPolicy
+
+	// DuplicateWindow is the time, in which messages with the same MsgID are stored only once. Publishing such a
+	// message again returns a PubAck marked as Duplicate. It should cover the retry horizon of the publishers and
+	// must not exceed MaxAge. Default is 30 minutes.
+	DuplicateWindow time.Duration
+
+	// Mirror makes the stream a read-only copy of another stream. A mirror must not have Subjects or Sources.
+	Mirror *StreamSource
+
+	// Sources are streams, whose messages are copied into the stream, e.g. to aggregate them.
+	Sources []StreamSource
+
+	// SubjectTransform changes the subjects of ingested messages before they are stored.
+	// Requires NATS server 2.10 or newer.
+	SubjectTransform *SubjectTransform
+
+	// AllowDirect allows GetMsg and GetLastMsgForSubject to read messages from all replicas of the stream, instead
+	// of only from its leader.
+	AllowDirect bool
+
+	// RePublish publishes stored messages again to core NATS subjects, e.g. for lightweight listeners without
+	// a consumer.
+	RePublish *RePublish
+
+	// AllowRollup allows messages with the HeaderRollup header, which replace the stored messages of their subject
+	// or the whole stream, e.g. for streams storing the latest state of entities. It must not be combined with
+	// DenyPurge, since a rollup purges messages.
+	AllowRollup bool
+
+	// DenyDelete forbids deleting single messages with DeleteMsg, e.g. for tamper-resistant audit streams.
+	// It can't be disabled after the stream is created.
+	DenyDelete bool
+
+	// DenyPurge forbids purging messages with PurgeStream and PurgeSubject. Messages are still removed by the
+	// limits of the stream. It can't be disabled after the stream is created.
+	DenyPurge bool
+
+	// Metadata are application-defined key/values of the stream, like the owner team or the schema version,
+	// which are returned by StreamInfo. Keys starting with "_nats." are reserved for the server.
+	// Requires NATS server 2.10 or newer.
+	Metadata map[string]string
+}
+
+// SubjectTransform maps subjects matching Source to Destination, e.g. "ORDERS.*.created" to
+// "ORDERS.created.{{wildcard(1)}}".
+type SubjectTransform struct {
+	// Source is the subject to transform, which may contain wildcards. Default is all subjects.
+	Source string
+
+	// Destination is the new subject, which may reference wildcards of Source.
+	Destination string
+}
+
+// RePublish publishes stored messages with a subject matching Source to Destination with core NATS.
+type RePublish struct {
+	// Source is the subject of stored messages to republish, which may contain wildcards. Default is all subjects.
+	Source string
+
+	// Destination is the core NATS subject, which may reference wildcards of Source.
+	Destination string
+
+	// HeadersOnly republishes only the headers of messages without their data.
+	HeadersOnly bool
+}
+
+// Placement selects the servers of a cluster, which store a stream and its replicas.
+type Placement struct {
+	// Cluster is the name of the cluster. Default is the cluster the Connection is connected to.
+	Cluster string
+
+	// Tags, which all selected servers must have, like "ssd" or "az:eu-central-1a".
+	Tags []string
+}
+
+// StreamSource is a stream, whose messages are copied into a mirror or another stream.
+type StreamSource struct {
+	// Name is the name of the origin stream.
+	Name string
+
+	// FilterSubject copies only messages of this subject. Default is all messages.
+	FilterSubject string
+
+	// StartSequence is the stream sequence of the first copied message. Default is the first message.
+	StartSequence uint64
+
+	// StartTime copies only messages published after StartTime. Must not be combined with StartSequence.
+	StartTime time.Time
+
+	// Domain is the JetStream domain of the origin stream, e.g. to replicate it from another region.
+	// Default is the domain of the Connection.
+	Domain string
+}
+
+// EnsureStream creates the stream defined by config, if it does not exist yet. An existing stream is not changed.
+func (c *Connection) EnsureStream(config StreamConfig) error {
+	if err := validateStreamConfig(config); err != nil {
+		return err
+	}
+	return c.nats.EnsureStreamExists(c.natsStreamConfig(config))
+}
+
+func validateStreamConfig(config StreamConfig) error {
+	if err := validateStreamName(config.Name); err != nil {
+		return err
+	}
+	if config.Mirror != nil && (len(config.Subjects) > 0 || len(config.Sources) > 0) {
+		return fmt.Errorf("stream %s with Mirror must not have Subjects or Sources", config.Name)
+	}
+	if config.DuplicateWindow < 0 {
+		return fmt.Errorf("DuplicateWindow of stream %s must not be negative", config.Name)
+	}
+	if maxAge := cmp.Or(config.MaxAge, defaultMaxAge); config.DuplicateWindow > maxAge {
+		return fmt.Errorf("DuplicateWindow %s of stream %s must not exceed MaxAge %s", config.DuplicateWindow, config.Name, maxAge)
+	}
+	if config.AllowRollup && config.DenyPurge {
+		return fmt.Errorf("stream %s: AllowRollup and DenyPurge must not be combined", config.Name)
+	}
+	sources := config.Sources
+	if config.Mirror != nil {
+		sources = []StreamSource{*config.Mirror}
+	}
+	for _, source := range sources {
+		if err := validateStreamName(source.Name); err != nil {
+			return fmt.Errorf("source of stream %s: %w", config.Name, err)
+		}
+		if source.StartSequence > 0 && !source.StartTime.IsZero() {
+			return fmt.Errorf("source %s of stream %s: StartSequence and StartTime must not be combined", source.Name, config.Name)
+		}
+	}
+	return nil
+}
+
+// requireStream returns ErrStreamNotFound, if the stream streamName does not exist.
+func (c *Connection) requireStream(streamName string) error {
+	_, err := c.nats.StreamInfo(streamName)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, streamName)
+	}
+	return err
+}
+
+// ensureStream creates the stream streamName with the default configuration, if it does not exist yet.
+func (c *Connection) ensureStream(streamName string) error {
+	return c.EnsureStream(StreamConfig{Name: streamName})
+}
+
+// natsStreamConfig converts config to a nats.StreamConfig with the defaults applied.
+func (c *Connection) natsStreamConfig(config StreamConfig) *nats.StreamConfig {
+	natsConfig := &nats.StreamConfig{
+		Name:              config.Name,
+		Subjects:          config.Subjects,
+		Retention:         nats.LimitsPolicy,
+		MaxAge:            config.MaxAge,
+		MaxBytes:          config.MaxBytes,
+		MaxMsgs:           config.MaxMsgs,
+		MaxMsgsPerSubject: config.MaxMsgsPerSubject,
+		Storage:           defaultStorageType,
+		Replicas:          config.Replicas,
+		Discard:           nats.DiscardOld,
+		Duplicates:        config.DuplicateWindow,
+		AllowDirect:       config.AllowDirect,
+		AllowRollup:       config.AllowRollup,
+		DenyDelete:        config.DenyDelete,
+		DenyPurge:         config.DenyPurge,
+		Metadata:          config.Metadata,
+	}
+	if config.Mirror != nil {
+		natsConfig.Mirror = natsStreamSource(*config.Mirror)
+	} else if len(natsConfig.Subjects) == 0 {
+		natsConfig.Subjects = []string{config.Name + ".>"}
+	}
+	switch config.Retention {
+	case InterestRetention:
+		natsConfig.Retention = nats.InterestPolicy
+	case WorkQueueRetention:
+		natsConfig.Retention = nats.WorkQueuePolicy
+	}
+	if natsConfig.MaxAge == 0 {
+		natsConfig.MaxAge = defaultMaxAge
+	}
+	if natsConfig.MaxBytes == 0 {
+		natsConfig.MaxBytes = -1
+	}
+	if natsConfig.MaxMsgs == 0 {
+		natsConfig.MaxMsgs = -1
+	}
+	if natsConfig.MaxMsgsPerSubject == 0 {
+		natsConfig.MaxMsgsPerSubject = -1
+	}
+	if config.Storage == MemoryStorage {
+		natsConfig.Storage = nats.MemoryStorage
+	}
+	if natsConfig.Replicas == 0 {
+		natsConfig.Replicas = len(c.nats.Servers())
+	}
+	if config.Placement != nil {
+		natsConfig.Placement = &nats.Placement{Cluster: config.Placement.Cluster, Tags: config.Placement.Tags}
+	}
+	if config.Discard == DiscardNew {
+		natsConfig.Discard = nats.DiscardNew
+	}
+	if natsConfig.Duplicates == 0 {
+		natsConfig.Duplicates = defaultDuplicationWindow
+	}
+	if config.SubjectTransform != nil {
+		natsConfig.SubjectTransform = &nats.SubjectTransformConfig{
+			Source:      config.SubjectTransform.Source,
+			Destination: config.SubjectTransform.Destination,
+		}
+	}
+	if config.RePublish != nil {
+		natsConfig.RePublish = &nats.RePublish{
+			Source:      config.RePublish.Source,
+			Destination: config.RePublish.Destination,
+			HeadersOnly: config.RePublish.HeadersOnly,
+		}
+	}
+	for _, source := range config.Sources {
+		natsConfig.Sources = append(natsConfig.Sources, natsStreamSource(source))
+	}
+	return natsConfig
+}
+
+func natsStreamSource(source StreamSource) *nats.StreamSource {
+	natsSource := &nats.StreamSource{
+		Name:          source.Name,
+		OptStartSeq:   source.StartSequence,
+		FilterSubject: source.FilterSubject,
+		Domain:        source.Domain,
+	}
+	if !source.StartTime.IsZero() {
+		natsSource.OptStartTime = &source.StartTime
+	}
+	return natsSource
+}
+
+// UpdateStream updates the configuration of the existing stream config.Name. Defaults are applied to fields with
+// zero values like in EnsureStream, so config must contain the complete desired configuration.
+// Some settings, like Storage and Retention, can't be changed by the server.
+func (c *Connection) UpdateStream(config StreamConfig) error {
+	if err := validateStreamConfig(config); err != nil {
+		return err
+	}
+	if err := c.nats.UpdateStream(c.natsStreamConfig(config)); err != nil {
+		return fmt.Errorf("stream %s could not be updated: %w", config.Name, err)
+	}
+	return nil
+}
+
+// ReconcileMode defines whether ReconcileStream applies the drift of a stream configuration.
+type ReconcileMode int
+
+const (
+	// ReconcileReport only reports the drift of the stream configuration.
+	ReconcileReport ReconcileMode = iota
+
+	// ReconcileApply creates or updates the stream, if its configuration drifted.
+	ReconcileApply
+)
+
+// StreamDrift is a setting of a stream, whose actual value differs from the desired one.
+type StreamDrift struct {
+	// Field is the name of the field of StreamConfig, or "Name" if the stream does not exist.
+	Field string
+
+	Desired any
+	Actual  any
+}
+
+// ReconcileStream compares the desired config of a stream with its actual configuration on the server and returns
+// the drift, so stream settings can be managed as code. With ReconcileApply, a missing stream is created and a
+// drifted stream is updated; the returned drift is the one found before.
+func (c *Connection) ReconcileStream(config StreamConfig, mode ReconcileMode) ([]StreamDrift, error) {
+	if err := validateStreamConfig(config); err != nil {
+		return nil, err
+	}
+
+	desired := c.natsStreamConfig(config)
+	info, err := c.nats.StreamInfo(config.Name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		drift := []StreamDrift{{Field: "Name", Desired: config.Name, Actual: nil}}
+		if mode == ReconcileApply {
+			if err := c.nats.EnsureStreamExists(desired); err != nil {
+				return drift, err
+			}
+		}
+		return drift, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("stream %s could not be fetched: %w", config.Name, err)
+	}
+
+	drift := streamDrift(desired, &info.Config)
+	if len(drift) > 0 && mode == ReconcileApply {
+		if err := c.nats.UpdateStream(desired); err != nil {
+			return drift, fmt.Errorf("stream %s could not be updated: %w", config.Name, err)
+		}
+	}
+	return drift, nil
+}
+
+// streamDrift returns the fields of StreamConfig, which differ between desired and actual.
+func streamDrift(desired, actual *nats.StreamConfig) []StreamDrift {
+	var drift []StreamDrift
+	add := func(field string, desired, actual any, equal bool) {
+		if !equal {
+			drift = append(drift, StreamDrift{Field: field, Desired: desired, Actual: actual})
+		}
+	}
+	add("Subjects", desired.Subjects, actual.Subjects, slices.Equal(desired.Subjects, actual.Subjects))
+	add("Retention", desired.Retention, actual.Retention, desired.Retention == actual.Retention)
+	add("MaxAge", desired.MaxAge, actual.MaxAge, desired.MaxAge == actual.MaxAge)
+	add("MaxBytes", desired.MaxBytes, actual.MaxBytes, desired.MaxBytes == actual.MaxBytes)
+	add("MaxMsgs", desired.MaxMsgs, actual.MaxMsgs, desired.MaxMsgs == actual.MaxMsgs)
+	add("MaxMsgsPerSubject", desired.MaxMsgsPerSubject, actual.MaxMsgsPerSubject, desired.MaxMsgsPerSubject == actual.MaxMsgsPerSubject)
+	add("Storage", desired.Storage, actual.Storage, desired.Storage == actual.Storage)
+	add("Replicas", desired.Replicas, actual.Replicas, desired.Replicas == actual.Replicas)
+	add("Placement", desired.Placement, actual.Placement, placementEqual(desired.Placement, actual.Placement))
+	add("Discard", desired.Discard, actual.Discard, desired.Discard == actual.Discard)
+	add("DuplicateWindow", desired.Duplicates, actual.Duplicates, desired.Duplicates == actual.Duplicates)
+	add("Mirror", desired.Mirror, actual.Mirror, streamSourceEqual(desired.Mirror, actual.Mirror))
+	add("Sources", desired.Sources, actual.Sources, slices.EqualFunc(desired.Sources, actual.Sources, streamSourceEqual))
+	add("SubjectTransform", desired.SubjectTransform, actual.SubjectTransform, pointerEqual(desired.SubjectTransform, actual.SubjectTransform))
+	add("AllowDirect", desired.AllowDirect, actual.AllowDirect, desired.AllowDirect == actual.AllowDirect)
+	add("RePublish", desired.RePublish, actual.RePublish, pointerEqual(desired.RePublish, actual.RePublish))
+	add("AllowRollup", desired.AllowRollup, actual.AllowRollup, desired.AllowRollup == actual.AllowRollup)
+	add("DenyDelete", desired.DenyDelete, actual.DenyDelete, desired.DenyDelete == actual.DenyDelete)
+	add("DenyPurge", desired.DenyPurge, actual.DenyPurge, desired.DenyPurge == actual.DenyPurge)
+	add("Metadata", desired.Metadata, userMetadata(actual.Metadata), maps.Equal(desired.Metadata, userMetadata(actual.Metadata)))
+	return drift
+}
+
+// DeleteStream deletes the stream streamName with all its messages and consumers.
+// Subscribers of the stream must be stopped before.
+func (c *Connection) DeleteStream(streamName string) error {
+	if err := validateStreamName(streamName); err != nil {
+		return err
+	}
+	if err := c.nats.DeleteStream(streamName); err != nil {
+		return fmt.Errorf("stream %s could not be deleted: %w", streamName, err)
+	}
+	return nil
+}
+
+// PurgeOptions selects the messages removed by PurgeStream. The zero value removes all messages of the stream.
+type PurgeOptions struct {
+	// Subject removes only messages of this subject, which may contain wildcards like "ORDERS.*.created".
+	Subject string
+
+	// Sequence removes only messages with a stream sequence lower than Sequence.
+	// Must not be combined with Keep.
+	Sequence uint64
+
+	// Keep is the number of the newest messages, which are not removed.
+	// Must not be combined with Sequence.
+	Keep uint64
+}
+
+// PurgeStream removes the messages of the stream streamName selected by opts, while the stream and its consumers
+// are kept.
+func (c *Connection) PurgeStream(streamName string, opts PurgeOptions) error {
+	if err := validateStreamName(streamName); err != nil {
+		return err
+	}
+	if opts.Sequence > 0 && opts.Keep > 0 {
+		return fmt.Errorf("stream %s could not be purged: Sequence and Keep must not be combined", streamName)
+	}
+
+	var request *nats.StreamPurgeRequest
+	if opts != (PurgeOptions{}) {
+		request = &nats.StreamPurgeRequest{Subject: opts.Subject, Sequence: opts.Sequence, Keep: opts.Keep}
+	}
+	if err := c.nats.PurgeStream(streamName, request); err != nil {
+		return fmt.Errorf("stream %s could not be purged: %w", streamName, err)
+	}
+	return nil
+}
+
+// StreamInfo contains the state of a stream.
+type StreamInfo struct {
+	// Name is the name of the stream.
+	Name string
+
+	// Msgs is the number of messages stored in the stream.
+	Msgs uint64
+
+	// Bytes is the size of all messages stored in the stream.
+	Bytes uint64
+
+	// FirstSeq is the stream sequence of the oldest message.
+	FirstSeq uint64
+
+	// FirstTime is the time the oldest message was published.
+	FirstTime time.Time
+
+	// LastSeq is the stream sequence of the newest message.
+	LastSeq uint64
+
+	// LastTime is the time the newest message was published.
+	LastTime time.Time
+
+	// Consumers is the number of consumers of the stream.
+	Consumers int
+
+	// Metadata are the key/values set by StreamConfig.Metadata.
+	Metadata map[string]string
+}
+
+// StreamInfo returns the state of the stream streamName, e.g. to read its backlog.
+// The returned error wraps nats.ErrStreamNotFound, if the stream does not exist.
+func (c *Connection) StreamInfo(streamName string) (StreamInfo, error) {
+	if err := validateStreamName(streamName); err != nil {
+		return StreamInfo{}, err
+	}
+	info, err := c.nats.StreamInfo(streamName)
+	if err != nil {
+		return StreamInfo{}, fmt.Errorf("stream %s could not be fetched: %w", streamName, err)
+	}
+	return StreamInfo{
+		Name:      info.Config.Name,
+		Msgs:      info.State.Msgs,
+		Bytes:     info.State.Bytes,
+		FirstSeq:  info.State.FirstSeq,
+		FirstTime: info.State.FirstTime,
+		LastSeq:   info.State.LastSeq,
+		LastTime:  info.State.LastTime,
+		Consumers: info.State.Consumers,
+		Metadata:  userMetadata(info.Config.Metadata),
+	}, nil
+}
+
+// streamSourceEqual reports whether the sources desired and actual are equal. The Domain of desired is compared
+// with the API prefix of actual, since the server only knows the prefix.
+func streamSourceEqual(desired, actual *nats.StreamSource) bool {
+	if desired == nil || actual == nil {
+		return desired == actual
+	}
+	var desiredPrefix, actualPrefix string
+	if desired.Domain != "" {
+		desiredPrefix = fmt.Sprintf("$JS.%s.API", desired.Domain)
+	} else if desired.External != nil {
+		desiredPrefix = desired.External.APIPrefix
+	}
+	if actual.External != nil {
+		actualPrefix = actual.External.APIPrefix
+	}
+	var desiredStart, actualStart time.Time
+	if desired.OptStartTime != nil {
+		desiredStart = *desired.OptStartTime
+	}
+	if actual.OptStartTime != nil {
+		actualStart = *actual.OptStartTime
+	}
+	return desired.Name == actual.Name &&
+		desired.FilterSubject == actual.FilterSubject &&
+		desired.OptStartSeq == actual.OptStartSeq &&
+		desiredStart.Equal(actualStart) &&
+		desiredPrefix == actualPrefix
+}
+
+// pointerEqual reports whether desired and actual are both nil or point to equal values.
+func pointerEqual[T comparable](desired, actual *T) bool {
+	if desired == nil || actual == nil {
+		return desired == actual
+	}
+	return *desired == *actual
+}
+
+// userMetadata returns metadata without the keys reserved for the server, which are added to the metadata of
+// streams and consumers by newer servers. It returns nil, if no other keys are set.
+func userMetadata(metadata map[string]string) map[string]string {
+	var user map[string]string
+	for key, value := range metadata {
+		if strings.HasPrefix(key, "_nats.") {
+			continue
+		}
+		if user == nil {
+			user = make(map[string]string, len(metadata))
+		}
+		user[key] = value
+	}
+	return user
+}
+
+func placementEqual(desired, actual *nats.Placement) bool {
+	if desired == nil || actual == nil {
+		return desired == actual
+	}
+	return desired.Cluster == actual.Cluster && slices.Equal(desired.Tags, actual.Tags)
+}
+
+// PurgeSubject removes all messages of the stream streamName with a subject matching subject, which may contain
+// wildcards, e.g. "ORDERS.tenant42.>" to delete the data of a tenant.
+func (c *Connection) PurgeSubject(streamName, subject string) error {
+	if subject == "" {
+		return fmt.Errorf("stream %s could not be purged: subject cannot be empty", streamName)
+	}
+	return c.PurgeStream(streamName, PurgeOptions{Subject: subject})
+}
+
+// SnapshotStream writes a backup of the stream streamName, including its configuration and consumers, to w,
+// e.g. a file. The stream can be restored from the backup with RestoreStream.
+func (c *Connection) SnapshotStream(streamName string, w io.Writer) error {
+	if err := validateStreamName(streamName); err != nil {
+		return err
+	}
+	if err := c.nats.SnapshotStream(streamName, w, cmp.Or(c.timeouts.Publish, defaultAPITimeout)); err != nil {
+		return fmt.Errorf("stream %s could not be snapshotted: %w", streamName, err)
+	}
+	return nil
+}
+
+// RestoreStream creates the stream streamName from a backup of the same stream written by SnapshotStream.
+// The stream must not exist.
+func (c *Connection) RestoreStream(streamName string, r io.Reader) error {
+	if err := validateStreamName(streamName); err != nil {
+		return err
+	}
+	if err := c.nats.RestoreStream(streamName, r, cmp.Or(c.timeouts.Publish, defaultAPITimeout)); err != nil {
+		return fmt.Errorf("stream %s could not be restored: %w", streamName, err)
+	}
+	return nil
+}
+
+// GetMsg returns the message of the stream streamName with the stream sequence seq without a consumer.
+// Compressed messages are decompressed, but chunks of large messages are returned as stored.
+// The returned error wraps nats.ErrMsgNotFound, if the message does not exist.
+func (c *Connection) GetMsg(streamName string, seq uint64) (Msg, error) {
+	if err := validateStreamName(streamName); err != nil {
+		return Msg{}, err
+	}
+	rawMsg, err := c.nats.GetMsg(streamName, seq)
+	if err != nil {
+		return Msg{}, fmt.Errorf("message %d of stream %s could not be fetched: %w", seq, streamName, err)
+	}
+	return makeStoredMsg(c.compression, streamName, rawMsg)
+}
+
+// GetLastMsgForSubject returns the last message of the stream streamName with the subject, e.g. the latest state
+// of an entity. The subject may contain wildcards. Like GetMsg, it needs no consumer.
+// The returned error wraps nats.ErrMsgNotFound, if no message of the subject exists.
+func (c *Connection) GetLastMsgForSubject(streamName, subject string) (Msg, error) {
+	if err := validateStreamName(streamName); err != nil {
+		return Msg{}, err
+	}
+	rawMsg, err := c.nats.GetLastMsg(streamName, subject)
+	if err != nil {
+		return Msg{}, fmt.Errorf("last message of subject %s could not be fetched: %w", subject, err)
+	}
+	return makeStoredMsg(c.compression, streamName, rawMsg)
+}
+
+// DeleteMsg deletes the message of the stream streamName with the stream sequence seq, e.g. for an erasure request.
+// If secure is true, the stored data is overwritten, so it can't be recovered from the disk; this is slower.
+// The returned error wraps nats.ErrMsgNotFound, if the message does not exist.
+func (c *Connection) DeleteMsg(streamName string, seq uint64, secure bool) error {
+	if err := validateStreamName(streamName); err != nil {
+		return err
+	}
+	if err := c.nats.DeleteMsg(streamName, seq, secure); err != nil {
+		return fmt.Errorf("message %d of stream %s could not be deleted: %w", seq, streamName, err)
+	}
+	return nil
+}
+
+// makeStoredMsg converts a message fetched from a stream to a Msg, whose Metadata contains its stream sequence.
+func makeStoredMsg(cfg compressionConfig, streamName string, rawMsg *nats.RawStreamMsg) (Msg, error) {
+	msg := Msg{
+		Subject: rawMsg.Subject,
+		Reply:   rawMsg.Header.Get(headerReplyTo),
+		MsgID:   rawMsg.Header.Get(nats.MsgIdHdr),
+		Data:    rawMsg.Data,
+		Header:  Header(rawMsg.Header),
+		Metadata: Metadata{
+			Stream:         streamName,
+			StreamSequence: rawMsg.Sequence,
+			Timestamp:      rawMsg.Time,
+		},
+	}
+	if err := decompressMsg(cfg, &msg); err != nil {
+		return Msg{}, fmt.Errorf("message %d of stream %s could not be decompressed: %w", rawMsg.Sequence, streamName, err)
+	}
+	return msg, nil
+}
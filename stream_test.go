@@ -0,0 +1,1036 @@
+package vnats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+)
+
+func TestConnection_natsStreamConfig(t *testing.T) {
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		config StreamConfig
+		want   *nats.StreamConfig
+	}{
+		{
+			name:   "Defaults",
+			config: StreamConfig{Name: "ORDERS"},
+			want: &nats.StreamConfig{
+				Name:              "ORDERS",
+				Subjects:          []string{"ORDERS.>"},
+				Retention:         nats.LimitsPolicy,
+				MaxAge:            defaultMaxAge,
+				MaxBytes:          -1,
+				MaxMsgs:           -1,
+				MaxMsgsPerSubject: -1,
+				Storage:           nats.FileStorage,
+				Discard:           nats.DiscardOld,
+				Duplicates:        defaultDuplicationWindow,
+			},
+		},
+		{
+			name: "Custom",
+			config: StreamConfig{
+				Name:              "ORDERS",
+				Subjects:          []string{"ORDERS.created", "ORDERS.cancelled"},
+				Retention:         WorkQueueRetention,
+				MaxAge:            time.Hour,
+				MaxBytes:          1024,
+				MaxMsgs:           10,
+				MaxMsgsPerSubject: 1,
+				Storage:           MemoryStorage,
+				Replicas:          3,
+				Placement:         &Placement{Cluster: "eu", Tags: []string{"ssd"}},
+				Discard:           DiscardNew,
+				DuplicateWindow:   time.Minute,
+				SubjectTransform: &SubjectTransform{
+					Source:      "ORDERS.*.created",
+					Destination: "ORDERS.created.{{wildcard(1)}}",
+				},
+				AllowDirect: true,
+				RePublish:   &RePublish{Source: "ORDERS.>", Destination: "events.>", HeadersOnly: true},
+				AllowRollup: true,
+				DenyDelete:  true,
+				Metadata:    map[string]string{"owner": "checkout"},
+			},
+			want: &nats.StreamConfig{
+				Name:              "ORDERS",
+				Subjects:          []string{"ORDERS.created", "ORDERS.cancelled"},
+				Retention:         nats.WorkQueuePolicy,
+				MaxAge:            time.Hour,
+				MaxBytes:          1024,
+				MaxMsgs:           10,
+				MaxMsgsPerSubject: 1,
+				Storage:           nats.MemoryStorage,
+				Replicas:          3,
+				Placement:         &nats.Placement{Cluster: "eu", Tags: []string{"ssd"}},
+				Discard:           nats.DiscardNew,
+				Duplicates:        time.Minute,
+				SubjectTransform: &nats.SubjectTransformConfig{
+					Source:      "ORDERS.*.created",
+					Destination: "ORDERS.created.{{wildcard(1)}}",
+				},
+				AllowDirect: true,
+				RePublish:   &nats.RePublish{Source: "ORDERS.>", Destination: "events.>", HeadersOnly: true},
+				AllowRollup: true,
+				DenyDelete:  true,
+				Metadata:    map[string]string{"owner": "checkout"},
+			},
+		},
+		{
+			name: "Mirror",
+			config: StreamConfig{
+				Name:   "ORDERS_MIRROR",
+				Mirror: &StreamSource{Name: "ORDERS", StartSequence: 10, Domain: "eu"},
+			},
+			want: &nats.StreamConfig{
+				Name:              "ORDERS_MIRROR",
+				Retention:         nats.LimitsPolicy,
+				MaxAge:            defaultMaxAge,
+				MaxBytes:          -1,
+				MaxMsgs:           -1,
+				MaxMsgsPerSubject: -1,
+				Storage:           nats.FileStorage,
+				Discard:           nats.DiscardOld,
+				Duplicates:        defaultDuplicationWindow,
+				Mirror:            &nats.StreamSource{Name: "ORDERS", OptStartSeq: 10, Domain: "eu"},
+			},
+		},
+		{
+			name: "Sources",
+			config: StreamConfig{
+				Name: "ALL",
+				Sources: []StreamSource{
+					{Name: "ORDERS", FilterSubject: "ORDERS.created"},
+					{Name: "PRODUCTS", StartTime: startTime},
+				},
+			},
+			want: &nats.StreamConfig{
+				Name:              "ALL",
+				Subjects:          []string{"ALL.>"},
+				Retention:         nats.LimitsPolicy,
+				MaxAge:            defaultMaxAge,
+				MaxBytes:          -1,
+				MaxMsgs:           -1,
+				MaxMsgsPerSubject: -1,
+				Storage:           nats.FileStorage,
+				Discard:           nats.DiscardOld,
+				Duplicates:        defaultDuplicationWindow,
+				Sources: []*nats.StreamSource{
+					{Name: "ORDERS", FilterSubject: "ORDERS.created"},
+					{Name: "PRODUCTS", OptStartTime: &startTime},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+			if diff := cmp.Diff(tt.want, conn.natsStreamConfig(tt.config)); diff != "" {
+				t.Errorf("natsStreamConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnection_EnsureStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_CONFIG"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	if err := conn.EnsureStream(StreamConfig{
+		Name:      streamName,
+		Retention: InterestRetention,
+		MaxMsgs:   100,
+		Storage:   MemoryStorage,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := js.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Config.Retention != nats.InterestPolicy || info.Config.MaxMsgs != 100 || info.Config.Storage != nats.MemoryStorage {
+		t.Errorf("stream was created with config %+v", info.Config)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_ReconcileStream(t *testing.T) {
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+	b := conn.nats.(*testBridge)
+	config := StreamConfig{Name: "ORDERS", MaxMsgs: 100}
+
+	drift, err := conn.ReconcileStream(config, ReconcileReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]StreamDrift{{Field: "Name", Desired: "ORDERS"}}, drift); diff != "" {
+		t.Errorf("ReconcileStream() of missing stream mismatch (-want +got):\n%s", diff)
+	}
+
+	actual := conn.natsStreamConfig(StreamConfig{Name: "ORDERS", MaxAge: time.Hour})
+	b.streamInfo = &nats.StreamInfo{Config: *actual}
+	drift, err = conn.ReconcileStream(config, ReconcileReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDrift := []StreamDrift{
+		{Field: "MaxAge", Desired: defaultMaxAge, Actual: time.Hour},
+		{Field: "MaxMsgs", Desired: int64(100), Actual: int64(-1)},
+	}
+	if diff := cmp.Diff(wantDrift, drift); diff != "" {
+		t.Errorf("ReconcileStream() mismatch (-want +got):\n%s", diff)
+	}
+	if b.updatedStream != nil {
+		t.Errorf("ReconcileReport must not update the stream")
+	}
+
+	placement := &Placement{Tags: []string{"ssd"}}
+	drift, err = conn.ReconcileStream(StreamConfig{Name: "ORDERS", MaxAge: time.Hour, Placement: placement}, ReconcileReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDrift = []StreamDrift{{Field: "Placement", Desired: &nats.Placement{Tags: []string{"ssd"}}, Actual: (*nats.Placement)(nil)}}
+	if diff := cmp.Diff(wantDrift, drift); diff != "" {
+		t.Errorf("ReconcileStream() of placement mismatch (-want +got):\n%s", diff)
+	}
+
+	b.streamInfo.Config.Metadata = map[string]string{"owner": "checkout", "_nats.ver": "2.11.0"}
+	metadata := map[string]string{"owner": "billing"}
+	drift, err = conn.ReconcileStream(StreamConfig{Name: "ORDERS", MaxAge: time.Hour, Metadata: metadata}, ReconcileReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDrift = []StreamDrift{{Field: "Metadata", Desired: metadata, Actual: map[string]string{"owner": "checkout"}}}
+	if diff := cmp.Diff(wantDrift, drift); diff != "" {
+		t.Errorf("ReconcileStream() of metadata mismatch (-want +got):\n%s", diff)
+	}
+	info, err := conn.StreamInfo("ORDERS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"owner": "checkout"}, info.Metadata); diff != "" {
+		t.Errorf("StreamInfo() metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := conn.ReconcileStream(config, ReconcileApply); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(conn.natsStreamConfig(config), b.updatedStream); diff != "" {
+		t.Errorf("updated stream config mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnection_UpdateStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_UPDATE"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	config := StreamConfig{Name: streamName, MaxMsgs: 100}
+	if drift, err := conn.ReconcileStream(config, ReconcileApply); err != nil || len(drift) != 1 {
+		t.Fatalf("ReconcileStream() of missing stream returned drift %v, error %v", drift, err)
+	}
+	if drift, err := conn.ReconcileStream(config, ReconcileReport); err != nil || len(drift) != 0 {
+		t.Fatalf("ReconcileStream() of created stream returned drift %v, error %v", drift, err)
+	}
+
+	config.MaxMsgs = 200
+	if err := conn.UpdateStream(config); err != nil {
+		t.Fatal(err)
+	}
+	info, err := js.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Config.MaxMsgs != 200 {
+		t.Errorf("stream has MaxMsgs %d, want 200", info.Config.MaxMsgs)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_PurgeStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        PurgeOptions
+		wantRequest *nats.StreamPurgeRequest
+		wantErr     bool
+	}{
+		{name: "All", opts: PurgeOptions{}},
+		{
+			name:        "Subject and keep",
+			opts:        PurgeOptions{Subject: "ORDERS.created", Keep: 5},
+			wantRequest: &nats.StreamPurgeRequest{Subject: "ORDERS.created", Keep: 5},
+		},
+		{
+			name:        "Sequence",
+			opts:        PurgeOptions{Sequence: 42},
+			wantRequest: &nats.StreamPurgeRequest{Sequence: 42},
+		},
+		{name: "Sequence and keep", opts: PurgeOptions{Sequence: 42, Keep: 5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+			err := conn.PurgeStream("ORDERS", tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PurgeStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantRequest, conn.nats.(*testBridge).purgeRequest); diff != "" {
+				t.Errorf("purge request mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnection_PurgeSubject(t *testing.T) {
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+	if err := conn.PurgeSubject("ORDERS", ""); err == nil {
+		t.Error("PurgeSubject() without subject must fail")
+	}
+	if err := conn.PurgeSubject("ORDERS", "ORDERS.tenant42.>"); err != nil {
+		t.Fatal(err)
+	}
+	want := &nats.StreamPurgeRequest{Subject: "ORDERS.tenant42.>"}
+	if diff := cmp.Diff(want, conn.nats.(*testBridge).purgeRequest); diff != "" {
+		t.Errorf("purge request mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnection_PurgeAndDeleteStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_PURGE"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, subject := range []string{"a", "a", "a", "b", "b"} {
+		if _, err := pub.Publish(&Msg{
+			Subject: streamName + "." + subject,
+			MsgID:   fmt.Sprintf("msg-%d", i),
+			Data:    []byte(subject),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := conn.PurgeStream(streamName, PurgeOptions{Subject: streamName + ".a", Keep: 1}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := js.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 3 {
+		t.Errorf("stream has %d messages after purging subject, want 3", info.State.Msgs)
+	}
+
+	if err := conn.PurgeSubject(streamName, streamName+".b"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = js.StreamInfo(streamName); err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream has %d messages after purging subject, want 1", info.State.Msgs)
+	}
+
+	if err := conn.PurgeStream(streamName, PurgeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = js.StreamInfo(streamName); err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 0 {
+		t.Errorf("stream has %d messages after purge, want 0", info.State.Msgs)
+	}
+
+	if err := conn.DeleteStream(streamName); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.StreamInfo(streamName); !errors.Is(err, nats.ErrStreamNotFound) {
+		t.Errorf("StreamInfo() of deleted stream returned error %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_StreamInfo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_INFO"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	if _, err := conn.StreamInfo(streamName); !errors.Is(err, nats.ErrStreamNotFound) {
+		t.Errorf("StreamInfo() of missing stream returned error %v", err)
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if _, err := pub.Publish(&Msg{
+			Subject: streamName + ".created",
+			MsgID:   fmt.Sprintf("msg-%d", i),
+			Data:    []byte("data"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = createSubscriber(t, conn, "TestStreamInfo", streamName+".>", MultipleSubscribersAllowed)
+
+	info, err := conn.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != streamName || info.Msgs != 3 || info.Bytes == 0 || info.FirstSeq != 1 || info.LastSeq != 3 ||
+		info.Consumers != 1 || info.LastTime.Before(info.FirstTime) {
+		t.Errorf("StreamInfo() = %+v", info)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_validateStreamConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  StreamConfig
+		wantErr bool
+	}{
+		{name: "Mirror", config: StreamConfig{Name: "COPY", Mirror: &StreamSource{Name: "ORDERS"}}},
+		{
+			name:    "Mirror with subjects",
+			config:  StreamConfig{Name: "COPY", Subjects: []string{"COPY.>"}, Mirror: &StreamSource{Name: "ORDERS"}},
+			wantErr: true,
+		},
+		{
+			name:    "Mirror with sources",
+			config:  StreamConfig{Name: "COPY", Mirror: &StreamSource{Name: "ORDERS"}, Sources: []StreamSource{{Name: "PRODUCTS"}}},
+			wantErr: true,
+		},
+		{name: "Invalid source", config: StreamConfig{Name: "ALL", Sources: []StreamSource{{Name: "ORDERS.>"}}}, wantErr: true},
+		{
+			name:    "Start sequence and time",
+			config:  StreamConfig{Name: "ALL", Sources: []StreamSource{{Name: "ORDERS", StartSequence: 1, StartTime: time.Now()}}},
+			wantErr: true,
+		},
+		{name: "Duplicate window of a day", config: StreamConfig{Name: "ORDERS", DuplicateWindow: 24 * time.Hour}},
+		{
+			name:    "Duplicate window exceeding MaxAge",
+			config:  StreamConfig{Name: "ORDERS", MaxAge: time.Hour, DuplicateWindow: 2 * time.Hour},
+			wantErr: true,
+		},
+		{name: "Rollup and deny purge", config: StreamConfig{Name: "ORDERS", AllowRollup: true, DenyPurge: true}, wantErr: true},
+		{name: "Negative duplicate window", config: StreamConfig{Name: "ORDERS", DuplicateWindow: -time.Minute}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateStreamConfig(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("validateStreamConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnection_EnsureStream_MirrorAndSources(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	originName := integrationTestStreamName + "_ORIGIN"
+	mirrorName := integrationTestStreamName + "_MIRROR"
+	aggregateName := integrationTestStreamName + "_AGGREGATE"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{mirrorName, aggregateName, originName} {
+		_ = js.DeleteStream(name)
+		t.Cleanup(func() { _ = js.DeleteStream(name) })
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: originName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, subject := range []string{"a", "b", "a"} {
+		if _, err := pub.Publish(&Msg{
+			Subject: originName + "." + subject,
+			MsgID:   fmt.Sprintf("msg-%d", i),
+			Data:    []byte(subject),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mirror := StreamConfig{Name: mirrorName, Mirror: &StreamSource{Name: originName}}
+	aggregate := StreamConfig{Name: aggregateName, Sources: []StreamSource{{Name: originName, FilterSubject: originName + ".a"}}}
+	for _, config := range []StreamConfig{mirror, aggregate} {
+		if err := conn.EnsureStream(config); err != nil {
+			t.Fatal(err)
+		}
+		if drift, err := conn.ReconcileStream(config, ReconcileReport); err != nil || len(drift) != 0 {
+			t.Errorf("ReconcileStream() of %s returned drift %v, error %v", config.Name, drift, err)
+		}
+	}
+
+	wantMsgs := map[string]uint64{mirrorName: 3, aggregateName: 2}
+	for name, want := range wantMsgs {
+		var msgs uint64
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			info, err := conn.StreamInfo(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msgs = info.Msgs; msgs == want {
+				break
+			}
+		}
+		if msgs != want {
+			t.Errorf("stream %s has %d messages, want %d", name, msgs, want)
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_EnsureStream_Retention(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	tests := []struct {
+		name      string
+		retention RetentionPolicy
+		wantMsgs  uint64
+	}{
+		{name: "Limits", retention: LimitsRetention, wantMsgs: 3},
+		{name: "Interest", retention: InterestRetention, wantMsgs: 0},
+		{name: "WorkQueue", retention: WorkQueueRetention, wantMsgs: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeIntegrationTestConn(t)
+			streamName := integrationTestStreamName + "_RETENTION"
+			js, err := conn.JetStream()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = js.DeleteStream(streamName)
+			t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+			if err := conn.EnsureStream(StreamConfig{
+				Name:      streamName,
+				Retention: tt.retention,
+				Storage:   MemoryStorage,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			// Interest retention only keeps messages for existing consumers, so subscribe before publishing.
+			sub := createSubscriber(t, conn, "TestRetention", streamName+".>", MultipleSubscribersAllowed)
+			pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range 3 {
+				if _, err := pub.Publish(&Msg{
+					Subject: streamName + ".created",
+					MsgID:   fmt.Sprintf("msg-%d", i),
+					Data:    []byte("data"),
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			handled := make(chan struct{}, 3)
+			if err := sub.Start(func(_ Msg) error {
+				handled <- struct{}{}
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			for range 3 {
+				select {
+				case <-handled:
+				case <-time.After(5 * time.Second):
+					t.Fatal("messages were not handled")
+				}
+			}
+
+			var info StreamInfo
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+				if info, err = conn.StreamInfo(streamName); err != nil {
+					t.Fatal(err)
+				}
+				if info.Msgs == tt.wantMsgs {
+					break
+				}
+			}
+			if info.Msgs != tt.wantMsgs {
+				t.Errorf("stream has %d messages after ack, want %d", info.Msgs, tt.wantMsgs)
+			}
+			if err := conn.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestConnection_EnsureStream_RePublish(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_REPUBLISH"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	config := StreamConfig{
+		Name:      streamName,
+		RePublish: &RePublish{Source: streamName + ".>", Destination: "republished." + streamName + ".>"},
+	}
+	if err := conn.EnsureStream(config); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := conn.ReconcileStream(config, ReconcileReport); err != nil || len(drift) != 0 {
+		t.Errorf("ReconcileStream() returned drift %v, error %v", drift, err)
+	}
+
+	nc := conn.nats.(*natsBridge).connection
+	received := make(chan *nats.Msg, 1)
+	sub, err := nc.ChanSubscribe("republished."+streamName+".>", received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+	if err := nc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pub.Publish(&Msg{Subject: streamName + ".created", MsgID: "msg-0", Data: []byte("data")}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.Subject != "republished."+streamName+".created" || string(msg.Data) != "data" {
+			t.Errorf("received republished message %s with data %q", msg.Subject, msg.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not republished")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_EnsureStream_MaxMsgsPerSubject(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_LATEST"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	config := StreamConfig{Name: streamName, MaxMsgsPerSubject: 1}
+	if err := conn.EnsureStream(config); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := conn.ReconcileStream(config, ReconcileReport); err != nil || len(drift) != 0 {
+		t.Errorf("ReconcileStream() returned drift %v, error %v", drift, err)
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, subject := range []string{streamName + ".42", streamName + ".43", streamName + ".42"} {
+		if _, err := pub.Publish(&Msg{Subject: subject, MsgID: fmt.Sprintf("msg-%d", i), Data: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := conn.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Msgs != 2 {
+		t.Errorf("stream contains %d messages, want only the latest of each of the 2 subjects", info.Msgs)
+	}
+	latest, err := conn.GetLastMsgForSubject(streamName, streamName+".42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(latest.Data) != "2" {
+		t.Errorf("GetLastMsgForSubject() returned data %q, want the latest message", latest.Data)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_EnsureStream_Rollup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_ROLLUP"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	config := StreamConfig{Name: streamName, AllowRollup: true}
+	if err := conn.EnsureStream(config); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := conn.ReconcileStream(config, ReconcileReport); err != nil || len(drift) != 0 {
+		t.Errorf("ReconcileStream() returned drift %v, error %v", drift, err)
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := streamName + ".42"
+	for i := range 3 {
+		if _, err := pub.Publish(&Msg{Subject: subject, MsgID: fmt.Sprintf("msg-%d", i), Data: []byte("event")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rollup := &Msg{Subject: subject, MsgID: "rollup", Data: []byte("state"), Header: Header{}}
+	rollup.Header.Set(HeaderRollup, RollupSubject)
+	if _, err := pub.Publish(rollup); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := conn.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Msgs != 1 {
+		t.Errorf("stream contains %d messages after rollup, want 1", info.Msgs)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_EnsureStream_DenyDeleteAndPurge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_AUDIT"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	config := StreamConfig{Name: streamName, DenyDelete: true, DenyPurge: true}
+	if err := conn.EnsureStream(config); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := conn.ReconcileStream(config, ReconcileReport); err != nil || len(drift) != 0 {
+		t.Errorf("ReconcileStream() returned drift %v, error %v", drift, err)
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack, err := pub.Publish(&Msg{Subject: streamName + ".login", MsgID: "msg-0", Data: []byte("audit")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.DeleteMsg(streamName, ack.Sequence, false); err == nil {
+		t.Error("DeleteMsg() of stream with DenyDelete succeeded")
+	}
+	if err := conn.PurgeStream(streamName, PurgeOptions{}); err == nil {
+		t.Error("PurgeStream() of stream with DenyPurge succeeded")
+	}
+	if info, err := conn.StreamInfo(streamName); err != nil || info.Msgs != 1 {
+		t.Errorf("StreamInfo() = %+v, error %v, want the message to be kept", info, err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_GetLastMsgForSubject(t *testing.T) {
+	storedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+	conn.nats.(*testBridge).storedMsgs = []*nats.RawStreamMsg{
+		{Subject: "ORDERS.42", Sequence: 1, Data: []byte("created")},
+		{Subject: "ORDERS.43", Sequence: 2, Data: []byte("created")},
+		{
+			Subject:  "ORDERS.42",
+			Sequence: 3,
+			Header:   nats.Header{nats.MsgIdHdr: []string{"msg-3"}},
+			Data:     []byte("shipped"),
+			Time:     storedAt,
+		},
+	}
+
+	got, err := conn.GetLastMsgForSubject("ORDERS", "ORDERS.42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Msg{
+		Subject:  "ORDERS.42",
+		MsgID:    "msg-3",
+		Data:     []byte("shipped"),
+		Header:   Header{nats.MsgIdHdr: []string{"msg-3"}},
+		Metadata: Metadata{Stream: "ORDERS", StreamSequence: 3, Timestamp: storedAt},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetLastMsgForSubject() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := conn.GetLastMsgForSubject("ORDERS", "ORDERS.44"); !errors.Is(err, nats.ErrMsgNotFound) {
+		t.Errorf("GetLastMsgForSubject() of missing subject returned error %v", err)
+	}
+}
+
+func TestConnection_GetMsg(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	for _, allowDirect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("AllowDirect=%t", allowDirect), func(t *testing.T) {
+			conn := makeIntegrationTestConn(t)
+			streamName := integrationTestStreamName + "_GET"
+			js, err := conn.JetStream()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = js.DeleteStream(streamName)
+			t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+			if err := conn.EnsureStream(StreamConfig{Name: streamName, AllowDirect: allowDirect}); err != nil {
+				t.Fatal(err)
+			}
+			pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, subject := range []string{"42", "43", "42"} {
+				if _, err := pub.Publish(&Msg{
+					Subject: streamName + "." + subject,
+					MsgID:   fmt.Sprintf("msg-%d", i),
+					Data:    []byte(fmt.Sprintf("data-%d", i)),
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			msg, err := conn.GetMsg(streamName, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Subject != streamName+".43" || msg.MsgID != "msg-1" || string(msg.Data) != "data-1" ||
+				msg.Metadata.StreamSequence != 2 {
+				t.Errorf("GetMsg() = %+v", msg)
+			}
+			msg, err = conn.GetLastMsgForSubject(streamName, streamName+".42")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(msg.Data) != "data-2" || msg.Metadata.StreamSequence != 3 {
+				t.Errorf("GetLastMsgForSubject() = %+v", msg)
+			}
+			if _, err := conn.GetMsg(streamName, 10); !errors.Is(err, nats.ErrMsgNotFound) {
+				t.Errorf("GetMsg() of missing message returned error %v", err)
+			}
+			next, err := conn.nats.GetNextMsg(streamName, streamName+".42", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(next.Data) != "data-2" || next.Sequence != 3 || next.Header.Get(nats.MsgIdHdr) != "msg-2" {
+				t.Errorf("GetNextMsg() = %+v", next)
+			}
+			if _, err := conn.nats.GetNextMsg(streamName, streamName+".43", 3); !errors.Is(err, nats.ErrMsgNotFound) {
+				t.Errorf("GetNextMsg() of missing message returned error %v", err)
+			}
+			if err := conn.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestConnection_DeleteMsg(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_DELETE"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if _, err := pub.Publish(&Msg{
+			Subject: streamName + ".customer",
+			MsgID:   fmt.Sprintf("msg-%d", i),
+			Data:    []byte("personal data"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := conn.DeleteMsg(streamName, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.DeleteMsg(streamName, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, seq := range []uint64{1, 2} {
+		if _, err := conn.GetMsg(streamName, seq); !errors.Is(err, nats.ErrMsgNotFound) {
+			t.Errorf("GetMsg() of deleted message %d returned error %v", seq, err)
+		}
+	}
+	if _, err := conn.GetMsg(streamName, 3); err != nil {
+		t.Errorf("GetMsg() of remaining message returned error %v", err)
+	}
+	if err := conn.DeleteMsg(streamName, 2, false); !errors.Is(err, nats.ErrMsgNotFound) {
+		t.Errorf("DeleteMsg() of deleted message returned error %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_SnapshotStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_SNAPSHOT"
+	otherName := integrationTestStreamName + "_OTHER"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: streamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("x"), 100*1024) // multiple chunks of the snapshot
+	for i := range 5 {
+		if _, err := pub.Publish(&Msg{
+			Subject: streamName + ".created",
+			MsgID:   fmt.Sprintf("msg-%d", i),
+			Data:    data,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var backup bytes.Buffer
+	if err := conn.SnapshotStream(streamName, &backup); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.DeleteStream(streamName); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.RestoreStream(otherName, bytes.NewReader(backup.Bytes())); err == nil {
+		t.Error("RestoreStream() with another stream name must fail")
+	}
+	if err := conn.RestoreStream(streamName, bytes.NewReader(backup.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	info, err := conn.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Msgs != 5 || info.LastSeq != 5 {
+		t.Errorf("restored stream has %d messages up to sequence %d, want 5", info.Msgs, info.LastSeq)
+	}
+	msg, err := conn.GetMsg(streamName, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.MsgID != "msg-2" || !bytes.Equal(msg.Data, data) {
+		t.Errorf("restored message %s has %d bytes", msg.MsgID, len(msg.Data))
+	}
+
+	if err := conn.RestoreStream(streamName, bytes.NewReader(backup.Bytes())); err == nil {
+		t.Error("RestoreStream() of existing stream must fail")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
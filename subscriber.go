@@ -1,99 +1,903 @@
 package vnats
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nats-io/nats.go"
 )
 
 // NewSubscriber creates a new Subscriber that subscribes to a NATS stream.
 func (c *Connection) NewSubscriber(args SubscriberArgs) (*Subscriber, error) {
-	subscription, err := c.nats.Subscribe(args.Subject, args.ConsumerName, args.Mode)
+	if err := validateSubjects(args); err != nil {
+		return nil, fmt.Errorf("subscriber could not be created: %w", err)
+	}
+	if args.MaxMsgs < 0 {
+		return nil, fmt.Errorf("subscriber could not be created: MaxMsgs must not be negative")
+	}
+	if args.FetchBatchSize < 0 {
+		return nil, fmt.Errorf("subscriber could not be created: FetchBatchSize must not be negative")
+	}
+	if args.Mode < MultipleSubscribersAllowed || args.Mode > SingleSubscriberOrderedPerSubject {
+		return nil, fmt.Errorf("subscriber could not be created: unknown Mode %d", args.Mode)
+	}
+	if err := c.checkSingleSubscriber(args); err != nil {
+		return nil, fmt.Errorf("subscriber could not be created: %w", err)
+	}
+	if args.Concurrency > 1 && args.Mode == SingleSubscriberStrictMessageOrder {
+		return nil, fmt.Errorf("subscriber could not be created: Concurrency is not allowed with SingleSubscriberStrictMessageOrder")
+	}
+	if args.DeliverPolicy == DeliverByStartSequence && args.StartSequence == 0 {
+		return nil, fmt.Errorf("subscriber could not be created: DeliverByStartSequence requires StartSequence")
+	}
+	if args.DeliverPolicy == DeliverByStartTime && args.StartTime.IsZero() {
+		return nil, fmt.Errorf("subscriber could not be created: DeliverByStartTime requires StartTime")
+	}
+	if args.FetchBatchSize == 0 {
+		args.FetchBatchSize = max(args.Concurrency, 1)
+	}
+	if args.AckWait == 0 {
+		args.AckWait = defaultAckWait
+	}
+	if args.InProgressInterval >= args.AckWait {
+		return nil, fmt.Errorf("subscriber could not be created: InProgressInterval must be shorter than AckWait")
+	}
+	if args.IdleBackoff >= args.AckWait {
+		return nil, fmt.Errorf("subscriber could not be created: IdleBackoff must be shorter than AckWait")
+	}
+	if args.IdleHeartbeat > 0 && args.IdleHeartbeat*2 >= c.fetchMaxWait(args.MaxWait) {
+		return nil, fmt.Errorf("subscriber could not be created: IdleHeartbeat must be shorter than half of MaxWait")
+	}
+	if args.Mode == SingleSubscriberStrictMessageOrder {
+		if args.MaxAckPending > 1 {
+			return nil, fmt.Errorf("subscriber could not be created: MaxAckPending must be 1 with SingleSubscriberStrictMessageOrder")
+		}
+		args.MaxAckPending = 1
+	} else if args.MaxAckPending == 0 {
+		args.MaxAckPending = defaultMaxAckPending
+	}
+
+	if c.strictTopology {
+		if err := c.requireConsumer(args.streamName(), args.ConsumerName); err != nil {
+			return nil, fmt.Errorf("subscriber could not be created: %w", err)
+		}
+	}
+	subscription, err := c.nats.Subscribe(args)
 	if err != nil {
 		return nil, fmt.Errorf("subscriber could not be created: %w", err)
 	}
 
 	sub := &Subscriber{
 		conn:         c,
-		subscription: subscription,
-		logger:       c.logger,
+		logger:       c.logger.With(slog.String("stream", args.streamName()), slog.String("consumer", args.ConsumerName)),
 		consumerName: args.ConsumerName,
+		subject:      args.Subject,
+		mode:         args.Mode,
 		quitSignal:   make(chan bool),
+
+		args:           args,
+		fetchBatchSize: args.FetchBatchSize,
+		maxWait:        args.MaxWait,
+		resumed:        make(chan struct{}),
+	}
+	sub.stats.stream, sub.stats.consumer, sub.stats.metrics = args.streamName(), args.ConsumerName, c.metrics
+	sub.livenessThreshold = sub.maxProgressInterval()
+	close(sub.resumed)
+	if len(args.Subjects) > 0 {
+		sub.subject = strings.Join(args.Subjects, ", ")
+	}
+	if args.Concurrency > 1 {
+		sub.workers = make(chan struct{}, args.Concurrency)
+	}
+	if args.Mode == SingleSubscriberOrderedPerSubject {
+		sub.sequencer = newSubjectSequencer()
 	}
+	sub.subscription.Store(subscription)
 
+	c.mu.Lock()
 	c.subscribers = append(c.subscribers, sub)
+	c.mu.Unlock()
 	return sub, nil
 }
 
+// checkSingleSubscriber checks that the Connection has no other Subscriber of the consumer of args, if either of
+// them requires a single Subscriber. Subscribers of other Connections, e.g. in other processes, can't be detected.
+func (c *Connection) checkSingleSubscriber(args SubscriberArgs) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, sub := range c.subscribers {
+		if sub.consumerName != args.ConsumerName || sub.args.streamName() != args.streamName() {
+			continue
+		}
+		if subscription := sub.subscription.Load(); subscription != nil && !subscription.IsValid() { // stopped
+			continue
+		}
+		if args.Mode != MultipleSubscribersAllowed || sub.mode != MultipleSubscribersAllowed {
+			return fmt.Errorf("consumer %s already has a Subscriber, only one is allowed with its Mode", args.ConsumerName)
+		}
+	}
+	return nil
+}
+
+// validateSubjects checks that either Subject or Subjects of args is set, and all Subjects belong to one stream.
+func validateSubjects(args SubscriberArgs) error {
+	if len(args.Subjects) == 0 {
+		return nil
+	}
+	if args.Subject != "" {
+		return fmt.Errorf("either Subject or Subjects must be set")
+	}
+	streamName, _, _ := strings.Cut(args.Subjects[0], ".")
+	for _, subject := range args.Subjects {
+		if s, _, _ := strings.Cut(subject, "."); s != streamName {
+			return fmt.Errorf("subjects %s and %s belong to different streams", args.Subjects[0], subject)
+		}
+	}
+	return nil
+}
+
+// streamName returns the name of the stream, which is the first token of the subjects.
+func (args SubscriberArgs) streamName() string {
+	subject := args.Subject
+	if len(args.Subjects) > 0 {
+		subject = args.Subjects[0]
+	}
+	streamName, _, _ := strings.Cut(subject, ".")
+	return streamName
+}
+
 // MsgHandler is the type of function the Subscriber has to implement to process an incoming message.
 type MsgHandler func(msg Msg) error
 
+// MsgHandlerWithContext is a MsgHandler, which receives a context. The context is done when the AckWait duration of
+// the message expires, since the message is redelivered afterwards, when the HandlerTimeout is exceeded,
+// or when the Subscriber is stopped or drained.
+type MsgHandlerWithContext func(ctx context.Context, msg Msg) error
+
 // Subscriber subscribes to a NATS consumer and pulls messages to handle by MsgHandler.
 type Subscriber struct {
 	conn         *Connection
-	subscription *nats.Subscription
+	subscription atomic.Pointer[nats.Subscription]
 	logger       *slog.Logger
 	consumerName string
-	handler      MsgHandler
+	subject      string
+	mode         SubscriptionMode
+	handler      AckHandler
+	interceptors []SubscribeInterceptor
 	quitSignal   chan bool
+	stopped      chan struct{} // closed when the go-routine started by Start returned
+	drainOnce    sync.Once
+	drainErr     error
+	ctx          context.Context // done when the Subscriber is stopped or drained
+	cancel       context.CancelFunc
+
+	args           SubscriberArgs // with defaults applied, used to recreate the consumer
+	fetchBatchSize int
+	maxWait        time.Duration
+	workers        chan struct{}     // bounds the concurrently handled messages, nil if they are handled sequentially
+	sequencer      *subjectSequencer // keeps the order per subject, nil unless SingleSubscriberOrderedPerSubject
+	inFlight       sync.WaitGroup
+	timedOut       chan struct{} // closed when the last handler, which exceeded HandlerTimeout, returned
+	fetched        int           // number of fetched messages, only accessed by the go-routine started by Start
+
+	running   atomic.Bool
+	lastFetch atomic.Int64 // unix nanoseconds
+
+	// lastProgress is updated, when a fetch or a handler starts, in unix nanoseconds. The Subscriber is not alive,
+	// if it made no progress within the livenessThreshold.
+	lastProgress      atomic.Int64
+	livenessThreshold time.Duration
+	pauseMu           sync.Mutex // guards paused and resumed
+	paused            bool
+	resumed           chan struct{} // closed unless the Subscriber is paused
+	stats             subscriberStats
+	panicsMu          sync.Mutex
+	panics            map[uint64]int // number of panics per stream sequence of messages, which weren't handled without panic
+}
+
+// SubscriberStatus describes the liveness of a Subscriber.
+type SubscriberStatus struct {
+	ConsumerName string
+	Subject      string
+
+	// Running is true, if the Subscriber was started and not stopped.
+	Running bool
+
+	// Paused is true, if the Subscriber was paused by Pause and not resumed yet.
+	Paused bool
+
+	// LastFetch is the time the Subscriber finished the last fetch of messages.
+	LastFetch time.Time
+
+	// LastProgress is the time the Subscriber started the last fetch or handler, or signaled that a handler is in
+	// progress with InProgressInterval.
+	LastProgress time.Time
+
+	// Alive is true, if the Subscriber is running and paused or made progress within the time a fetch and a handler
+	// may take: MaxWait plus IdleBackoff plus HandlerTimeout, or AckWait without HandlerTimeout.
+	// Otherwise, the MsgHandler is probably stuck.
+	Alive bool
 }
 
 // Start subscribes to the NATS consumer and starts a go-routine that handles pulled messages.
 func (s *Subscriber) Start(handler MsgHandler) (err error) {
+	return s.StartWithContext(func(_ context.Context, msg Msg) error {
+		return handler(msg)
+	})
+}
+
+// StartWithContext starts the Subscriber like Start, but passes a context to the handler, so long-running work
+// can be aborted when the message would be redelivered or the Subscriber is stopped.
+func (s *Subscriber) StartWithContext(handler MsgHandlerWithContext) error {
+	return s.start(s.implicitAck(s.intercept(handler)))
+}
+
+// Next fetches the next message synchronously, for callers, which drive the consumption themselves instead of
+// starting a handler, like CLI tools or tests. It waits until a message is available or ctx is done.
+// The message must be acknowledged with the returned Acker. Next can't be used after the Subscriber was started.
+func (s *Subscriber) Next(ctx context.Context) (Msg, Acker, error) {
+	if s.handler != nil {
+		return Msg{}, nil, fmt.Errorf("subscriber %s is started, Next can't be used", s.consumerName)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return Msg{}, nil, err
+		}
+
+		// a context without deadline is bound by the default wait of the JetStream context
+		fetchCtx, cancel := context.WithCancel(ctx)
+		natsMsgs, err := s.subscription.Load().Fetch(1, nats.Context(fetchCtx))
+		cancel()
+		s.lastFetch.Store(time.Now().UnixNano())
+		if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+			continue
+		} else if err != nil {
+			return Msg{}, nil, fmt.Errorf("message could not be fetched: %w", err)
+		}
+
+		for _, natsMsg := range natsMsgs {
+			if msg, acker, ok := s.prepareMsg(natsMsg); ok {
+				s.stats.deliver(msg)
+				return msg, acker, nil
+			}
+		}
+	}
+}
+
+func (s *Subscriber) start(handler AckHandler) error {
 	if s.handler != nil {
 		return fmt.Errorf("handler is already set, don't call Start() multiple times")
 	}
 
 	s.handler = handler
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.lastFetch.Store(time.Now().UnixNano())
+	s.progress()
+	s.stopped = make(chan struct{})
+	s.running.Store(true)
 
 	go func() {
-		for {
-			select {
-			case <-s.quitSignal:
-				s.logger.Info("Received signal to quit subscription go-routine.")
-				return
-			default:
-				s.processMessages()
-			}
+		exhausted := s.run()
+		s.running.Store(false)
+		close(s.stopped)
+		if exhausted { // after closing stopped, since DrainSubscriptions may wait for it while holding the lock
+			s.conn.removeSubscriber(s)
 		}
 	}()
 
 	return nil
 }
 
+// run handles pulled messages until the Subscriber is drained, or MaxMsgs or Until is reached.
+// It returns true in the latter case, after the subscription was drained.
+func (s *Subscriber) run() (exhausted bool) {
+	for {
+		select {
+		case <-s.quitSignal:
+			s.logger.Info("Received signal to quit subscription go-routine.")
+			s.inFlight.Wait()
+			return false
+		case <-s.resumedSignal():
+			if s.exhausted() {
+				s.inFlight.Wait()
+				if err := s.drainSubscription(); err != nil {
+					s.logger.Error("Subscription could not be drained", slog.String("error", err.Error()))
+				}
+				s.cancel()
+				s.logger.Info("Subscriber reached MaxMsgs or Until")
+				return true
+			}
+			s.processMessages()
+		}
+	}
+}
+
+// exhausted reports whether MaxMsgs messages were fetched or Until is reached.
+func (s *Subscriber) exhausted() bool {
+	return (s.args.MaxMsgs > 0 && s.fetched >= s.args.MaxMsgs) ||
+		(!s.args.Until.IsZero() && !time.Now().Before(s.args.Until))
+}
+
+// Done returns a channel, which is closed when the Subscriber stopped handling messages, e.g. after MaxMsgs
+// messages were handled or Until is reached. It must be called after the Subscriber was started.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.stopped
+}
+
 // Stop unsubscribes the consumer from the NATS stream.
 func (s *Subscriber) Stop() error {
-	if err := s.subscription.Unsubscribe(); err != nil {
+	if err := s.subscription.Load().Unsubscribe(); err != nil {
 		return err
 	}
+	if s.cancel != nil {
+		s.cancel()
+	}
 
 	s.handler = nil
-	s.logger.Info("Unsubscribed consumer", slog.String("name", s.consumerName))
+	s.logger.Info("Unsubscribed consumer")
+
+	return nil
+}
+
+// Unsubscribe drains the subscription of this Subscriber only, waits until the handlers of messages already fetched
+// returned, and removes the Subscriber from the Connection. The Connection and its other Subscribers keep running.
+// If ctx is done before, the context error is returned, while draining continues in the background.
+func (s *Subscriber) Unsubscribe(ctx context.Context) error {
+	if !s.conn.removeSubscriber(s) {
+		return fmt.Errorf("subscriber %s is not subscribed", s.consumerName)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		if err := s.drain(context.Background()); err != nil {
+			done <- fmt.Errorf("subscriber %s could not be drained: %w", s.consumerName, err)
+			return
+		}
+		if s.stopped != nil {
+			<-s.stopped
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			s.logger.Info("Unsubscribed consumer")
+		}
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("subscriber %s could not be unsubscribed: %w", s.consumerName, ctx.Err())
+	}
+}
+
+// Pause stops fetching messages until Resume is called, e.g. during a maintenance window. Messages, which are
+// already fetched, are still handled. The consumer and its state are kept on the server, so no messages are lost.
+// Pause returns an error with InactiveThreshold, since the server would delete the consumer of the paused Subscriber.
+func (s *Subscriber) Pause() error {
+	if s.args.InactiveThreshold > 0 {
+		return fmt.Errorf("consumer %s could not be paused: it is deleted after InactiveThreshold", s.consumerName)
+	}
+
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.paused {
+		return nil
+	}
+	s.paused = true
+	s.resumed = make(chan struct{})
+	s.logger.Info("Paused consumer")
+	return nil
+}
+
+// Resume continues fetching messages after Pause.
+func (s *Subscriber) Resume() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if !s.paused {
+		return
+	}
+	s.paused = false
+	s.progress() // don't report the Subscriber as stuck before the next fetch
+	close(s.resumed)
+	s.logger.Info("Resumed consumer")
+}
+
+// PauseUntil pauses the delivery of messages by the consumer on the server until the given time, e.g. during
+// a planned maintenance of a downstream system. Unlike Pause, it affects all Subscribers of the consumer, even in
+// other processes, and the consumer resumes automatically. A zero time resumes the consumer immediately.
+// Requires NATS server 2.11 or newer. The pause must be shorter than InactiveThreshold, since the server would
+// delete the consumer otherwise.
+func (s *Subscriber) PauseUntil(until time.Time) error {
+	if s.args.InactiveThreshold > 0 && !until.IsZero() && time.Until(until) >= s.args.InactiveThreshold {
+		return fmt.Errorf("consumer %s could not be paused: the pause must be shorter than InactiveThreshold", s.consumerName)
+	}
+	if err := s.conn.nats.PauseConsumer(s.args.streamName(), s.consumerName, until); err != nil {
+		return fmt.Errorf("consumer %s could not be paused: %w", s.consumerName, err)
+	}
+	if until.IsZero() {
+		s.logger.Info("Resumed consumer on server")
+	} else {
+		s.logger.Info("Paused consumer on server", slog.Time("until", until))
+	}
+	return nil
+}
+
+// resumedSignal returns a channel, which is closed unless the Subscriber is paused.
+func (s *Subscriber) resumedSignal() <-chan struct{} {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return s.resumed
+}
 
+// drain drains the subscription and quits the go-routine started by Start.
+func (s *Subscriber) drain(ctx context.Context) error {
+	if err := s.drainSubscription(); err != nil {
+		return err
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	defer close(s.quitSignal) // the go-routine returns after the running handlers, if ctx was done before
+	if s.running.Load() {
+		select {
+		case s.quitSignal <- true:
+		case <-s.stopped: // the go-routine returned after MaxMsgs or Until
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	return nil
 }
 
+// drainSubscription drains the subscription once, since the go-routine started by Start drains it as well
+// after MaxMsgs or Until.
+func (s *Subscriber) drainSubscription() error {
+	s.drainOnce.Do(func() {
+		s.drainErr = s.subscription.Load().Drain()
+	})
+	return s.drainErr
+}
+
+// ensureConsumer checks if the consumer of the Subscriber still exists on the server and recreates it otherwise,
+// e.g. if it was deleted by the inactivity threshold.
+func (s *Subscriber) ensureConsumer() (recreated bool, err error) {
+	current := s.subscription.Load()
+	if _, err := current.ConsumerInfo(); !errors.Is(err, nats.ErrConsumerNotFound) {
+		return false, err
+	}
+	if s.conn.strictTopology {
+		return false, fmt.Errorf("%w: %s must not be recreated with WithStrictTopology", ErrConsumerNotFound, s.consumerName)
+	}
+
+	// Unsubscribe before subscribing again, otherwise the recreated consumer would be deleted by Unsubscribe.
+	if err := current.Unsubscribe(); err != nil {
+		s.logger.Debug("Unsubscribe of deleted consumer failed", slog.Any("error", err))
+	}
+
+	subscription, err := s.conn.nats.Subscribe(s.args)
+	if err != nil {
+		return false, fmt.Errorf("consumer %s could not be recreated: %w", s.consumerName, err)
+	}
+	s.subscription.Store(subscription)
+	return true, nil
+}
+
+// maxProgressInterval returns the longest time between the progress of a healthy Subscriber: a fetch waits up to
+// MaxWait and may be followed by IdleBackoff, a handler runs up to HandlerTimeout, or AckWait before its message
+// is redelivered.
+func (s *Subscriber) maxProgressInterval() time.Duration {
+	fetch := s.conn.fetchMaxWait(s.maxWait) + max(s.args.IdleBackoff, 0)
+	return fetch + cmp.Or(s.args.HandlerTimeout, s.ackWait())
+}
+
+// fetchMaxWait returns the time a pull request waits for messages: maxWait, the Fetch timeout, or else the wait of
+// the JetStream context, which is the Publish timeout or the default of nats.go.
+func (c *Connection) fetchMaxWait(maxWait time.Duration) time.Duration {
+	return cmp.Or(maxWait, c.timeouts.Fetch, c.timeouts.Publish, defaultFetchMaxWait)
+}
+
+// progress signals that the Subscriber is alive, see SubscriberStatus.Alive.
+func (s *Subscriber) progress() {
+	s.lastProgress.Store(time.Now().UnixNano())
+}
+
+func (s *Subscriber) status() SubscriberStatus {
+	lastFetch := time.Unix(0, s.lastFetch.Load())
+	lastProgress := time.Unix(0, s.lastProgress.Load())
+	running := s.running.Load()
+	s.pauseMu.Lock()
+	paused := s.paused
+	s.pauseMu.Unlock()
+
+	return SubscriberStatus{
+		ConsumerName: s.consumerName,
+		Subject:      s.subject,
+		Running:      running,
+		Paused:       paused,
+		LastFetch:    lastFetch,
+		LastProgress: lastProgress,
+		Alive:        running && (paused || time.Since(lastProgress) < s.livenessThreshold),
+	}
+}
+
 func (s *Subscriber) processMessages() {
-	natsMsgs, err := s.subscription.Fetch(1) // Fetch only one msg at once to keep the order
-	if errors.Is(err, nats.ErrTimeout) {     // ErrTimeout is expected/ no new messages, so we don't log it
+	defer recoverPanic(s.logger, s.conn.hooks, "subscriber "+s.consumerName)
+
+	var fetchOptions []nats.PullOpt
+	maxWait := s.conn.fetchMaxWait(s.maxWait)
+	if !s.args.Until.IsZero() { // don't wait for messages after Until
+		maxWait = min(maxWait, time.Until(s.args.Until))
+	}
+	if maxWait > 0 {
+		fetchOptions = append(fetchOptions, nats.MaxWait(maxWait))
+	}
+	if s.args.IdleHeartbeat > 0 && s.args.IdleHeartbeat*2 < maxWait { // nats.go rejects longer heartbeats
+		fetchOptions = append(fetchOptions, nats.PullHeartbeat(s.args.IdleHeartbeat))
+	}
+	batchSize := s.fetchBatchSize
+	if s.args.MaxMsgs > 0 { // don't fetch messages after MaxMsgs, they would be redelivered after AckWait
+		batchSize = min(batchSize, s.args.MaxMsgs-s.fetched)
+	}
+
+	s.progress()
+	natsMsgs, err := s.subscription.Load().Fetch(batchSize, fetchOptions...)
+	s.lastFetch.Store(time.Now().UnixNano())
+	s.fetched += len(natsMsgs)
+	if errors.Is(err, nats.ErrTimeout) { // ErrTimeout is expected/ no new messages, so we don't log it
+		s.idle()
 		return
 	} else if err != nil {
 		s.logger.Error("Failed to receive msg", slog.String("error", err.Error()))
+		s.idle()
 		return
 	}
 
-	msg := makeMsg(natsMsgs[0])
-	if err = s.handler(msg); err != nil {
-		s.logger.Error("Message handle error, will be NAKed", slog.String("error", err.Error()))
-		if err := natsMsgs[0].NakWithDelay(defaultNakDelay); err != nil {
-			s.logger.Error("natsMsg.Nak() failed", slog.String("error", err.Error()))
+	for _, natsMsg := range natsMsgs {
+		if s.workers == nil { // handle the messages one after another to keep the order
+			s.handleMsg(natsMsg)
+			continue
+		}
+
+		var wait <-chan struct{}
+		var done func()
+		if s.sequencer != nil {
+			wait, done = s.sequencer.enqueue(natsMsg.Subject)
+		}
+
+		s.workers <- struct{}{}
+		s.inFlight.Add(1)
+		go func() {
+			defer s.inFlight.Done()
+			defer func() { <-s.workers }()
+			defer recoverPanic(s.logger, s.conn.hooks, "subscriber "+s.consumerName)
+			if s.sequencer != nil { // wait for the previous message of the subject
+				defer done()
+				<-wait
+			}
+			s.handleMsg(natsMsg)
+		}()
+	}
+}
+
+// idle pauses for IdleBackoff, or until the Subscriber is stopped.
+func (s *Subscriber) idle() {
+	if s.args.IdleBackoff <= 0 {
+		return
+	}
+	timer := time.NewTimer(s.args.IdleBackoff)
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// nakDelay returns the delay of the redelivery of a failed message, which was delivered numDelivered times.
+func (s *Subscriber) nakDelay(numDelivered uint64) time.Duration {
+	backoff := s.args.Backoff
+	if len(backoff) == 0 {
+		return defaultNakDelay
+	}
+	if numDelivered == 0 { // not a JetStream message
+		numDelivered = 1
+	}
+	return backoff[min(numDelivered, uint64(len(backoff)))-1]
+}
+
+func (s *Subscriber) ackWait() time.Duration {
+	if s.args.AckWait == 0 {
+		return defaultAckWait
+	}
+	return s.args.AckWait
+}
+
+// handleMsg reassembles and decompresses natsMsg and calls the handler.
+func (s *Subscriber) handleMsg(natsMsg *nats.Msg) {
+	msg, msgAcker, ok := s.prepareMsg(natsMsg)
+	if !ok {
+		return
+	}
+
+	var acker Acker = msgAcker
+	if s.sequencer != nil {
+		seq := msg.Metadata.StreamSequence
+		if s.sequencer.blocked(natsMsg.Subject, seq) {
+			s.logger.Debug("Earlier message of subject was NAKed, message will be NAKed", slog.String("subject", natsMsg.Subject))
+			s.nak(natsMsg, s.nakDelay(msg.Metadata.NumDelivered))
+			return
 		}
+		acker = &orderedAcker{Acker: acker, sequencer: s.sequencer, subject: natsMsg.Subject, seq: seq}
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	switch {
+	case s.args.HandlerTimeout > 0:
+		ctx, cancel = context.WithTimeout(s.ctx, s.args.HandlerTimeout)
+	case s.args.InProgressInterval > 0:
+		ctx, cancel = context.WithCancel(s.ctx)
+	default:
+		ctx, cancel = context.WithTimeout(s.ctx, s.ackWait())
+	}
+	defer cancel()
+	if s.args.InProgressInterval > 0 {
+		stop := s.heartbeat(natsMsg)
+		defer stop()
+	}
+
+	s.stats.deliver(msg)
+
+	if s.args.HandlerTimeout > 0 {
+		s.callHandlerWithTimeout(ctx, msg, acker)
+		return
+	}
+	s.callHandler(ctx, msg, acker)
+}
+
+// callHandlerWithTimeout calls the handler like callHandler, but NAKs the message, if ctx expires before the handler
+// returned. The handler keeps running in the background until it returns and is counted as in flight. With
+// Concurrency, it keeps its worker slot until then; otherwise, it returns after the handler that timed out before.
+func (s *Subscriber) callHandlerWithTimeout(ctx context.Context, msg Msg, acker Acker) {
+	done := make(chan struct{})
+	s.inFlight.Add(1)
+	go func() {
+		defer s.inFlight.Done()
+		defer close(done)
+		s.callHandler(ctx, msg, acker)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	select {
+	case <-done: // the handler returned just in time
+		return
+	default:
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) { // the Subscriber is stopped, wait for the handler
+		<-done
 		return
 	}
 
-	if err = natsMsgs[0].Ack(); err != nil {
+	s.logger.Warn("Handler exceeded HandlerTimeout, message will be NAKed",
+		slog.String("subject", msg.Subject), slog.String("msgID", msg.MsgID))
+	if err := acker.Nak(s.nakDelay(msg.Metadata.NumDelivered)); err != nil {
+		s.logger.Error("natsMsg.Nak() failed", slog.String("error", err.Error()))
+	}
+	if s.conn.hooks.OnHandlerTimeout != nil {
+		s.conn.hooks.OnHandlerTimeout(s.consumerName, msg)
+	}
+
+	if s.workers != nil { // keep the worker slot, so timed out handlers are bounded by Concurrency
+		<-done
+		return
+	}
+	// Messages are handled one after another, so at most one timed out handler keeps running besides the next one.
+	previous := s.timedOut
+	s.timedOut = done
+	if previous != nil {
+		<-previous
+	}
+}
+
+// callHandler calls the handler and recovers its panics, so the message is NAKed, or terminated after MaxPanics.
+func (s *Subscriber) callHandler(ctx context.Context, msg Msg, acker Acker) {
+	ctx, span := s.conn.startProcessSpan(ctx, s.args.streamName(), s.consumerName, msg)
+	defer span.End()
+
+	s.progress()
+	seq := msg.Metadata.StreamSequence
+	s.stats.addInFlight(1)
+	defer s.stats.addInFlight(-1)
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			s.resetPanics(seq)
+			return
+		}
+		failSpan(ctx, recovered)
+		reportPanic(s.logger, s.conn.hooks, "handler of subscriber "+s.consumerName, recovered,
+			slog.String("subject", msg.Subject),
+			slog.String("msgID", msg.MsgID),
+			slog.Uint64("streamSequence", seq),
+			slog.Uint64("numDelivered", msg.Metadata.NumDelivered))
+
+		if s.args.MaxPanics > 0 && s.countPanic(seq) >= s.args.MaxPanics {
+			s.resetPanics(seq)
+			s.logger.Error("Handler panicked MaxPanics times, message will be terminated",
+				slog.String("subject", msg.Subject), slog.String("msgID", msg.MsgID))
+			if err := acker.Term(); err != nil {
+				s.logger.Error("natsMsg.Term() failed", slog.String("error", err.Error()))
+			}
+			return
+		}
+		if err := acker.Nak(s.nakDelay(msg.Metadata.NumDelivered)); err != nil {
+			s.logger.Error("natsMsg.Nak() failed", slog.String("error", err.Error()))
+		}
+	}()
+	s.handler(ctx, msg, acker)
+}
+
+// countPanic increments and returns the number of panics for the message with the stream sequence seq.
+func (s *Subscriber) countPanic(seq uint64) int {
+	s.panicsMu.Lock()
+	defer s.panicsMu.Unlock()
+	if s.panics == nil {
+		s.panics = make(map[uint64]int)
+	}
+	s.panics[seq]++
+	return s.panics[seq]
+}
+
+func (s *Subscriber) resetPanics(seq uint64) {
+	s.panicsMu.Lock()
+	defer s.panicsMu.Unlock()
+	delete(s.panics, seq)
+}
+
+// prepareMsg reassembles and decompresses natsMsg. It returns false, if the message must not be handled,
+// since it is a chunk of a handled message or invalid. natsMsg is acknowledged, NAKed or terminated then.
+func (s *Subscriber) prepareMsg(natsMsg *nats.Msg) (Msg, *msgAcker, bool) {
+	msg := makeMsg(natsMsg)
+	msg.Subject = strings.TrimPrefix(msg.Subject, s.conn.streamPrefix)
+	msg.Metadata.Stream = strings.TrimPrefix(msg.Metadata.Stream, s.conn.streamPrefix)
+	if msg.Header.Get(headerChunkID) != "" {
+		if s.args.Mode != SingleSubscriberStrictMessageOrder {
+			s.logger.Error("Chunked messages require SingleSubscriberStrictMessageOrder, chunk will be terminated",
+				slog.String("subject", msg.Subject), slog.String("msgID", msg.MsgID))
+			s.term(natsMsg)
+			return Msg{}, nil, false
+		}
+		if msg.Header.Get(headerChunkIndex) != "0" { // delivered after the first chunk was acknowledged
+			s.logger.Debug("Chunk of a handled message is acknowledged", slog.String("msgID", msg.MsgID))
+			s.ack(natsMsg)
+			return Msg{}, nil, false
+		}
+		complete, err := s.reassembleChunks(msg)
+		if err != nil {
+			s.logger.Error("Chunked message could not be reassembled, will be NAKed", slog.String("error", err.Error()),
+				slog.String("subject", msg.Subject), slog.String("msgID", msg.MsgID))
+			s.nak(natsMsg, s.nakDelay(msg.Metadata.NumDelivered))
+			return Msg{}, nil, false
+		}
+		msg = complete
+	}
+
+	if err := decompressMsg(s.conn.compression, &msg); err != nil { // it fails on each redelivery as well
+		s.logger.Error("Message could not be decompressed, will be terminated", slog.String("error", err.Error()))
+		s.term(natsMsg)
+		return Msg{}, nil, false
+	}
+	return msg, &msgAcker{natsMsg: natsMsg, stats: &s.stats, deliveredAt: time.Now()}, true
+}
+
+// heartbeat signals that natsMsg is in progress every InProgressInterval, until stop is called.
+func (s *Subscriber) heartbeat(natsMsg *nats.Msg) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.args.InProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := natsMsg.InProgress(); err != nil { // e.g. the message was already acknowledged
+					s.logger.Debug("natsMsg.InProgress() failed", slog.String("error", err.Error()))
+					continue
+				}
+				s.progress() // the handler may run longer than AckWait
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// implicitAck converts handler to an AckHandler, which acknowledges the message if handler succeeded,
+// and NAKs it otherwise.
+func (s *Subscriber) implicitAck(handler MsgHandlerWithContext) AckHandler {
+	return func(ctx context.Context, msg Msg, acker Acker) {
+		if err := handler(ctx, msg); err != nil {
+			var nakDelay *nakDelayError
+			if errors.As(err, &nakDelay) {
+				if err := acker.Nak(nakDelay.delay); err != nil {
+					s.logger.Error("natsMsg.Nak() failed", slog.String("error", err.Error()))
+				}
+				return
+			}
+			failSpan(ctx, err)
+
+			if s.args.MaxDeliver > 0 && msg.Metadata.NumDelivered >= uint64(s.args.MaxDeliver) {
+				s.logger.Error("Message handle error at last delivery, will be terminated",
+					slog.String("error", err.Error()), slog.String("subject", msg.Subject), slog.String("msgID", msg.MsgID))
+				if s.args.OnMaxDeliverExceeded != nil {
+					s.args.OnMaxDeliverExceeded(msg, err)
+				}
+				if err := acker.Term(); err != nil {
+					s.logger.Error("natsMsg.Term() failed", slog.String("error", err.Error()))
+				}
+				return
+			}
+
+			delay := s.nakDelay(msg.Metadata.NumDelivered)
+			s.logger.Error("Message handle error, will be NAKed", slog.String("error", err.Error()),
+				slog.String("subject", msg.Subject), slog.String("msgID", msg.MsgID), slog.Duration("delay", delay))
+			if err := acker.Nak(delay); err != nil {
+				s.logger.Error("natsMsg.Nak() failed", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		if err := acker.Ack(); err != nil {
+			s.logger.Error("natsMsg.Ack() failed:", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (s *Subscriber) ack(natsMsg *nats.Msg) {
+	if err := natsMsg.Ack(); err != nil {
 		s.logger.Error("natsMsg.Ack() failed:", slog.String("error", err.Error()))
 	}
 }
+
+func (s *Subscriber) term(natsMsg *nats.Msg) {
+	if err := natsMsg.Term(); err != nil {
+		s.logger.Error("natsMsg.Term() failed", slog.String("error", err.Error()))
+		return
+	}
+	s.stats.ack(Termed, 0) // right after the delivery, before any handler
+}
+
+func (s *Subscriber) nak(natsMsg *nats.Msg, delay time.Duration) {
+	if err := natsMsg.NakWithDelay(delay); err != nil {
+		s.logger.Error("natsMsg.Nak() failed", slog.String("error", err.Error()))
+		return
+	}
+	s.stats.ack(Naked, 0) // right after the delivery, before any handler
+}
+
+// nakDelayError is returned by a MsgHandler to NAK the message with a specific delay, without logging an error.
+type nakDelayError struct {
+	delay time.Duration
+}
+
+func (e *nakDelayError) Error() string {
+	return fmt.Sprintf("message is NAKed with delay %s", e.delay)
+}
@@ -1,8 +1,15 @@
 package vnats
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -389,3 +396,934 @@ func makeHandlerSubscriber(t *testing.T, alwaysFail bool, s *subscriptionState,
 	}
 	return handler
 }
+
+func TestSubscriber_FetchBatchSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".batch"
+	conn := makeIntegrationTestConn(t)
+
+	want := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+	publishStringMessages(t, conn, subject, want)
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:   "TestFetchBatchSize",
+		Subject:        subject,
+		FetchBatchSize: 4,
+		MaxWait:        time.Millisecond * 500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, len(want))
+	if err := sub.Start(func(msg Msg) error {
+		received <- string(msg.Data)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range want {
+		select {
+		case got := <-received:
+			if got != w {
+				t.Errorf("received %s, want %s", got, w)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("message %s was not received", w)
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_NewSubscriber_InvalidFetchBatchSize(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+	if _, err := conn.NewSubscriber(SubscriberArgs{ConsumerName: "invalid", Subject: "MESSAGES.>", FetchBatchSize: -1}); err == nil {
+		t.Error("NewSubscriber() with negative FetchBatchSize should fail")
+	}
+}
+
+func TestSubscriber_Concurrency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".concurrency"
+	conn := makeIntegrationTestConn(t)
+
+	msgs := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+	publishStringMessages(t, conn, subject, msgs)
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: "TestConcurrency",
+		Subject:      subject,
+		Concurrency:  4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, maxRunning atomic.Int32
+	handled := make(chan struct{}, len(msgs))
+	if err := sub.Start(func(_ Msg) error {
+		current := running.Add(1)
+		for {
+			previous := maxRunning.Load()
+			if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 200)
+		running.Add(-1)
+		handled <- struct{}{}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range msgs {
+		select {
+		case <-handled:
+		case <-time.After(time.Second * 5):
+			t.Fatal("not all messages were handled")
+		}
+	}
+	if got := maxRunning.Load(); got < 2 || got > 4 {
+		t.Errorf("%d messages were handled concurrently, want 2 to 4", got)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_NewSubscriber_ConcurrencyWithStrictOrder(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+	_, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: "invalid",
+		Subject:      "MESSAGES.>",
+		Mode:         SingleSubscriberStrictMessageOrder,
+		Concurrency:  4,
+	})
+	if err == nil {
+		t.Error("NewSubscriber() with Concurrency and SingleSubscriberStrictMessageOrder should fail")
+	}
+}
+
+func TestSubscriber_StartWithContext(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".context"
+	conn := makeIntegrationTestConn(t)
+	publishStringMessages(t, conn, subject, []string{"long running"})
+
+	sub := createSubscriber(t, conn, "TestStartWithContext", subject, MultipleSubscribersAllowed)
+	started := make(chan time.Time, 1)
+	aborted := make(chan error, 1)
+	if err := sub.StartWithContext(func(ctx context.Context, _ Msg) error {
+		deadline, _ := ctx.Deadline()
+		started <- deadline
+		<-ctx.Done()
+		aborted <- ctx.Err()
+		return ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case deadline := <-started:
+		if until := time.Until(deadline); until <= 0 || until > defaultAckWait {
+			t.Errorf("handler context has deadline in %s, want within AckWait", until)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("handler was not called")
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+	select {
+	case err := <-aborted:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler context error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second * 5):
+		t.Error("handler context was not cancelled on shutdown")
+	}
+}
+
+func TestSubscriber_Metadata(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".metadata"
+	conn := makeIntegrationTestConn(t)
+	publishStringMessages(t, conn, subject, []string{"redelivered"})
+
+	sub := createSubscriber(t, conn, "TestMetadata", subject, MultipleSubscribersAllowed)
+	deliveries := make(chan Metadata, 2)
+	if err := sub.Start(func(msg Msg) error {
+		deliveries <- msg.Metadata
+		if msg.Metadata.NumDelivered == 1 {
+			return fmt.Errorf("fail first delivery")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for want := uint64(1); want <= 2; want++ {
+		select {
+		case meta := <-deliveries:
+			if meta.NumDelivered != want || meta.Stream != integrationTestStreamName || meta.Consumer != "TestMetadata" || meta.StreamSequence == 0 {
+				t.Errorf("delivery %d has Metadata %+v", want, meta)
+			}
+		case <-time.After(defaultNakDelay * 2):
+			t.Fatalf("delivery %d was not received", want)
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriber_StartWithAcker(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".acker"
+	conn := makeIntegrationTestConn(t)
+	publishStringMessages(t, conn, subject, []string{"nak", "terminate"})
+
+	sub := createSubscriber(t, conn, "TestStartWithAcker", subject, SingleSubscriberStrictMessageOrder)
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	if err := sub.StartWithAcker(func(_ context.Context, msg Msg, acker Acker) {
+		mu.Lock()
+		calls[string(msg.Data)]++
+		mu.Unlock()
+
+		if err := acker.InProgress(); err != nil {
+			t.Errorf("InProgress() error = %v", err)
+		}
+		var err error
+		switch {
+		case string(msg.Data) == "terminate":
+			err = acker.Term()
+		case msg.Metadata.NumDelivered == 1:
+			err = acker.Nak(time.Millisecond * 100)
+		default:
+			err = acker.Ack()
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Second * 2)
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["nak"] != 2 || calls["terminate"] != 1 {
+		t.Errorf("handler calls = %v, want nak twice and terminate once", calls)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriber_AckWaitAndMaxAckPending(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+
+	tests := []struct {
+		name              string
+		args              SubscriberArgs
+		wantAckWait       time.Duration
+		wantMaxAckPending int
+	}{
+		{
+			name:              "defaults",
+			args:              SubscriberArgs{ConsumerName: "TestAckWaitDefaults", Subject: integrationTestStreamName + ".ackwait"},
+			wantAckWait:       defaultAckWait,
+			wantMaxAckPending: defaultMaxAckPending,
+		},
+		{
+			name:              "strict order",
+			args:              SubscriberArgs{ConsumerName: "TestAckWaitStrict", Subject: integrationTestStreamName + ".ackwait", Mode: SingleSubscriberStrictMessageOrder},
+			wantAckWait:       defaultAckWait,
+			wantMaxAckPending: 1,
+		},
+		{
+			name:              "custom",
+			args:              SubscriberArgs{ConsumerName: "TestAckWaitCustom", Subject: integrationTestStreamName + ".ackwait", AckWait: time.Minute * 5, MaxAckPending: 50},
+			wantAckWait:       time.Minute * 5,
+			wantMaxAckPending: 50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := conn.NewSubscriber(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := sub.subscription.Load().ConsumerInfo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Config.AckWait != tt.wantAckWait || info.Config.MaxAckPending != tt.wantMaxAckPending {
+				t.Errorf("consumer has AckWait = %s, MaxAckPending = %d, want %s, %d",
+					info.Config.AckWait, info.Config.MaxAckPending, tt.wantAckWait, tt.wantMaxAckPending)
+			}
+		})
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_NewSubscriber_MaxAckPendingWithStrictOrder(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+	_, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:  "invalid",
+		Subject:       "MESSAGES.>",
+		Mode:          SingleSubscriberStrictMessageOrder,
+		MaxAckPending: 10,
+	})
+	if err == nil {
+		t.Error("NewSubscriber() with MaxAckPending and SingleSubscriberStrictMessageOrder should fail")
+	}
+}
+
+func TestSubscriber_InProgressInterval(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".heartbeat"
+	conn := makeIntegrationTestConn(t)
+	publishStringMessages(t, conn, subject, []string{"long running"})
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:       "TestInProgressInterval",
+		Subject:            subject,
+		AckWait:            time.Second,
+		InProgressInterval: time.Millisecond * 300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deliveries atomic.Int32
+	if err := sub.StartWithContext(func(ctx context.Context, _ Msg) error {
+		deliveries.Add(1)
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("handler context has a deadline with InProgressInterval")
+		}
+		time.Sleep(time.Millisecond * 2500)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Second * 4)
+	if n := deliveries.Load(); n != 1 {
+		t.Errorf("message was delivered %d times, want 1", n)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_NewSubscriber_InvalidInProgressInterval(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+	_, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:       "invalid",
+		Subject:            "MESSAGES.>",
+		AckWait:            time.Second,
+		InProgressInterval: time.Second,
+	})
+	if err == nil {
+		t.Error("NewSubscriber() with InProgressInterval not shorter than AckWait should fail")
+	}
+}
+
+func TestSubscriber_PauseUntil(t *testing.T) {
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+	sub := &Subscriber{
+		conn:         conn,
+		logger:       slog.Default(),
+		consumerName: "shipping",
+		args:         SubscriberArgs{ConsumerName: "shipping", Subject: "ORDERS.created"},
+	}
+	b := conn.nats.(*testBridge)
+
+	until := time.Now().Add(time.Hour)
+	if err := sub.PauseUntil(until); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.pausedUntil["shipping"]; !got.Equal(until) {
+		t.Errorf("consumer is paused until %v, want %v", got, until)
+	}
+	if err := sub.PauseUntil(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.pausedUntil["shipping"]; !got.IsZero() {
+		t.Errorf("consumer is paused until %v after resume", got)
+	}
+}
+
+func TestSubscriber_Pause_InactiveThreshold(t *testing.T) {
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+	sub := &Subscriber{
+		conn:         conn,
+		logger:       slog.Default(),
+		consumerName: "shipping",
+		args:         SubscriberArgs{ConsumerName: "shipping", Subject: "ORDERS.created", InactiveThreshold: time.Hour},
+	}
+	b := conn.nats.(*testBridge)
+
+	if err := sub.Pause(); err == nil {
+		t.Error("Pause() with InactiveThreshold should return an error")
+	}
+	if err := sub.PauseUntil(time.Now().Add(time.Hour * 2)); err == nil {
+		t.Error("PauseUntil() not shorter than InactiveThreshold should return an error")
+	}
+	if _, ok := b.pausedUntil["shipping"]; ok {
+		t.Error("consumer was paused on the server")
+	}
+	if err := sub.PauseUntil(time.Now().Add(time.Minute)); err != nil {
+		t.Errorf("PauseUntil() shorter than InactiveThreshold returned error %v", err)
+	}
+}
+
+func TestSubscriber_LogFields(t *testing.T) {
+	var logs bytes.Buffer
+	conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+	conn.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	sub, err := conn.NewSubscriber(SubscriberArgs{ConsumerName: "shipping", Subject: "ORDERS.created"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sub.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("log %q is not structured: %v", logs.String(), err)
+	}
+	if record["stream"] != "ORDERS" || record["consumer"] != "shipping" {
+		t.Errorf("log %q does not contain the fields stream=ORDERS and consumer=shipping", logs.String())
+	}
+}
+
+func TestSubscriber_PauseResume(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".pause"
+	conn := makeIntegrationTestConn(t)
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: "TestPauseResume",
+		Subject:      subject,
+		MaxWait:      time.Millisecond * 500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan string, 1)
+	if err := sub.Start(func(msg Msg) error {
+		received <- string(msg.Data)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sub.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second) // wait for the pending fetch to expire
+	publishStringMessages(t, conn, subject, []string{"after maintenance"})
+	select {
+	case data := <-received:
+		t.Fatalf("paused subscriber received %q", data)
+	case <-time.After(time.Second):
+	}
+	if status := sub.status(); !status.Paused || !status.Alive {
+		t.Errorf("status() of paused subscriber = %+v", status)
+	}
+
+	sub.Resume()
+	select {
+	case data := <-received:
+		if data != "after maintenance" {
+			t.Errorf("received %q, want %q", data, "after maintenance")
+		}
+	case <-time.After(time.Second * 5):
+		t.Error("resumed subscriber did not receive the message")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriber_Unsubscribe(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".unsubscribe"
+	conn := makeIntegrationTestConn(t)
+	other := createSubscriber(t, conn, "TestUnsubscribeOther", integrationTestStreamName+".unsubscribeOther", MultipleSubscribersAllowed)
+	if err := other.Start(func(_ Msg) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := createSubscriber(t, conn, "TestUnsubscribe", subject, MultipleSubscribersAllowed)
+	started := make(chan struct{})
+	var finished atomic.Bool
+	if err := sub.Start(func(_ Msg) error {
+		close(started)
+		time.Sleep(time.Millisecond * 500)
+		finished.Store(true)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	publishStringMessages(t, conn, subject, []string{"in flight"})
+	select {
+	case <-started:
+	case <-time.After(time.Second * 5):
+		t.Fatal("handler was not called")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err := sub.Unsubscribe(ctx); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Unsubscribe() returned before the in-flight handler finished")
+	}
+	if subs := conn.subscriberList(); len(subs) != 1 || subs[0] != other {
+		t.Errorf("subscribers after Unsubscribe() = %v, want only the other subscriber", subs)
+	}
+	if err := sub.Unsubscribe(ctx); err == nil {
+		t.Error("second Unsubscribe() should fail")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriber_DeliverPolicy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".deliverPolicy"
+	conn := makeIntegrationTestConn(t)
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pub.Publish(&Msg{Subject: subject, MsgID: "old", Data: []byte("old")}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 10)
+	startTime := time.Now()
+	ack, err := pub.Publish(&Msg{Subject: subject, MsgID: "last", Data: []byte("last")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args SubscriberArgs
+		want string
+	}{
+		{name: "DeliverAll", args: SubscriberArgs{DeliverPolicy: DeliverAll}, want: "old"},
+		{name: "DeliverLastPerSubject", args: SubscriberArgs{DeliverPolicy: DeliverLastPerSubject}, want: "last"},
+		{name: "DeliverByStartSequence", args: SubscriberArgs{DeliverPolicy: DeliverByStartSequence, StartSequence: ack.Sequence}, want: "last"},
+		{name: "DeliverByStartTime", args: SubscriberArgs{DeliverPolicy: DeliverByStartTime, StartTime: startTime}, want: "last"},
+		{name: "DeliverNew", args: SubscriberArgs{DeliverPolicy: DeliverNew}, want: "new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args.ConsumerName = "Test" + tt.name
+			tt.args.Subject = subject
+			sub, err := conn.NewSubscriber(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			received := make(chan string, 3)
+			if err := sub.Start(func(msg Msg) error {
+				received <- string(msg.Data)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "new" {
+				if _, err := pub.Publish(&Msg{Subject: subject, MsgID: "new-" + tt.name, Data: []byte("new")}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			select {
+			case got := <-received:
+				if got != tt.want {
+					t.Errorf("first message = %q, want %q", got, tt.want)
+				}
+			case <-time.After(time.Second * 5):
+				t.Error("no message received")
+			}
+			if err := sub.Unsubscribe(context.Background()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriber_DeliverPolicy_Rebind(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	args := SubscriberArgs{ConsumerName: "TestRebind", Subject: integrationTestStreamName + ".rebind"}
+	conn := makeIntegrationTestConn(t)
+	if err := conn.ApplyTopology(Topology{Consumers: []ConsumerConfig{{
+		Stream:        integrationTestStreamName,
+		Name:          args.ConsumerName,
+		Subjects:      []string{args.Subject},
+		DeliverPolicy: DeliverNew,
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := conn.NewSubscriber(args)
+	if err != nil {
+		t.Fatalf("NewSubscriber() without DeliverPolicy could not bind to the existing consumer: %v", err)
+	}
+	if err := sub.Start(func(_ Msg) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	args.DeliverPolicy = DeliverAll
+	if _, err := conn.NewSubscriber(args); err == nil {
+		t.Error("NewSubscriber() with a different DeliverPolicy should not bind to the existing consumer")
+	}
+	if err := conn.DeleteConsumer(integrationTestStreamName, args.ConsumerName); err != nil {
+		t.Error(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_NewSubscriber_InvalidDeliverPolicy(t *testing.T) {
+	conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+	for _, policy := range []DeliverPolicy{DeliverByStartSequence, DeliverByStartTime} {
+		if _, err := conn.NewSubscriber(SubscriberArgs{
+			ConsumerName:  "invalid",
+			Subject:       "MESSAGES.>",
+			DeliverPolicy: policy,
+		}); err == nil {
+			t.Errorf("NewSubscriber() with DeliverPolicy %d without start position should fail", policy)
+		}
+	}
+}
+
+func TestConnection_NewSubscriber_Subjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    SubscriberArgs
+		wantErr bool
+	}{
+		{
+			name:    "Subjects of one stream",
+			args:    SubscriberArgs{ConsumerName: "subjects", Subjects: []string{"ORDERS.created", "ORDERS.cancelled"}},
+			wantErr: false,
+		},
+		{
+			name:    "Subject and Subjects",
+			args:    SubscriberArgs{ConsumerName: "subjects", Subject: "ORDERS.>", Subjects: []string{"ORDERS.created"}},
+			wantErr: true,
+		},
+		{
+			name:    "Subjects of different streams",
+			args:    SubscriberArgs{ConsumerName: "subjects", Subjects: []string{"ORDERS.created", "PRODUCTS.created"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+			if _, err := conn.NewSubscriber(tt.args); (err != nil) != tt.wantErr {
+				t.Errorf("NewSubscriber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubscriber_IdleBackoffAndHeartbeat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".idle"
+	conn := makeIntegrationTestConn(t)
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:  "TestIdleBackoffAndHeartbeat",
+		Subject:       subject,
+		MaxWait:       time.Millisecond * 200,
+		IdleBackoff:   time.Millisecond * 300,
+		IdleHeartbeat: time.Millisecond * 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan string, 1)
+	if err := sub.Start(func(msg Msg) error {
+		received <- string(msg.Data)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Second) // some idle fetches with heartbeats and backoffs
+	if status := sub.status(); !status.Alive {
+		t.Errorf("status() of idle subscriber = %+v", status)
+	}
+	publishStringMessages(t, conn, subject, []string{"after idle"})
+	select {
+	case data := <-received:
+		if data != "after idle" {
+			t.Errorf("received %q, want %q", data, "after idle")
+		}
+	case <-time.After(time.Second * 5):
+		t.Error("message was not received after idle backoff")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnection_NewSubscriber_InvalidIdleSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     SubscriberArgs
+		timeouts TimeoutConfig
+	}{
+		{name: "IdleBackoff not shorter than AckWait", args: SubscriberArgs{AckWait: time.Second, IdleBackoff: time.Second}},
+		{name: "IdleHeartbeat too long", args: SubscriberArgs{MaxWait: time.Second, IdleHeartbeat: time.Millisecond * 500}},
+		{name: "IdleHeartbeat too long for the default MaxWait", args: SubscriberArgs{IdleHeartbeat: time.Second * 3}},
+		{
+			name:     "IdleHeartbeat too long for the Publish timeout",
+			args:     SubscriberArgs{IdleHeartbeat: time.Second},
+			timeouts: TimeoutConfig{Publish: time.Second * 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "MESSAGES", 0, nil, "", nil)
+			conn.timeouts = tt.timeouts
+			tt.args.ConsumerName = "invalid"
+			tt.args.Subject = "MESSAGES.>"
+			if _, err := conn.NewSubscriber(tt.args); err == nil {
+				t.Error("NewSubscriber() should fail")
+			}
+		})
+	}
+}
+
+func TestSubscriber_MaxMsgsAndUntil(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	tests := []struct {
+		name        string
+		args        SubscriberArgs
+		wantHandled int32
+	}{
+		{
+			name:        "MaxMsgs",
+			args:        SubscriberArgs{MaxMsgs: 3, FetchBatchSize: 2},
+			wantHandled: 3,
+		},
+		{
+			name:        "Until",
+			args:        SubscriberArgs{Until: time.Now().Add(time.Millisecond * 500), MaxWait: time.Second},
+			wantHandled: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject := integrationTestStreamName + ".bounded"
+			conn := makeIntegrationTestConn(t)
+			publishStringMessages(t, conn, subject, []string{"1", "2", "3", "4", "5"})
+
+			tt.args.ConsumerName = "Test" + tt.name
+			tt.args.Subject = subject
+			sub, err := conn.NewSubscriber(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var handled atomic.Int32
+			if err := sub.Start(func(_ Msg) error {
+				handled.Add(1)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case <-sub.Done():
+			case <-time.After(time.Second * 5):
+				t.Fatal("subscriber did not stop")
+			}
+			if got := handled.Load(); got != tt.wantHandled {
+				t.Errorf("%d messages were handled, want %d", got, tt.wantHandled)
+			}
+			time.Sleep(time.Millisecond * 50) // the subscriber is removed after Done is closed
+			if subs := conn.subscriberList(); len(subs) != 0 {
+				t.Errorf("stopped subscriber was not removed from the Connection: %v", subs)
+			}
+			if err := conn.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSubscriber_Next(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".next"
+	conn := makeIntegrationTestConn(t)
+	publishStringMessages(t, conn, subject, []string{"first", "second"})
+	sub := createSubscriber(t, conn, "TestNext", subject, SingleSubscriberStrictMessageOrder)
+
+	for _, want := range []string{"first", "second"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		msg, acker, err := sub.Next(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if string(msg.Data) != want {
+			t.Errorf("Next() = %q, want %q", msg.Data, want)
+		}
+		if err := acker.Ack(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	if _, _, err := sub.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next() without messages error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := sub.Start(func(_ Msg) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sub.Next(context.Background()); err == nil {
+		t.Error("Next() of started subscriber should fail")
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscriber_maxProgressInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    SubscriberArgs
+		fetch   time.Duration
+		publish time.Duration
+		want    time.Duration
+	}{
+		{name: "defaults", want: defaultFetchMaxWait + defaultAckWait},
+		{name: "fetch timeout", fetch: time.Second * 10, want: time.Second*10 + defaultAckWait},
+		{name: "publish timeout", publish: time.Second * 20, want: time.Second*20 + defaultAckWait},
+		{
+			name: "MaxWait longer than AckWait",
+			args: SubscriberArgs{MaxWait: time.Minute, AckWait: time.Second * 10, IdleBackoff: time.Second},
+			want: time.Minute + time.Second + time.Second*10,
+		},
+		{
+			name: "HandlerTimeout longer than AckWait",
+			args: SubscriberArgs{MaxWait: time.Second, AckWait: time.Second * 10, HandlerTimeout: time.Minute},
+			want: time.Second + time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+			conn.timeouts = TimeoutConfig{Fetch: tt.fetch, Publish: tt.publish}
+			tt.args.ConsumerName = "shipping"
+			tt.args.Subject = "ORDERS.created"
+			sub, err := conn.NewSubscriber(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sub.livenessThreshold != tt.want {
+				t.Errorf("livenessThreshold = %s, want %s", sub.livenessThreshold, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriber_Alive_LongRunningHandler(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".longrunning"
+	conn := makeIntegrationTestConn(t)
+	publishStringMessages(t, conn, subject, []string{"long running"})
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName:       "TestAliveLongRunning",
+		Subject:            subject,
+		AckWait:            time.Second,
+		MaxWait:            time.Millisecond * 1500, // longer than AckWait
+		InProgressInterval: time.Millisecond * 200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	if err := sub.Start(func(_ Msg) error {
+		close(started)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	<-started
+	time.Sleep(time.Millisecond * 2500) // the handler runs longer than AckWait
+	if status := sub.status(); !status.Alive {
+		t.Errorf("status() of subscriber with long-running handler = %+v", status)
+	}
+	close(release)
+
+	time.Sleep(time.Millisecond * 2500) // idle fetches wait longer than AckWait
+	if status := sub.status(); !status.Alive {
+		t.Errorf("status() of idle subscriber with MaxWait longer than AckWait = %+v", status)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
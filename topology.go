@@ -0,0 +1,33 @@
+package vnats
+
+import "fmt"
+
+// Topology declares the streams and consumers owned by a service, so they can be managed as code.
+// It can be defined in Go or decoded from a configuration file, e.g. with encoding/json.
+type Topology struct {
+	// Streams are created or updated to match their StreamConfig.
+	Streams []StreamConfig
+
+	// Consumers are created or updated to match their ConsumerConfig, after all Streams are applied.
+	Consumers []ConsumerConfig
+}
+
+// ApplyTopology creates missing streams and consumers of topology and updates existing ones, whose configuration
+// drifted. It is idempotent, so it can be called on each start of a service. ApplyTopology stops at the first
+// error; streams and consumers applied before are not reverted.
+func (c *Connection) ApplyTopology(topology Topology) error {
+	for _, config := range topology.Streams {
+		if _, err := c.ReconcileStream(config, ReconcileApply); err != nil {
+			return fmt.Errorf("topology could not be applied: %w", err)
+		}
+	}
+	for _, config := range topology.Consumers {
+		if err := validateConsumerConfig(config); err != nil {
+			return fmt.Errorf("topology could not be applied: consumer %s: %w", config.Name, err)
+		}
+		if err := c.nats.EnsureConsumer(config.Stream, natsConsumerConfig(config)); err != nil {
+			return fmt.Errorf("topology could not be applied: consumer %s could not be created: %w", config.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package vnats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+)
+
+func TestConnection_ApplyTopology(t *testing.T) {
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		consumer   ConsumerConfig
+		wantConfig *nats.ConsumerConfig
+		wantErr    bool
+	}{
+		{
+			name:     "Defaults",
+			consumer: ConsumerConfig{Stream: "ORDERS", Name: "shipping"},
+			wantConfig: &nats.ConsumerConfig{
+				Durable:       "shipping",
+				AckPolicy:     nats.AckExplicitPolicy,
+				AckWait:       defaultAckWait,
+				MaxAckPending: defaultMaxAckPending,
+			},
+		},
+		{
+			name: "Custom",
+			consumer: ConsumerConfig{
+				Stream:        "ORDERS",
+				Name:          "shipping",
+				Subjects:      []string{"ORDERS.created"},
+				DeliverPolicy: DeliverByStartTime,
+				StartTime:     startTime,
+				AckWait:       time.Minute,
+				MaxAckPending: 10,
+				MaxDeliver:    5,
+				Metadata:      map[string]string{"owner": "shipping-service"},
+			},
+			wantConfig: &nats.ConsumerConfig{
+				Durable:       "shipping",
+				DeliverPolicy: nats.DeliverByStartTimePolicy,
+				OptStartTime:  &startTime,
+				AckPolicy:     nats.AckExplicitPolicy,
+				AckWait:       time.Minute,
+				MaxAckPending: 10,
+				MaxDeliver:    5,
+				FilterSubject: "ORDERS.created",
+				Metadata:      map[string]string{"owner": "shipping-service"},
+			},
+		},
+		{
+			name:     "Multiple subjects",
+			consumer: ConsumerConfig{Stream: "ORDERS", Name: "shipping", Subjects: []string{"ORDERS.a", "ORDERS.b"}},
+			wantConfig: &nats.ConsumerConfig{
+				Durable:        "shipping",
+				AckPolicy:      nats.AckExplicitPolicy,
+				AckWait:        defaultAckWait,
+				MaxAckPending:  defaultMaxAckPending,
+				FilterSubjects: []string{"ORDERS.a", "ORDERS.b"},
+			},
+		},
+		{name: "Missing name", consumer: ConsumerConfig{Stream: "ORDERS"}, wantErr: true},
+		{name: "Invalid stream", consumer: ConsumerConfig{Stream: "ORDERS.>", Name: "shipping"}, wantErr: true},
+		{
+			name:     "Missing start sequence",
+			consumer: ConsumerConfig{Stream: "ORDERS", Name: "shipping", DeliverPolicy: DeliverByStartSequence},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := makeTestConnection(t, "ORDERS", 0, nil, "", nil)
+			err := conn.ApplyTopology(Topology{
+				Streams:   []StreamConfig{{Name: "ORDERS"}},
+				Consumers: []ConsumerConfig{tt.consumer},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyTopology() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			info, err := conn.nats.ConsumerInfo("ORDERS", tt.consumer.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantConfig, &info.Config); diff != "" {
+				t.Errorf("consumer config mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnection_ApplyTopology_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	conn := makeIntegrationTestConn(t)
+	streamName := integrationTestStreamName + "_TOPOLOGY"
+	consumerName := "TestApplyTopology"
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = js.DeleteStream(streamName)
+	t.Cleanup(func() { _ = js.DeleteStream(streamName) })
+
+	topology := Topology{
+		Streams: []StreamConfig{{Name: streamName, MaxMsgs: 100}},
+		Consumers: []ConsumerConfig{{
+			Stream:   streamName,
+			Name:     consumerName,
+			Subjects: []string{streamName + ".>"},
+		}},
+	}
+	if err := conn.ApplyTopology(topology); err != nil {
+		t.Fatal(err)
+	}
+	topology.Streams[0].MaxMsgs = 200
+	topology.Consumers[0].MaxDeliver = 5
+	if err := conn.ApplyTopology(topology); err != nil {
+		t.Fatal(err)
+	}
+
+	streamInfo, err := js.StreamInfo(streamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamInfo.Config.MaxMsgs != 200 {
+		t.Errorf("stream has MaxMsgs %d, want 200", streamInfo.Config.MaxMsgs)
+	}
+	consumerInfo, err := js.ConsumerInfo(streamName, consumerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consumerInfo.Config.MaxDeliver != 5 {
+		t.Errorf("consumer has MaxDeliver %d, want 5", consumerInfo.Config.MaxDeliver)
+	}
+
+	if _, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: consumerName,
+		Subject:      streamName + ".>",
+		MaxDeliver:   5,
+	}); err != nil {
+		t.Errorf("subscriber could not bind to consumer of topology: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,86 @@
+package vnats
+
+import (
+	"context"
+	"fmt"
+)
+
+// Tracer creates spans around publishing a message and around the handler of each received message, and propagates
+// their trace context in the message header, e.g. the OpenTelemetry implementation of the oteltracing package.
+// Implementations must be safe for concurrent use.
+type Tracer interface {
+	// StartPublish starts a span for publishing msg to stream. The returned context is passed to Inject.
+	StartPublish(ctx context.Context, stream string, msg *Msg) (context.Context, Span)
+
+	// Inject writes the trace context of ctx into the header of the message, which is published.
+	Inject(ctx context.Context, header Header)
+
+	// StartProcess extracts the trace context of the publisher from the header of msg and starts a span for
+	// handling msg by consumer. The returned context is passed to the handler.
+	StartProcess(ctx context.Context, stream, consumer string, msg Msg) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// Fail marks the span as failed, e.g. if publishing failed or the handler returned an error or panicked.
+	Fail(err error)
+
+	// End ends the span.
+	End()
+}
+
+// noopSpan is returned without WithTracer.
+type noopSpan struct{}
+
+func (noopSpan) Fail(error) {}
+
+func (noopSpan) End() {}
+
+// spanKey is the context key of the span started by startProcessSpan.
+type spanKey struct{}
+
+// startPublishSpan starts a span for publishing msg to stream, if WithTracer was passed.
+// The span must be injected into the published message with injectTrace.
+func (c *Connection) startPublishSpan(ctx context.Context, stream string, msg *Msg) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return c.tracer.StartPublish(ctx, stream, msg)
+}
+
+// injectTrace writes the trace context of ctx into header, if WithTracer was passed.
+func (c *Connection) injectTrace(ctx context.Context, header Header) {
+	if c.tracer != nil {
+		c.tracer.Inject(ctx, header)
+	}
+}
+
+// startProcessSpan starts a span for handling msg as child of the span of the publisher, if WithTracer was passed.
+func (c *Connection) startProcessSpan(ctx context.Context, stream, consumer string, msg Msg) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := c.tracer.StartProcess(ctx, stream, consumer, msg)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// endSpan marks span as failed, if err is not nil, and ends it.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.Fail(err)
+	}
+	span.End()
+}
+
+// failSpan marks the span started by startProcessSpan for ctx as failed, e.g. when a handler failed or panicked.
+func failSpan(ctx context.Context, reason any) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	err, ok := reason.(error)
+	if !ok {
+		err = fmt.Errorf("%v", reason)
+	}
+	span.Fail(err)
+}
@@ -0,0 +1,201 @@
+package vnats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPublisher_Tracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	bridge := &headerBridge{recordingBridge: &recordingBridge{testBridge: testBridge{TB: t, streamName: "ORDERS"}}}
+	conn := &Connection{nats: bridge, logger: slog.Default(), tracer: tracer}
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: "ORDERS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pub.Publish(NewMsg("ORDERS.created", "order-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got := bridge.header.Get(traceHeader); got != "order-1" {
+		t.Errorf("%s header = %q, want the trace of the publish span", traceHeader, got)
+	}
+	want := []recordingSpan{{name: "publish ORDERS", trace: "order-1", ended: true}}
+	if got := tracer.ended(); !slices.Equal(got, want) {
+		t.Errorf("got spans %v, want %v", got, want)
+	}
+}
+
+func TestPublisher_Tracing_Disabled(t *testing.T) {
+	bridge := &headerBridge{recordingBridge: &recordingBridge{testBridge: testBridge{TB: t, streamName: "ORDERS"}}}
+	conn := &Connection{nats: bridge, logger: slog.Default()}
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: "ORDERS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pub.Publish(NewMsg("ORDERS.created", "order-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got := bridge.header.Get(traceHeader); got != "" {
+		t.Errorf("%s header = %q, want none without WithTracer", traceHeader, got)
+	}
+}
+
+// headerBridge records the header of the last published message.
+type headerBridge struct {
+	*recordingBridge
+	header nats.Header
+}
+
+func (b *headerBridge) PublishMsg(ctx context.Context, msg *nats.Msg, msgID string) (*nats.PubAck, error) {
+	b.header = msg.Header
+	return b.recordingBridge.PublishMsg(ctx, msg, msgID)
+}
+
+// traceHeader is the header, in which recordingTracer propagates the trace.
+const traceHeader = "Test-Trace"
+
+// recordingTracer records the spans it started. The trace of a publish span is the MsgID of the published message,
+// process spans continue the trace of the header.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	name   string
+	trace  string
+	failed bool
+	ended  bool
+}
+
+// traceKey is the context key of the trace of recordingTracer.
+type traceKey struct{}
+
+func (t *recordingTracer) StartPublish(ctx context.Context, stream string, msg *Msg) (context.Context, Span) {
+	return t.start(ctx, "publish "+stream, msg.MsgID)
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, header Header) {
+	if trace, ok := ctx.Value(traceKey{}).(string); ok {
+		header.Set(traceHeader, trace)
+	}
+}
+
+func (t *recordingTracer) StartProcess(ctx context.Context, stream, _ string, msg Msg) (context.Context, Span) {
+	return t.start(ctx, "process "+stream, msg.Header.Get(traceHeader))
+}
+
+func (t *recordingTracer) start(ctx context.Context, name, trace string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordingSpan{tracer: t, name: name, trace: trace}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, traceKey{}, trace), span
+}
+
+// ended returns copies of the ended spans in the order they were started.
+func (t *recordingTracer) ended() []recordingSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []recordingSpan
+	for _, span := range t.spans {
+		if span.ended {
+			spans = append(spans, recordingSpan{name: span.name, trace: span.trace, failed: span.failed, ended: true})
+		}
+	}
+	return spans
+}
+
+func (s *recordingSpan) Fail(_ error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.failed = true
+}
+
+func (s *recordingSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+func TestConnection_Tracing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	subject := integrationTestStreamName + ".traced"
+	tracer := &recordingTracer{}
+	conn := makeIntegrationTestConn(t)
+	conn.tracer = tracer
+
+	sub, err := conn.NewSubscriber(SubscriberArgs{
+		ConsumerName: "TestTracing",
+		Subject:      subject,
+		Mode:         SingleSubscriberStrictMessageOrder,
+		Backoff:      []time.Duration{time.Millisecond * 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handled := make(chan string, 3)
+	if err := sub.StartWithContext(func(ctx context.Context, msg Msg) error {
+		trace, _ := ctx.Value(traceKey{}).(string)
+		handled <- trace
+		if msg.Metadata.NumDelivered == 1 && msg.MsgID == "traced-1" {
+			return errors.New("not yet")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := conn.NewPublisher(PublisherArgs{StreamName: integrationTestStreamName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msgID := range []string{"traced-1", "traced-2"} {
+		if _, err := pub.Publish(NewMsg(subject, msgID, []byte(msgID))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var traces []string
+	for range 3 {
+		select {
+		case trace := <-handled:
+			traces = append(traces, trace)
+		case <-time.After(time.Second * 5):
+			t.Fatal("not all messages were handled")
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+	if want := []string{"traced-1", "traced-1", "traced-2"}; !slices.Equal(traces, want) {
+		t.Errorf("handlers got traces %v, want the traces of the publishers %v", traces, want)
+	}
+
+	var failed, succeeded int
+	for _, span := range tracer.ended() {
+		if span.name != "process "+integrationTestStreamName {
+			continue
+		}
+		if span.failed {
+			failed++
+		} else {
+			succeeded++
+		}
+	}
+	if failed != 1 || succeeded != 2 {
+		t.Errorf("got %d failed and %d succeeded process spans, want 1 and 2", failed, succeeded)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/fond-of-vertigo/vnats/zaplog
+
+go 1.23
+
+require (
+	github.com/google/go-cmp v0.7.0
+	go.uber.org/zap v1.28.0
+)
+
+require go.uber.org/multierr v1.10.0 // indirect
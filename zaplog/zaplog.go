@@ -0,0 +1,130 @@
+// Package zaplog adapts a *zap.Logger to the *slog.Logger used by vnats, so services using zap don't need their
+// own glue code:
+//
+//	conn, err := vnats.Connect(servers, vnats.WithLogger(zaplog.Logger(logger)))
+package zaplog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger returns a *slog.Logger, which writes to logger. The level of logger decides which records are written.
+func Logger(logger *zap.Logger) *slog.Logger {
+	return slog.New(NewHandler(logger))
+}
+
+// Handler is a slog.Handler, which writes records to the core of a *zap.Logger. Attributes are converted to
+// typed zap fields, groups to nested objects.
+type Handler struct {
+	core zapcore.Core
+}
+
+// NewHandler returns a Handler, which writes to logger.
+func NewHandler(logger *zap.Logger) *Handler {
+	return &Handler{core: logger.Core()}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(zapLevel(level))
+}
+
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	entry := zapcore.Entry{
+		Level:   zapLevel(record.Level),
+		Time:    record.Time,
+		Message: record.Message,
+	}
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+	checked := h.core.Check(entry, nil)
+	if checked == nil {
+		return nil
+	}
+
+	fields := make([]zapcore.Field, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendField(fields, attr)
+		return true
+	})
+	checked.Write(fields...)
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zapcore.Field, 0, len(attrs))
+	for _, attr := range attrs {
+		fields = appendField(fields, attr)
+	}
+	return &Handler{core: h.core.With(fields)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{core: h.core.With([]zapcore.Field{zap.Namespace(name)})}
+}
+
+// appendField converts attr to a zap field and appends it to fields. Empty attributes are dropped and groups
+// without a key are inlined, like slog.Handler requires.
+func appendField(fields []zapcore.Field, attr slog.Attr) []zapcore.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(attr.Key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(attr.Key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(attr.Key, attr.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(attr.Key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(attr.Key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(attr.Key, attr.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(attr.Key, attr.Value.Time()))
+	case slog.KindGroup:
+		var group []zapcore.Field
+		for _, groupAttr := range attr.Value.Group() {
+			group = appendField(group, groupAttr)
+		}
+		if len(group) == 0 {
+			return fields
+		}
+		if attr.Key == "" {
+			return append(fields, group...)
+		}
+		return append(fields, zap.Dict(attr.Key, group...))
+	default:
+		if err, ok := attr.Value.Any().(error); ok {
+			return append(fields, zap.NamedError(attr.Key, err))
+		}
+		return append(fields, zap.Any(attr.Key, attr.Value.Any()))
+	}
+}
+
+// zapLevel maps level to the zap level, which includes it. Levels between the slog levels are rounded down.
+func zapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
@@ -0,0 +1,73 @@
+package zaplog
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := Logger(zap.New(core)).With(slog.String("stream", "ORDERS"))
+
+	logger.Debug("Not written")
+	logger.Error("Message handle error, will be NAKed",
+		slog.Any("error", errors.New("failed")),
+		slog.Duration("delay", time.Second),
+		slog.Group("msg", slog.String("subject", "ORDERS.created"), slog.Uint64("seq", 42)),
+		slog.Attr{},
+	)
+	logger.WithGroup("subscriber").Warn("Handler exceeded HandlerTimeout", slog.String("consumer", "shipping"))
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want 2", len(entries))
+	}
+	if entries[0].Level != zapcore.ErrorLevel || entries[0].Message != "Message handle error, will be NAKed" {
+		t.Errorf("got entry %s %q", entries[0].Level, entries[0].Message)
+	}
+	want := map[string]any{
+		"stream": "ORDERS",
+		"error":  "failed",
+		"delay":  time.Second,
+		"msg":    map[string]any{"subject": "ORDERS.created", "seq": uint64(42)},
+	}
+	if diff := cmp.Diff(want, entries[0].ContextMap()); diff != "" {
+		t.Errorf("fields mismatch (-want +got):\n%s", diff)
+	}
+
+	want = map[string]any{
+		"stream":     "ORDERS",
+		"subscriber": map[string]any{"consumer": "shipping"},
+	}
+	if diff := cmp.Diff(want, entries[1].ContextMap()); diff != "" {
+		t.Errorf("fields of group mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_zapLevel(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  zapcore.Level
+	}{
+		{level: slog.LevelDebug, want: zapcore.DebugLevel},
+		{level: slog.LevelInfo, want: zapcore.InfoLevel},
+		{level: slog.LevelInfo + 2, want: zapcore.InfoLevel},
+		{level: slog.LevelWarn, want: zapcore.WarnLevel},
+		{level: slog.LevelError, want: zapcore.ErrorLevel},
+		{level: slog.LevelError + 4, want: zapcore.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			if got := zapLevel(tt.level); got != tt.want {
+				t.Errorf("zapLevel() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}